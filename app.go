@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/job"
+	"google.golang.org/grpc"
+)
+
+// App 除了 web 服务，还有 gRPC 服务和一些后台任务要一起启动
+type App struct {
+	server      *gin.Engine
+	grpcServer  *grpc.Server
+	deletionJob *job.AccountDeletionJob
+	exportJob   *job.ExportCleanupJob
+	smsJob      *job.AsyncSMSJob
+}
@@ -20,8 +20,10 @@ type Author struct {
 type ArticleStatus uint8
 
 const (
-	ArticleStatusUnknown = iota
+	ArticleStatusUnknown ArticleStatus = iota
 	ArticleStatusPrivate
 	ArticleStatusUnPublished
 	ArticleStatusPublished
+	// ArticleStatusModerated 被管理员下架，作者不能再修改或者重新发表
+	ArticleStatusModerated
 )
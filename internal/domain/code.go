@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// CodePolicy 验证码的策略，每个业务场景可以不一样
+type CodePolicy struct {
+	// Length 验证码的位数
+	Length int
+	// Expiration 有效期
+	Expiration time.Duration
+	// ResendInterval 多久之后才能重新发送
+	ResendInterval time.Duration
+	// MaxAttempts 最多可以验证几次
+	MaxAttempts int
+}
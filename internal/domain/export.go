@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+type ExportStatus string
+
+const (
+	ExportStatusPending ExportStatus = "pending"
+	ExportStatusReady   ExportStatus = "ready"
+	ExportStatusFailed  ExportStatus = "failed"
+)
+
+// ExportTask 一次个人数据导出，每个用户同时只保留最近的一次
+type ExportTask struct {
+	Id     string
+	Uid    int64
+	Status ExportStatus
+	// ExpireAt 下载链接的过期时间，只有 ready 的时候才有意义
+	ExpireAt time.Time
+	Ctime    time.Time
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+type LoginMethod string
+
+const (
+	LoginMethodPassword LoginMethod = "password"
+	LoginMethodSMS      LoginMethod = "sms"
+	LoginMethodMFA      LoginMethod = "mfa"
+	LoginMethodRefresh  LoginMethod = "refresh"
+)
+
+type LoginOutcome string
+
+const (
+	LoginOutcomeSuccess     LoginOutcome = "success"
+	LoginOutcomeFailure     LoginOutcome = "failure"
+	LoginOutcomeLocked      LoginOutcome = "locked"
+	LoginOutcomeMFARequired LoginOutcome = "mfa_required"
+)
+
+// LoginLog 一次登录尝试，只增不改
+type LoginLog struct {
+	Id        int64
+	Uid       int64
+	IP        string
+	UserAgent string
+	Method    LoginMethod
+	Outcome   LoginOutcome
+	// Device 根据 UserAgent 算出来的设备指纹
+	Device  string
+	Country string
+	// Suspicious 和历史登录相比，设备和地区都是新的
+	Suspicious bool
+	Ctime      time.Time
+}
@@ -0,0 +1,8 @@
+package domain
+
+// TOTP 用户绑定的身份验证器
+type TOTP struct {
+	Uid     int64
+	Secret  string
+	Enabled bool
+}
@@ -0,0 +1,61 @@
+package domain
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	// RoleModerator 只能管理内容
+	RoleModerator = "moderator"
+	// RoleService 给内部服务的账号用，不给真人
+	RoleService = "service"
+)
+
+type Permission string
+
+const (
+	PermUserRead        Permission = "user:read"
+	PermUserManage      Permission = "user:manage"
+	PermRoleManage      Permission = "role:manage"
+	PermArticleModerate Permission = "article:moderate"
+	PermRateLimitReset  Permission = "ratelimit:reset"
+	// PermSMSManage 给其它业务方签发短信网关 token
+	PermSMSManage Permission = "sms:manage"
+	// PermMetricsRead 查看 /admin/debug/vars 里面的运行指标
+	PermMetricsRead Permission = "metrics:read"
+	// PermUserLogin 代替用户调用 gRPC 的 Login，只有内部服务有
+	PermUserLogin Permission = "user:login"
+)
+
+// rolePermissions 角色和权限的对应关系，角色不多，直接写死在代码里面
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermUserRead, PermUserManage, PermRoleManage,
+		PermArticleModerate, PermRateLimitReset, PermSMSManage, PermMetricsRead,
+	},
+	RoleModerator: {
+		PermUserRead, PermArticleModerate,
+	},
+	RoleService: {
+		PermUserLogin,
+	},
+}
+
+// IsValidRole 角色是否存在
+func IsValidRole(role string) bool {
+	if role == RoleUser {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission 只要有一个角色拥有这个权限就可以
+func HasPermission(roles []string, perm Permission) bool {
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
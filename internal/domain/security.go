@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+type SecurityEventType string
+
+const (
+	SecurityEventAccountLocked SecurityEventType = "account_locked"
+	SecurityEventIPLocked      SecurityEventType = "ip_locked"
+	SecurityEventSuspicious    SecurityEventType = "suspicious_login"
+)
+
+// SecurityEvent 需要告警的安全事件
+type SecurityEvent struct {
+	Type   SecurityEventType
+	Uid    int64
+	IP     string
+	Detail string
+	Time   time.Time
+}
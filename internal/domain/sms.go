@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// AsyncSMS 同步发送失败或者被限流之后存起来，等着异步重试的短信
+type AsyncSMS struct {
+	Id      int64
+	TplId   string
+	Args    []string
+	Numbers []string
+	// RetryCnt 已经重试过的次数
+	RetryCnt int
+	RetryMax int
+}
+
+type SMSDeliveryStatus string
+
+const (
+	// SMSDeliveryAccepted 服务商已经受理，等待回执
+	SMSDeliveryAccepted SMSDeliveryStatus = "accepted"
+	// SMSDeliveryRejected 服务商没有受理，比如参数错误、欠费
+	SMSDeliveryRejected    SMSDeliveryStatus = "rejected"
+	SMSDeliveryDelivered   SMSDeliveryStatus = "delivered"
+	SMSDeliveryUndelivered SMSDeliveryStatus = "undelivered"
+)
+
+// SMSDelivery 一条短信的发送记录，一个号码一条
+type SMSDelivery struct {
+	Id       int64
+	Provider string
+	TplId    string
+	// Phone 写入的时候是完整的手机号，查询出来的是打码之后的
+	Phone    string
+	Status   SMSDeliveryStatus
+	SerialNo string
+	// Reason 没有受理或者没有送达的原因
+	Reason string
+	Ctime  time.Time
+	Utime  time.Time
+}
+
+// MaskPhone 只保留前面一部分和最后 4 位，比如 +86138****0000
+func MaskPhone(phone string) string {
+	if len(phone) <= 8 {
+		return strings.Repeat("*", len(phone))
+	}
+	return phone[:len(phone)-8] + "****" + phone[len(phone)-4:]
+}
@@ -0,0 +1,11 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMaskPhone(t *testing.T) {
+	assert.Equal(t, "+86138****0000", MaskPhone("+8613800000000"))
+	assert.Equal(t, "****", MaskPhone("1234"))
+}
@@ -12,5 +12,44 @@ type User struct {
 	Nickname string
 	Birth    string
 	Bio      string
-	Ctime    time.Time
+	Roles    []string
+	Status   UserStatus
+	// Locale 用户选择的界面语言，空字符串表示跟随浏览器
+	Locale string
+	// DeleteAfter 申请注销之后的冷静期截止时间，过了这个时间才真正删除
+	DeleteAfter time.Time
+	// MergedInto 合并之后被保留的账号
+	MergedInto int64
+	Ctime      time.Time
+}
+
+type UserStatus uint8
+
+const (
+	// UserStatusActive 老数据的默认值是 0，所以 0 代表正常
+	UserStatusActive UserStatus = iota
+	// UserStatusDisabled 被管理员禁用
+	UserStatusDisabled
+	// UserStatusDeactivated 用户自己停用，重新登录就恢复
+	UserStatusDeactivated
+	// UserStatusPendingDeletion 申请了注销，冷静期内登录之后可以撤销
+	UserStatusPendingDeletion
+	// UserStatusDeleted 已经匿名化，不能再登录
+	UserStatusDeleted
+	// UserStatusMerged 已经合并到别的账号，只留下一条记录指向合并后的账号
+	UserStatusMerged
+)
+
+// CanLogin 停用和冷静期中的账号都还可以登录，方便用户恢复
+func (s UserStatus) CanLogin() bool {
+	return s != UserStatusDisabled && s != UserStatusDeleted && s != UserStatusMerged
+}
+
+// Credential 合并账号、更换手机号这类敏感操作要求重新认证，要求的因素和登录一样
+type Credential struct {
+	Password string
+	MFACode  string
+	// Code 发到当前手机号的验证码，没有密码的账号登录靠的就是它
+	Code string
+	IP   string
 }
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"context"
+	articlev1 "github.com/skcheng003/webook/api/proto/gen/article/v1"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/service"
+	"google.golang.org/grpc"
+)
+
+// ArticleServiceServer 把 service.ArticleService 暴露给内部的其它服务
+type ArticleServiceServer struct {
+	articlev1.UnimplementedArticleServiceServer
+	svc service.ArticleService
+}
+
+func NewArticleServiceServer(svc service.ArticleService) *ArticleServiceServer {
+	return &ArticleServiceServer{
+		svc: svc,
+	}
+}
+
+func (s *ArticleServiceServer) Register(server *grpc.Server) {
+	articlev1.RegisterArticleServiceServer(server, s)
+}
+
+// Permissions 管理员才能调用的方法
+func (s *ArticleServiceServer) Permissions() map[string]domain.Permission {
+	return map[string]domain.Permission{
+		articlev1.ArticleService_Unpublish_FullMethodName: domain.PermArticleModerate,
+	}
+}
+
+func (s *ArticleServiceServer) Save(ctx context.Context, req *articlev1.SaveRequest) (*articlev1.SaveResponse, error) {
+	uc, _ := ClaimsFromContext(ctx)
+	id, err := s.svc.Save(ctx, domain.Article{
+		Id:      req.GetId(),
+		Title:   req.GetTitle(),
+		Content: req.GetContent(),
+		Author:  domain.Author{Id: uc.Uid},
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &articlev1.SaveResponse{Id: id}, nil
+}
+
+func (s *ArticleServiceServer) Publish(ctx context.Context, req *articlev1.PublishRequest) (*articlev1.PublishResponse, error) {
+	uc, _ := ClaimsFromContext(ctx)
+	id, err := s.svc.Publish(ctx, domain.Article{
+		Id:      req.GetId(),
+		Title:   req.GetTitle(),
+		Content: req.GetContent(),
+		Author:  domain.Author{Id: uc.Uid},
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &articlev1.PublishResponse{Id: id}, nil
+}
+
+func (s *ArticleServiceServer) Withdraw(ctx context.Context, req *articlev1.WithdrawRequest) (*articlev1.WithdrawResponse, error) {
+	uc, _ := ClaimsFromContext(ctx)
+	err := s.svc.Withdraw(ctx, uc.Uid, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &articlev1.WithdrawResponse{}, nil
+}
+
+func (s *ArticleServiceServer) GetById(ctx context.Context, req *articlev1.GetByIdRequest) (*articlev1.GetByIdResponse, error) {
+	uc, _ := ClaimsFromContext(ctx)
+	art, err := s.svc.GetById(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	// 草稿只有作者自己能看，别人的文章当成不存在
+	if art.Author.Id != uc.Uid {
+		return nil, toStatus(service.ErrArticleNoFound)
+	}
+	return &articlev1.GetByIdResponse{Article: toArticleDTO(art)}, nil
+}
+
+func (s *ArticleServiceServer) GetPubById(ctx context.Context,
+	req *articlev1.GetPubByIdRequest) (*articlev1.GetPubByIdResponse, error) {
+	art, err := s.svc.GetPubById(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &articlev1.GetPubByIdResponse{Article: toArticleDTO(art)}, nil
+}
+
+func (s *ArticleServiceServer) Unpublish(ctx context.Context,
+	req *articlev1.UnpublishRequest) (*articlev1.UnpublishResponse, error) {
+	err := s.svc.Unpublish(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &articlev1.UnpublishResponse{}, nil
+}
+
+func toArticleDTO(art domain.Article) *articlev1.Article {
+	return &articlev1.Article{
+		Id:       art.Id,
+		Title:    art.Title,
+		Content:  art.Content,
+		AuthorId: art.Author.Id,
+		Status:   int32(art.Status),
+		Ctime:    art.CreateTime.UnixMilli(),
+		Utime:    art.UpdateTime.UnixMilli(),
+	}
+}
@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// TokenVerifier jwt.Handler 里面校验 access token 的那部分
+type TokenVerifier interface {
+	ParseAccessToken(signedToken string) (*jwt.UserClaims, error)
+	CheckSession(ctx context.Context, ssid string) error
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext 拿到 AuthInterceptorBuilder 放进去的 claims
+func ClaimsFromContext(ctx context.Context) (*jwt.UserClaims, bool) {
+	uc, ok := ctx.Value(claimsKey{}).(*jwt.UserClaims)
+	return uc, ok
+}
+
+// AuthInterceptorBuilder 和 HTTP 一样校验 webook 的 access token，
+// token 放在 metadata 的 authorization 里面，格式是 Bearer <token>
+type AuthInterceptorBuilder struct {
+	verifier TokenVerifier
+	public   map[string]bool
+	perms    map[string]domain.Permission
+}
+
+func NewAuthInterceptorBuilder(verifier TokenVerifier) *AuthInterceptorBuilder {
+	return &AuthInterceptorBuilder{
+		verifier: verifier,
+		public:   map[string]bool{},
+		perms:    map[string]domain.Permission{},
+	}
+}
+
+// IgnoreMethod 不需要登录的方法，传完整的方法名，比如 /user.v1.UserService/Login
+func (b *AuthInterceptorBuilder) IgnoreMethod(methods ...string) *AuthInterceptorBuilder {
+	for _, m := range methods {
+		b.public[m] = true
+	}
+	return b
+}
+
+// RequirePermission 除了登录还要求有某个权限
+func (b *AuthInterceptorBuilder) RequirePermission(method string, perm domain.Permission) *AuthInterceptorBuilder {
+	b.perms[method] = perm
+	return b
+}
+
+func (b *AuthInterceptorBuilder) Build() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		if b.public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		claims, err := b.verifier.ParseAccessToken(b.extractToken(ctx))
+		if err != nil || claims.Uid == 0 {
+			return nil, status.Error(codes.Unauthenticated, "请先登录")
+		}
+		// 查询当前 session 是否已经退出
+		if err = b.verifier.CheckSession(ctx, claims.Ssid); err != nil {
+			return nil, status.Error(codes.Unauthenticated, "请先登录")
+		}
+		if perm, ok := b.perms[info.FullMethod]; ok && !domain.HasPermission(claims.Roles, perm) {
+			return nil, status.Error(codes.PermissionDenied, "没有权限")
+		}
+		if lang, ok := i18n.Parse(claims.Locale); ok {
+			ctx = i18n.WithLang(ctx, lang)
+		}
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+func (b *AuthInterceptorBuilder) extractToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	vals := md.Get("authorization")
+	if len(vals) == 0 {
+		return ""
+	}
+	token, ok := strings.CutPrefix(vals[0], "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
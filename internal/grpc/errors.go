@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"errors"
+	"github.com/skcheng003/webook/internal/service"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus 把 service 的错误转成 gRPC 的状态码，没有列出来的都是 Internal，
+// 并且不把内部错误的细节返回给调用方
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	var code codes.Code
+	switch {
+	case errors.Is(err, service.ErrUserNoFound):
+		// ErrArticleNoFound 也是同一个错误
+		code = codes.NotFound
+	case errors.Is(err, service.ErrInvalidUserOrPassword),
+		errors.Is(err, service.ErrCodeInvalid),
+		errors.Is(err, service.ErrMFAInvalidCode):
+		code = codes.Unauthenticated
+	case errors.Is(err, service.ErrUserDisabled),
+		errors.Is(err, service.ErrAccountLocked),
+		errors.Is(err, service.ErrArticleNotOwned),
+		errors.Is(err, service.ErrArticleModerated):
+		code = codes.PermissionDenied
+	case errors.Is(err, service.ErrUserDuplicateEmail),
+		errors.Is(err, service.ErrPhoneBound),
+		errors.Is(err, service.ErrEmailBound):
+		code = codes.AlreadyExists
+	case errors.Is(err, service.ErrAccountNotLocked),
+		errors.Is(err, service.ErrAccountNoPhone),
+		errors.Is(err, service.ErrAccountNotActive),
+		errors.Is(err, service.ErrUserNotMergeable),
+		errors.Is(err, service.ErrUserNotDisabled),
+		errors.Is(err, service.ErrReauthRequired),
+		errors.Is(err, service.ErrMFANotEnrolled),
+		errors.Is(err, service.ErrMFAAlreadyEnabled),
+		errors.Is(err, service.ErrMFARequired),
+		errors.Is(err, service.ErrCaptchaRequired):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrPhoneBlocked),
+		errors.Is(err, service.ErrCaptchaInvalid):
+		code = codes.InvalidArgument
+	case errors.Is(err, service.ErrLoginTooFrequent),
+		errors.Is(err, service.ErrCodeSendTooMany),
+		errors.Is(err, service.ErrCodeQuotaExceeded),
+		errors.Is(err, service.ErrCodeVerifyTooManyTimes):
+		code = codes.ResourceExhausted
+	default:
+		zap.L().Error("grpc 调用 service 失败", zap.Error(err))
+		return status.Error(codes.Internal, "系统错误")
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,429 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	articlev1 "github.com/skcheng003/webook/api/proto/gen/article/v1"
+	userv1 "github.com/skcheng003/webook/api/proto/gen/user/v1"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/service"
+	svcmocks "github.com/skcheng003/webook/internal/service/mocks"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+// fakeVerifier token 就是 claims 的名字
+type fakeVerifier map[string]*jwt.UserClaims
+
+func (f fakeVerifier) ParseAccessToken(signedToken string) (*jwt.UserClaims, error) {
+	uc, ok := f[signedToken]
+	if !ok {
+		return nil, errors.New("token 无效")
+	}
+	return uc, nil
+}
+
+func (f fakeVerifier) CheckSession(ctx context.Context, ssid string) error {
+	if ssid == "logout" {
+		return errors.New("session 已经退出")
+	}
+	return nil
+}
+
+// services 测试用例里面没有设置的用空的 mock 代替
+type services struct {
+	user    service.UserService
+	article service.ArticleService
+	code    service.CodeService
+}
+
+type nopRevoker struct{}
+
+func (nopRevoker) RevokeAllSessions(ctx context.Context, uid int64) error {
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	verifier := fakeVerifier{
+		"user":  {Uid: 123, Ssid: "s1", Roles: []string{domain.RoleUser}},
+		"admin": {Uid: 1, Ssid: "s2", Roles: []string{domain.RoleAdmin}},
+		// 内部服务的账号
+		"service": {Uid: 2, Ssid: "s3", Roles: []string{domain.RoleService}},
+		"logout":  {Uid: 123, Ssid: "logout"},
+	}
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) services
+		token string
+		call  func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error
+
+		wantCode codes.Code
+	}{
+		{
+			name: "内部服务代替用户登录",
+			mock: func(ctrl *gomock.Controller) services {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123", "1.2.3.4").
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				return services{user: userSvc}
+			},
+			token: "service",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-real-ip", "1.2.3.4")
+				resp, err := uc.Login(ctx, &userv1.LoginRequest{
+					Email: "123@qq.com", Password: "hello#world123",
+				})
+				if err == nil {
+					assert.Equal(t, int64(123), resp.GetUser().GetId())
+				}
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "登录没有 token",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Login(ctx, &userv1.LoginRequest{Email: "123@qq.com", Password: "hello#world123"})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "普通用户不能调用登录",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Login(ctx, &userv1.LoginRequest{Email: "123@qq.com", Password: "hello#world123"})
+				return err
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "密码错误",
+			mock: func(ctrl *gomock.Controller) services {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				// 没有 x-real-ip 的时候用连接的对端地址，bufconn 的地址是 bufconn
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "wrong", "bufconn").
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				return services{user: userSvc}
+			},
+			token: "service",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Login(ctx, &userv1.LoginRequest{Email: "123@qq.com", Password: "wrong"})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "开启了二次验证没有带验证码",
+			mock: func(ctrl *gomock.Controller) services {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123", gomock.Any()).
+					Return(domain.User{Id: 123}, service.ErrMFARequired)
+				return services{user: userSvc}
+			},
+			token: "service",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Login(ctx, &userv1.LoginRequest{Email: "123@qq.com", Password: "hello#world123"})
+				return err
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name: "二次验证码错误",
+			mock: func(ctrl *gomock.Controller) services {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123", gomock.Any()).
+					Return(domain.User{Id: 123}, service.ErrMFARequired)
+				userSvc.EXPECT().LoginMFA(gomock.Any(), int64(123), "654321", gomock.Any()).
+					Return(domain.User{Id: 123}, service.ErrMFAInvalidCode)
+				return services{user: userSvc}
+			},
+			token: "service",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Login(ctx, &userv1.LoginRequest{
+					Email: "123@qq.com", Password: "hello#world123", MfaCode: "654321",
+				})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "没有 token",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Profile(ctx, &userv1.ProfileRequest{})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "session 已经退出",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			token: "logout",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Profile(ctx, &userv1.ProfileRequest{})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "查询用户资料",
+			mock: func(ctrl *gomock.Controller) services {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindProfileJWT(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Nickname: "webook"}, nil)
+				return services{user: userSvc}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				resp, err := uc.Profile(ctx, &userv1.ProfileRequest{Id: 123})
+				if err == nil {
+					assert.Equal(t, "webook", resp.GetUser().GetNickname())
+				}
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "绑定手机号没有验证码",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.BindPhone(ctx, &userv1.BindPhoneRequest{Phone: "13800138000"})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "绑定手机号验证码不对",
+			mock: func(ctrl *gomock.Controller) services {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "user/bind_phone", "13800138000", "123456").
+					Return(false, nil)
+				return services{code: codeSvc}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.BindPhone(ctx, &userv1.BindPhoneRequest{Phone: "13800138000", Code: "123456"})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "绑定邮箱",
+			mock: func(ctrl *gomock.Controller) services {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "user/bind_email", "123@qq.com", "123456").
+					Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().BindEmail(gomock.Any(), int64(123), "123@qq.com", gomock.Any()).
+					DoAndReturn(func(ctx context.Context, uid int64, email string, cred domain.Credential) error {
+						// 更换邮箱要用的重新认证因素原样传给 service
+						assert.Equal(t, "hello#world123", cred.Password)
+						assert.Equal(t, "654321", cred.MFACode)
+						return nil
+					})
+				return services{user: userSvc, code: codeSvc}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.BindEmail(ctx, &userv1.BindEmailRequest{Email: "123@qq.com", Code: "123456",
+					Password: "hello#world123", MfaCode: "654321"})
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "普通用户查别人的资料",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Profile(ctx, &userv1.ProfileRequest{Id: 456})
+				return err
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "管理员查别人的资料",
+			mock: func(ctrl *gomock.Controller) services {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindProfileJWT(gomock.Any(), int64(456)).
+					Return(domain.User{Id: 456}, nil)
+				return services{user: userSvc}
+			},
+			token: "admin",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Profile(ctx, &userv1.ProfileRequest{Id: 456})
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "普通用户按邮箱查资料",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.ProfileByEmail(ctx, &userv1.ProfileByEmailRequest{Email: "456@qq.com"})
+				return err
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "普通用户按手机号创建账号",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.FindOrCreate(ctx, &userv1.FindOrCreateRequest{Phone: "13800138000"})
+				return err
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "普通用户禁用账号",
+			mock: func(ctrl *gomock.Controller) services {
+				return services{}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Disable(ctx, &userv1.DisableRequest{Id: 456})
+				return err
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "管理员禁用账号",
+			mock: func(ctrl *gomock.Controller) services {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Disable(gomock.Any(), int64(456)).Return(nil)
+				return services{user: userSvc}
+			},
+			token: "admin",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Disable(ctx, &userv1.DisableRequest{Id: 456})
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "解禁没有被禁用的账号",
+			mock: func(ctrl *gomock.Controller) services {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Enable(gomock.Any(), int64(456)).Return(service.ErrUserNotDisabled)
+				return services{user: userSvc}
+			},
+			token: "admin",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := uc.Enable(ctx, &userv1.EnableRequest{Id: 456})
+				return err
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name: "看别人的草稿",
+			mock: func(ctrl *gomock.Controller) services {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetById(gomock.Any(), int64(1)).
+					Return(domain.Article{Id: 1, Author: domain.Author{Id: 456}}, nil)
+				return services{article: artSvc}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := ac.GetById(ctx, &articlev1.GetByIdRequest{Id: 1})
+				return err
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "系统错误不暴露细节",
+			mock: func(ctrl *gomock.Controller) services {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).
+					Return(domain.Article{}, errors.New("db 连接断开"))
+				return services{article: artSvc}
+			},
+			token: "user",
+			call: func(ctx context.Context, uc userv1.UserServiceClient, ac articlev1.ArticleServiceClient) error {
+				_, err := ac.GetPubById(ctx, &articlev1.GetPubByIdRequest{Id: 1})
+				if err != nil {
+					assert.Equal(t, "系统错误", status.Convert(err).Message())
+				}
+				return err
+			},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svcs := tc.mock(ctrl)
+			if svcs.user == nil {
+				svcs.user = svcmocks.NewMockUserService(ctrl)
+			}
+			if svcs.article == nil {
+				svcs.article = svcmocks.NewMockArticleService(ctrl)
+			}
+			if svcs.code == nil {
+				svcs.code = svcmocks.NewMockCodeService(ctrl)
+			}
+			userSrv := NewUserServiceServer(svcs.user, nopRevoker{}, svcs.code, svcs.code)
+			artSrv := NewArticleServiceServer(svcs.article)
+			auth := NewAuthInterceptorBuilder(verifier).IgnoreMethod(userSrv.PublicMethods()...)
+			for method, perm := range userSrv.Permissions() {
+				auth.RequirePermission(method, perm)
+			}
+			server := grpc.NewServer(grpc.UnaryInterceptor(auth.Build()))
+			userSrv.Register(server)
+			artSrv.Register(server)
+
+			l := bufconn.Listen(1024 * 1024)
+			go func() {
+				_ = server.Serve(l)
+			}()
+			defer server.Stop()
+
+			conn, err := grpc.DialContext(context.Background(), "bufnet",
+				grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+					return l.DialContext(ctx)
+				}),
+				grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			ctx := context.Background()
+			if tc.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tc.token)
+			}
+			err = tc.call(ctx, userv1.NewUserServiceClient(conn), articlev1.NewArticleServiceClient(conn))
+			assert.Equal(t, tc.wantCode, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	userv1 "github.com/skcheng003/webook/api/proto/gen/user/v1"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+)
+
+const (
+	// 和 HTTP 接口用同一个 biz，两边发的验证码可以互相用
+	bindPhoneBiz = "user/bind_phone"
+	bindEmailBiz = "user/bind_email"
+)
+
+// UserServiceServer 把 service.UserService 暴露给内部的其它服务
+type UserServiceServer struct {
+	userv1.UnimplementedUserServiceServer
+	svc          service.UserService
+	revoker      service.SessionRevoker
+	smsCodeSvc   service.CodeService
+	emailCodeSvc service.EmailCodeService
+}
+
+func NewUserServiceServer(svc service.UserService, revoker service.SessionRevoker,
+	smsCodeSvc service.CodeService, emailCodeSvc service.EmailCodeService) *UserServiceServer {
+	return &UserServiceServer{
+		svc:          svc,
+		revoker:      revoker,
+		smsCodeSvc:   smsCodeSvc,
+		emailCodeSvc: emailCodeSvc,
+	}
+}
+
+func (s *UserServiceServer) Register(server *grpc.Server) {
+	userv1.RegisterUserServiceServer(server, s)
+}
+
+// PublicMethods 不需要登录的方法
+func (s *UserServiceServer) PublicMethods() []string {
+	return []string{
+		userv1.UserService_SignUp_FullMethodName,
+		userv1.UserService_SendUnlockCode_FullMethodName,
+		userv1.UserService_UnlockAccount_FullMethodName,
+	}
+}
+
+// Permissions 管理员或者内部服务才能调用的方法。
+// Profile 只能查自己，查别人要有 PermUserRead，在方法里面判断
+func (s *UserServiceServer) Permissions() map[string]domain.Permission {
+	return map[string]domain.Permission{
+		userv1.UserService_Login_FullMethodName:          domain.PermUserLogin,
+		userv1.UserService_ProfileByEmail_FullMethodName: domain.PermUserRead,
+		userv1.UserService_FindOrCreate_FullMethodName:   domain.PermUserManage,
+		userv1.UserService_Disable_FullMethodName:        domain.PermUserManage,
+		userv1.UserService_Enable_FullMethodName:         domain.PermUserManage,
+		userv1.UserService_UpdateRoles_FullMethodName:    domain.PermRoleManage,
+	}
+}
+
+func (s *UserServiceServer) SignUp(ctx context.Context, req *userv1.SignUpRequest) (*userv1.SignUpResponse, error) {
+	if req.GetEmail() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "邮箱和密码不能为空")
+	}
+	err := s.svc.SignUp(ctx, domain.User{
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+	})
+	return &userv1.SignUpResponse{}, toStatus(err)
+}
+
+// Login 调用方是内部服务，开启了二次验证的账号要同时带上 mfa_code
+func (s *UserServiceServer) Login(ctx context.Context, req *userv1.LoginRequest) (*userv1.LoginResponse, error) {
+	ip := clientIP(ctx)
+	u, err := s.svc.Login(ctx, req.GetEmail(), req.GetPassword(), ip)
+	// 账号不存在和密码错误返回一样的错误，避免被用来探测账号
+	if errors.Is(err, service.ErrUserNoFound) {
+		err = service.ErrInvalidUserOrPassword
+	}
+	if errors.Is(err, service.ErrMFARequired) {
+		if req.GetMfaCode() == "" {
+			return nil, status.Error(codes.FailedPrecondition, "需要二次验证")
+		}
+		// 和 HTTP 一样，二次验证码错误也计入失败次数
+		u, err = s.svc.LoginMFA(ctx, u.Id, req.GetMfaCode(), ip)
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &userv1.LoginResponse{User: toUserDTO(u)}, nil
+}
+
+func (s *UserServiceServer) SendUnlockCode(ctx context.Context,
+	req *userv1.SendUnlockCodeRequest) (*userv1.SendUnlockCodeResponse, error) {
+	err := s.svc.SendUnlockCode(ctx, req.GetEmail())
+	return &userv1.SendUnlockCodeResponse{}, toStatus(err)
+}
+
+func (s *UserServiceServer) UnlockAccount(ctx context.Context,
+	req *userv1.UnlockAccountRequest) (*userv1.UnlockAccountResponse, error) {
+	err := s.svc.UnlockAccount(ctx, req.GetEmail(), req.GetCode())
+	return &userv1.UnlockAccountResponse{}, toStatus(err)
+}
+
+func (s *UserServiceServer) EditProfile(ctx context.Context,
+	req *userv1.EditProfileRequest) (*userv1.EditProfileResponse, error) {
+	var locale i18n.Lang
+	if req.GetLocale() != "" {
+		var ok bool
+		if locale, ok = i18n.Parse(req.GetLocale()); !ok {
+			return nil, status.Error(codes.InvalidArgument, "不支持的语言")
+		}
+	}
+	uc, _ := ClaimsFromContext(ctx)
+	err := s.svc.EditProfile(ctx, domain.User{
+		Id:       uc.Uid,
+		Nickname: req.GetNickname(),
+		Birth:    req.GetBirth(),
+		Bio:      req.GetBio(),
+		Locale:   string(locale),
+	})
+	return &userv1.EditProfileResponse{}, toStatus(err)
+}
+
+func (s *UserServiceServer) Profile(ctx context.Context, req *userv1.ProfileRequest) (*userv1.ProfileResponse, error) {
+	uc, _ := ClaimsFromContext(ctx)
+	if req.GetId() != uc.Uid && !domain.HasPermission(uc.Roles, domain.PermUserRead) {
+		return nil, status.Error(codes.PermissionDenied, "没有权限")
+	}
+	u, err := s.svc.FindProfileJWT(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &userv1.ProfileResponse{User: toUserDTO(u)}, nil
+}
+
+func (s *UserServiceServer) ProfileByEmail(ctx context.Context,
+	req *userv1.ProfileByEmailRequest) (*userv1.ProfileByEmailResponse, error) {
+	u, err := s.svc.FindProfile(ctx, req.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &userv1.ProfileByEmailResponse{User: toUserDTO(u)}, nil
+}
+
+func (s *UserServiceServer) FindOrCreate(ctx context.Context,
+	req *userv1.FindOrCreateRequest) (*userv1.FindOrCreateResponse, error) {
+	if req.GetPhone() == "" {
+		return nil, status.Error(codes.InvalidArgument, "手机号不能为空")
+	}
+	u, err := s.svc.FindOrCreate(ctx, req.GetPhone())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &userv1.FindOrCreateResponse{User: toUserDTO(u)}, nil
+}
+
+// BindPhone 和 HTTP 一样，要先用验证码证明新手机号属于当前用户
+func (s *UserServiceServer) BindPhone(ctx context.Context, req *userv1.BindPhoneRequest) (*userv1.BindPhoneResponse, error) {
+	if err := s.verify(ctx, s.smsCodeSvc, bindPhoneBiz, req.GetPhone(), req.GetCode()); err != nil {
+		return nil, err
+	}
+	uc, _ := ClaimsFromContext(ctx)
+	cred := domain.Credential{Password: req.GetPassword(), MFACode: req.GetMfaCode(),
+		Code: req.GetCurrentCode(), IP: peerIP(ctx)}
+	err := s.svc.BindPhone(ctx, uc.Uid, req.GetPhone(), cred)
+	return &userv1.BindPhoneResponse{}, toStatus(err)
+}
+
+func (s *UserServiceServer) BindEmail(ctx context.Context, req *userv1.BindEmailRequest) (*userv1.BindEmailResponse, error) {
+	if err := s.verify(ctx, s.emailCodeSvc, bindEmailBiz, req.GetEmail(), req.GetCode()); err != nil {
+		return nil, err
+	}
+	uc, _ := ClaimsFromContext(ctx)
+	cred := domain.Credential{Password: req.GetPassword(), MFACode: req.GetMfaCode(),
+		Code: req.GetCurrentCode(), IP: peerIP(ctx)}
+	err := s.svc.BindEmail(ctx, uc.Uid, req.GetEmail(), cred)
+	return &userv1.BindEmailResponse{}, toStatus(err)
+}
+
+func (s *UserServiceServer) verify(ctx context.Context, codeSvc service.CodeService,
+	biz string, target string, code string) error {
+	if target == "" || code == "" {
+		return status.Error(codes.InvalidArgument, "验证码不能为空")
+	}
+	ok, err := codeSvc.Verify(ctx, biz, target, code)
+	if err != nil {
+		return toStatus(err)
+	}
+	if !ok {
+		return status.Error(codes.InvalidArgument, "验证码不对")
+	}
+	return nil
+}
+
+// Disable 和管理后台一样，禁用之后踢掉所有设备
+func (s *UserServiceServer) Disable(ctx context.Context, req *userv1.DisableRequest) (*userv1.DisableResponse, error) {
+	err := s.svc.Disable(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	err = s.revoker.RevokeAllSessions(ctx, req.GetId())
+	if err != nil {
+		zap.L().Warn("禁用账号时吊销 session 失败", zap.Int64("uid", req.GetId()), zap.Error(err))
+	}
+	return &userv1.DisableResponse{}, nil
+}
+
+func (s *UserServiceServer) Enable(ctx context.Context, req *userv1.EnableRequest) (*userv1.EnableResponse, error) {
+	err := s.svc.Enable(ctx, req.GetId())
+	return &userv1.EnableResponse{}, toStatus(err)
+}
+
+func (s *UserServiceServer) UpdateRoles(ctx context.Context,
+	req *userv1.UpdateRolesRequest) (*userv1.UpdateRolesResponse, error) {
+	err := s.svc.UpdateRoles(ctx, req.GetId(), req.GetRoles())
+	return &userv1.UpdateRolesResponse{}, toStatus(err)
+}
+
+func toUserDTO(u domain.User) *userv1.User {
+	return &userv1.User{
+		Id:       u.Id,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Nickname: u.Nickname,
+		Birth:    u.Birth,
+		Bio:      u.Bio,
+		Locale:   u.Locale,
+		Roles:    u.Roles,
+		Status:   int32(u.Status),
+		Ctime:    u.Ctime.UnixMilli(),
+	}
+}
+
+// clientIP 最终用户的 IP，Login 只有内部服务能调用，所以可以相信 metadata 里面的 x-real-ip，
+// 没有的话就是直接连过来的客户端
+func clientIP(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get("x-real-ip"); len(vals) > 0 && net.ParseIP(vals[0]) != nil {
+			return vals[0]
+		}
+	}
+	return peerIP(ctx)
+}
+
+// peerIP 直接连过来的客户端的地址，最终用户能调用的接口只能用这个，metadata 可以随便伪造
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
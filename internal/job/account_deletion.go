@@ -0,0 +1,49 @@
+package job
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/service"
+	"go.uber.org/zap"
+	"time"
+)
+
+// AccountDeletionJob 定时清理冷静期已经结束的账号
+type AccountDeletionJob struct {
+	svc      service.AccountService
+	interval time.Duration
+}
+
+func NewAccountDeletionJob(svc service.AccountService) *AccountDeletionJob {
+	return &AccountDeletionJob{
+		svc:      svc,
+		interval: time.Minute,
+	}
+}
+
+// Start 阻塞直到 ctx 被取消，一般在单独的 goroutine 里面调用。
+// 多实例部署的时候每个实例都会跑，purge 是幂等的，所以只是浪费一点资源
+func (j *AccountDeletionJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.run(ctx)
+		}
+	}
+}
+
+func (j *AccountDeletionJob) run(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, j.interval)
+	defer cancel()
+	cnt, err := j.svc.PurgeDue(ctx)
+	if err != nil {
+		zap.L().Error("清理注销账号失败", zap.Error(err))
+		return
+	}
+	if cnt > 0 {
+		zap.L().Info("清理注销账号", zap.Int("count", cnt))
+	}
+}
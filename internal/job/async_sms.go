@@ -0,0 +1,54 @@
+package job
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/service/sms/async"
+	"go.uber.org/zap"
+	"time"
+)
+
+// AsyncSMSJob 定时重试存在数据库里面的短信。
+// 多实例部署的时候靠抢占保证同一条短信不会被同时发送
+type AsyncSMSJob struct {
+	svc      *async.Service
+	interval time.Duration
+	// timeout 单条短信的发送超时
+	timeout time.Duration
+}
+
+func NewAsyncSMSJob(svc *async.Service) *AsyncSMSJob {
+	return &AsyncSMSJob{
+		svc:      svc,
+		interval: time.Second,
+		timeout:  time.Second * 5,
+	}
+}
+
+func (j *AsyncSMSJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.run(ctx)
+		}
+	}
+}
+
+// run 一直重试到没有到期的短信为止
+func (j *AsyncSMSJob) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		sctx, cancel := context.WithTimeout(ctx, j.timeout)
+		ok, err := j.svc.RetryOnce(sctx)
+		cancel()
+		if err != nil {
+			zap.L().Error("异步重试短信失败", zap.Error(err))
+			return
+		}
+		if !ok {
+			return
+		}
+	}
+}
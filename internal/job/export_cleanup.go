@@ -0,0 +1,41 @@
+package job
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/service"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ExportCleanupJob 定时删除过期的导出文件，里面都是个人数据，不能一直留着
+type ExportCleanupJob struct {
+	svc      service.ExportService
+	interval time.Duration
+}
+
+func NewExportCleanupJob(svc service.ExportService) *ExportCleanupJob {
+	return &ExportCleanupJob{
+		svc:      svc,
+		interval: time.Minute * 10,
+	}
+}
+
+func (j *ExportCleanupJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cnt, err := j.svc.Cleanup(ctx)
+			if err != nil {
+				zap.L().Error("清理导出文件失败", zap.Error(err))
+				continue
+			}
+			if cnt > 0 {
+				zap.L().Info("清理导出文件", zap.Int("count", cnt))
+			}
+		}
+	}
+}
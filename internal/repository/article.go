@@ -2,13 +2,106 @@ package repository
 
 import (
 	"context"
+	"github.com/ecodeclub/ekit/slice"
 	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository/dao"
+	"time"
+)
+
+var (
+	ErrArticleNoFound   = dao.ErrArticleNoFound
+	ErrArticleNotOwned  = dao.ErrArticleNotOwned
+	ErrArticleModerated = dao.ErrArticleModerated
 )
 
 type ArticleRepository interface {
-	Create(ctx context.Context, article domain.Article) (int64, error)
-	Update(ctx context.Context, article domain.Article) (int64, error)
+	Create(ctx context.Context, art domain.Article) (int64, error)
+	Update(ctx context.Context, art domain.Article) error
+	// SyncStatus 作者修改自己文章的状态
+	SyncStatus(ctx context.Context, authorId int64, id int64, status domain.ArticleStatus) error
+	// UpdateStatus 不校验作者，给管理员用
+	UpdateStatus(ctx context.Context, id int64, status domain.ArticleStatus) error
+	FindById(ctx context.Context, id int64) (domain.Article, error)
+	UpdateStatusByAuthor(ctx context.Context, authorId int64, status domain.ArticleStatus) error
+	DeleteByAuthor(ctx context.Context, authorId int64) error
+	ListByAuthor(ctx context.Context, authorId int64, offset int, limit int) ([]domain.Article, error)
 }
 
 type CachedArticleRepository struct {
+	dao dao.ArticleDao
+}
+
+func NewCachedArticleRepository(dao dao.ArticleDao) ArticleRepository {
+	return &CachedArticleRepository{
+		dao: dao,
+	}
+}
+
+func (repo *CachedArticleRepository) Create(ctx context.Context, art domain.Article) (int64, error) {
+	return repo.dao.Insert(ctx, repo.toEntity(art))
+}
+
+func (repo *CachedArticleRepository) Update(ctx context.Context, art domain.Article) error {
+	return repo.dao.UpdateById(ctx, repo.toEntity(art))
+}
+
+func (repo *CachedArticleRepository) SyncStatus(ctx context.Context, authorId int64,
+	id int64, status domain.ArticleStatus) error {
+	return repo.dao.UpdateStatusByAuthor(ctx, authorId, id, uint8(status))
+}
+
+func (repo *CachedArticleRepository) UpdateStatus(ctx context.Context, id int64, status domain.ArticleStatus) error {
+	return repo.dao.UpdateStatus(ctx, id, uint8(status))
+}
+
+func (repo *CachedArticleRepository) FindById(ctx context.Context, id int64) (domain.Article, error) {
+	art, err := repo.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	return repo.toDomain(art), nil
+}
+
+func (repo *CachedArticleRepository) UpdateStatusByAuthor(ctx context.Context, authorId int64,
+	status domain.ArticleStatus) error {
+	return repo.dao.UpdateStatusByAuthorId(ctx, authorId, uint8(status))
+}
+
+func (repo *CachedArticleRepository) DeleteByAuthor(ctx context.Context, authorId int64) error {
+	return repo.dao.DeleteByAuthorId(ctx, authorId)
+}
+
+func (repo *CachedArticleRepository) ListByAuthor(ctx context.Context, authorId int64,
+	offset int, limit int) ([]domain.Article, error) {
+	arts, err := repo.dao.FindByAuthorId(ctx, authorId, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Article, domain.Article](arts, func(idx int, src dao.Article) domain.Article {
+		return repo.toDomain(src)
+	}), nil
+}
+
+func (repo *CachedArticleRepository) toEntity(art domain.Article) dao.Article {
+	return dao.Article{
+		Id:       art.Id,
+		Title:    art.Title,
+		Content:  art.Content,
+		AuthorId: art.Author.Id,
+		Status:   uint8(art.Status),
+	}
+}
+
+func (repo *CachedArticleRepository) toDomain(art dao.Article) domain.Article {
+	return domain.Article{
+		Id:      art.Id,
+		Title:   art.Title,
+		Content: art.Content,
+		Author: domain.Author{
+			Id: art.AuthorId,
+		},
+		Status:     domain.ArticleStatus(art.Status),
+		CreateTime: time.UnixMilli(art.Ctime),
+		UpdateTime: time.UnixMilli(art.Utime),
+	}
 }
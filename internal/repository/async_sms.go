@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository/dao"
+	"time"
+)
+
+var ErrWaitingSMSNotFound = dao.ErrWaitingSMSNotFound
+
+type AsyncSMSRepository interface {
+	Add(ctx context.Context, s domain.AsyncSMS) error
+	// PreemptWaitingSMS 抢占一条可以重试的短信，没有就返回 ErrWaitingSMSNotFound
+	PreemptWaitingSMS(ctx context.Context) (domain.AsyncSMS, error)
+	// Delete 发送成功或者放弃重试之后删掉
+	Delete(ctx context.Context, id int64) error
+	// MarkFailed 按照 s 里面的号码和参数更新，等到 nextTime 再重试
+	MarkFailed(ctx context.Context, s domain.AsyncSMS, nextTime time.Time) error
+}
+
+type asyncSMSRepository struct {
+	dao dao.AsyncSMSDAO
+}
+
+func NewAsyncSMSRepository(dao dao.AsyncSMSDAO) AsyncSMSRepository {
+	return &asyncSMSRepository{
+		dao: dao,
+	}
+}
+
+// smsConfig 存到 dao.AsyncSMS.Config 里面的内容
+type smsConfig struct {
+	TplId   string
+	Args    []string
+	Numbers []string
+}
+
+func (r *asyncSMSRepository) Add(ctx context.Context, s domain.AsyncSMS) error {
+	cfg, err := r.marshalConfig(s)
+	if err != nil {
+		return err
+	}
+	return r.dao.Insert(ctx, dao.AsyncSMS{
+		Config:   cfg,
+		RetryMax: s.RetryMax,
+	})
+}
+
+func (r *asyncSMSRepository) PreemptWaitingSMS(ctx context.Context) (domain.AsyncSMS, error) {
+	s, err := r.dao.GetWaitingSMS(ctx)
+	if err != nil {
+		return domain.AsyncSMS{}, err
+	}
+	var cfg smsConfig
+	if err = json.Unmarshal([]byte(s.Config), &cfg); err != nil {
+		return domain.AsyncSMS{}, err
+	}
+	return domain.AsyncSMS{
+		Id:       s.Id,
+		TplId:    cfg.TplId,
+		Args:     cfg.Args,
+		Numbers:  cfg.Numbers,
+		RetryCnt: s.RetryCnt,
+		RetryMax: s.RetryMax,
+	}, nil
+}
+
+func (r *asyncSMSRepository) Delete(ctx context.Context, id int64) error {
+	return r.dao.Delete(ctx, id)
+}
+
+func (r *asyncSMSRepository) MarkFailed(ctx context.Context, s domain.AsyncSMS, nextTime time.Time) error {
+	cfg, err := r.marshalConfig(s)
+	if err != nil {
+		return err
+	}
+	return r.dao.MarkFailed(ctx, s.Id, cfg, nextTime)
+}
+
+func (r *asyncSMSRepository) marshalConfig(s domain.AsyncSMS) (string, error) {
+	cfg, err := json.Marshal(smsConfig{
+		TplId:   s.TplId,
+		Args:    s.Args,
+		Numbers: s.Numbers,
+	})
+	return string(cfg), err
+}
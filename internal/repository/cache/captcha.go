@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// CaptchaCache 图形验证码的答案和触发验证码的请求计数
+type CaptchaCache interface {
+	Set(ctx context.Context, id string, answer string, expiration time.Duration) error
+	// GetDel 取出来就删掉，一个验证码只能用一次，不存在返回 ErrKeyNotExist
+	GetDel(ctx context.Context, id string) (string, error)
+	// IncrHit 在 window 内累加 scene 下 ip 的请求次数，window 从第一次请求开始算
+	IncrHit(ctx context.Context, scene string, ip string, window time.Duration) (int64, error)
+}
+
+type RedisCaptchaCache struct {
+	cmd redis.Cmdable
+}
+
+func NewRedisCaptchaCache(cmd redis.Cmdable) CaptchaCache {
+	return &RedisCaptchaCache{
+		cmd: cmd,
+	}
+}
+
+func (c *RedisCaptchaCache) Set(ctx context.Context, id string, answer string, expiration time.Duration) error {
+	return c.cmd.Set(ctx, c.key(id), answer, expiration).Err()
+}
+
+func (c *RedisCaptchaCache) GetDel(ctx context.Context, id string) (string, error) {
+	// 用事务而不是 GETDEL，低版本的 Redis 也能用
+	pipe := c.cmd.TxPipeline()
+	get := pipe.Get(ctx, c.key(id))
+	pipe.Del(ctx, c.key(id))
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return "", err
+	}
+	return get.Val(), nil
+}
+
+func (c *RedisCaptchaCache) IncrHit(ctx context.Context, scene string, ip string, window time.Duration) (int64, error) {
+	// 和登录失败计数是一样的逻辑
+	return c.cmd.Eval(ctx, luaIncrFailure, []string{c.hitKey(scene, ip)}, int64(window.Seconds())).Int64()
+}
+
+func (c *RedisCaptchaCache) key(id string) string {
+	return fmt.Sprintf("captcha:%s", id)
+}
+
+func (c *RedisCaptchaCache) hitKey(scene string, ip string) string {
+	return fmt.Sprintf("captcha:hit:%s:%s", scene, ip)
+}
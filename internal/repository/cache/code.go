@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"github.com/skcheng003/webook/internal/domain"
 )
 
 var (
@@ -19,7 +20,7 @@ var (
 )
 
 type CodeCache interface {
-	Set(ctx context.Context, biz string, phone string, code string) error
+	Set(ctx context.Context, biz string, phone string, code string, policy domain.CodePolicy) error
 	Verify(ctx context.Context, biz string, phone string, inputCode string) (bool, error)
 }
 
@@ -35,12 +36,14 @@ func NewRedisCodeCache(cmd redis.Cmdable) CodeCache {
 }
 
 // Set 如果该手机在该业务场景下，验证码不存在（都已经过期），那么发送
-// 如果已经有一个验证码，但是发出去已经一分钟了，允许重发
+// 如果已经有一个验证码，但是发出去已经超过重发间隔了，允许重发
 // 如果已经有一个验证码，但是没有过期时间，说明有不知名错误
-// 如果已经有一个验证码，但是发出去不到一分钟，不允许重发
-// 验证码有效期 10 分钟
-func (c *RedisCodeCache) Set(ctx context.Context, biz string, phone string, code string) error {
-	res, err := c.cache.Eval(ctx, luaSetCode, []string{c.key(biz, phone)}, code).Int()
+// 如果已经有一个验证码，但是发出去还不到重发间隔，不允许重发
+// 有效期、重发间隔和验证次数都由 policy 决定
+func (c *RedisCodeCache) Set(ctx context.Context, biz string, phone string,
+	code string, policy domain.CodePolicy) error {
+	res, err := c.cache.Eval(ctx, luaSetCode, []string{c.key(biz, phone)}, code,
+		int64(policy.Expiration.Seconds()), int64(policy.ResendInterval.Seconds()), policy.MaxAttempts).Int()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+var (
+	//go:embed lua/incr_code_quota.lua
+	luaIncrCodeQuota string
+	//go:embed lua/decr_code_quota.lua
+	luaDecrCodeQuota     string
+	ErrCodeQuotaExceeded = errors.New("今天发送验证码的次数太多")
+)
+
+// CodeQuotaLimit 每天的发送上限，0 表示不限制
+type CodeQuotaLimit struct {
+	// PhoneBiz 同一个手机号在同一个业务
+	PhoneBiz int
+	// Phone 同一个手机号所有业务加起来
+	Phone int
+	// IP 同一个 IP 所有业务加起来
+	IP int
+}
+
+// CodeQuotaCache 按自然日统计验证码的发送次数，防止短信轰炸
+type CodeQuotaCache interface {
+	// Incr 所有计数都没有超过上限才会一起加一，否则返回 ErrCodeQuotaExceeded。ip 为空不统计 IP
+	Incr(ctx context.Context, biz string, phone string, ip string, limit CodeQuotaLimit) error
+	// Decr 把 Incr 占用的次数还回去，用在最终没有发送的情况
+	Decr(ctx context.Context, biz string, phone string, ip string) error
+}
+
+type RedisCodeQuotaCache struct {
+	cmd redis.Cmdable
+	now func() time.Time
+}
+
+func NewRedisCodeQuotaCache(cmd redis.Cmdable) CodeQuotaCache {
+	return &RedisCodeQuotaCache{
+		cmd: cmd,
+		now: time.Now,
+	}
+}
+
+func (c *RedisCodeQuotaCache) Incr(ctx context.Context, biz string, phone string,
+	ip string, limit CodeQuotaLimit) error {
+	keys := c.keys(biz, phone, ip)
+	args := []any{limit.PhoneBiz, limit.Phone}
+	if ip != "" {
+		args = append(args, limit.IP)
+	}
+	// key 里面带了日期，过期时间只是为了清理，比一天长一点就行
+	args = append(args, int64((time.Hour * 25).Seconds()))
+	res, err := c.cmd.Eval(ctx, luaIncrCodeQuota, keys, args...).Int()
+	if err != nil {
+		return err
+	}
+	if res != 0 {
+		return ErrCodeQuotaExceeded
+	}
+	return nil
+}
+
+func (c *RedisCodeQuotaCache) Decr(ctx context.Context, biz string, phone string, ip string) error {
+	return c.cmd.Eval(ctx, luaDecrCodeQuota, c.keys(biz, phone, ip)).Err()
+}
+
+func (c *RedisCodeQuotaCache) keys(biz string, phone string, ip string) []string {
+	day := c.now().Format("20060102")
+	keys := []string{
+		fmt.Sprintf("code_quota:%s:phone:%s:%s", day, biz, phone),
+		fmt.Sprintf("code_quota:%s:phone:%s", day, phone),
+	}
+	if ip != "" {
+		keys = append(keys, fmt.Sprintf("code_quota:%s:ip:%s", day, ip))
+	}
+	return keys
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/skcheng003/webook/internal/domain"
+	"time"
+)
+
+// ExportTaskCache 导出任务的状态，过期了任务也就没有意义了，所以只放 redis
+type ExportTaskCache interface {
+	Get(ctx context.Context, uid int64) (domain.ExportTask, error)
+	Set(ctx context.Context, task domain.ExportTask, expiration time.Duration) error
+}
+
+type RedisExportTaskCache struct {
+	cmd redis.Cmdable
+}
+
+func NewRedisExportTaskCache(cmd redis.Cmdable) ExportTaskCache {
+	return &RedisExportTaskCache{
+		cmd: cmd,
+	}
+}
+
+func (c *RedisExportTaskCache) Get(ctx context.Context, uid int64) (domain.ExportTask, error) {
+	val, err := c.cmd.Get(ctx, c.key(uid)).Bytes()
+	if err != nil {
+		return domain.ExportTask{}, err
+	}
+	var task domain.ExportTask
+	err = json.Unmarshal(val, &task)
+	return task, err
+}
+
+func (c *RedisExportTaskCache) Set(ctx context.Context, task domain.ExportTask, expiration time.Duration) error {
+	val, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return c.cmd.Set(ctx, c.key(task.Uid), val, expiration).Err()
+}
+
+func (c *RedisExportTaskCache) key(uid int64) string {
+	return fmt.Sprintf("user:export:%d", uid)
+}
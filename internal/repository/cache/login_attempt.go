@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+var (
+	//go:embed lua/incr_failure.lua
+	luaIncrFailure string
+	//go:embed lua/lock_user.lua
+	luaLockUser string
+)
+
+// LoginAttemptCache 记录登录失败次数和锁定状态
+type LoginAttemptCache interface {
+	// IncrFailure 分别累加账号和 IP 的失败次数，uid 为 0 说明账号不存在，只统计 IP
+	IncrFailure(ctx context.Context, uid int64, ip string, window time.Duration) (int64, int64, error)
+	// LockUser 锁定账号，返回这次锁定的时长
+	LockUser(ctx context.Context, uid int64, base time.Duration, max time.Duration) (time.Duration, error)
+	LockIP(ctx context.Context, ip string, duration time.Duration) error
+	// UserLockTTL 账号剩余的锁定时间，没有锁定返回 0
+	UserLockTTL(ctx context.Context, uid int64) (time.Duration, error)
+	IPLockTTL(ctx context.Context, ip string) (time.Duration, error)
+	ResetFailure(ctx context.Context, uid int64) error
+	UnlockUser(ctx context.Context, uid int64) error
+}
+
+type RedisLoginAttemptCache struct {
+	cmd redis.Cmdable
+	// 锁定次数保留的时间
+	levelExpiration time.Duration
+}
+
+func NewRedisLoginAttemptCache(cmd redis.Cmdable) LoginAttemptCache {
+	return &RedisLoginAttemptCache{
+		cmd:             cmd,
+		levelExpiration: time.Hour * 24,
+	}
+}
+
+func (c *RedisLoginAttemptCache) IncrFailure(ctx context.Context, uid int64,
+	ip string, window time.Duration) (int64, int64, error) {
+	seconds := int64(window.Seconds())
+	ipCnt, err := c.cmd.Eval(ctx, luaIncrFailure, []string{c.ipFailureKey(ip)}, seconds).Int64()
+	if err != nil {
+		return 0, 0, err
+	}
+	if uid == 0 {
+		return 0, ipCnt, nil
+	}
+	uidCnt, err := c.cmd.Eval(ctx, luaIncrFailure, []string{c.userFailureKey(uid)}, seconds).Int64()
+	return uidCnt, ipCnt, err
+}
+
+func (c *RedisLoginAttemptCache) LockUser(ctx context.Context, uid int64,
+	base time.Duration, max time.Duration) (time.Duration, error) {
+	seconds, err := c.cmd.Eval(ctx, luaLockUser,
+		[]string{c.userLockKey(uid), c.userLevelKey(uid), c.userFailureKey(uid)},
+		int64(base.Seconds()), int64(max.Seconds()), int64(c.levelExpiration.Seconds())).Int64()
+	return time.Duration(seconds) * time.Second, err
+}
+
+func (c *RedisLoginAttemptCache) LockIP(ctx context.Context, ip string, duration time.Duration) error {
+	pipe := c.cmd.TxPipeline()
+	pipe.Set(ctx, c.ipLockKey(ip), 1, duration)
+	pipe.Del(ctx, c.ipFailureKey(ip))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisLoginAttemptCache) UserLockTTL(ctx context.Context, uid int64) (time.Duration, error) {
+	return c.ttl(ctx, c.userLockKey(uid))
+}
+
+func (c *RedisLoginAttemptCache) IPLockTTL(ctx context.Context, ip string) (time.Duration, error) {
+	return c.ttl(ctx, c.ipLockKey(ip))
+}
+
+func (c *RedisLoginAttemptCache) ResetFailure(ctx context.Context, uid int64) error {
+	return c.cmd.Del(ctx, c.userFailureKey(uid)).Err()
+}
+
+func (c *RedisLoginAttemptCache) UnlockUser(ctx context.Context, uid int64) error {
+	return c.cmd.Del(ctx, c.userLockKey(uid), c.userLevelKey(uid), c.userFailureKey(uid)).Err()
+}
+
+func (c *RedisLoginAttemptCache) ttl(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.cmd.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// key 不存在的时候 TTL 返回的是负数
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (c *RedisLoginAttemptCache) userFailureKey(uid int64) string {
+	return fmt.Sprintf("login:fail:uid:%d", uid)
+}
+
+func (c *RedisLoginAttemptCache) ipFailureKey(ip string) string {
+	return fmt.Sprintf("login:fail:ip:%s", ip)
+}
+
+func (c *RedisLoginAttemptCache) userLockKey(uid int64) string {
+	return fmt.Sprintf("login:lock:uid:%d", uid)
+}
+
+func (c *RedisLoginAttemptCache) userLevelKey(uid int64) string {
+	return fmt.Sprintf("login:lock:level:%d", uid)
+}
+
+func (c *RedisLoginAttemptCache) ipLockKey(ip string) string {
+	return fmt.Sprintf("login:lock:ip:%s", ip)
+}
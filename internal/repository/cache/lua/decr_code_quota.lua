@@ -0,0 +1,9 @@
+-- KEYS 是 incr_code_quota.lua 里面加过一的计数，这里还回去
+-- 已经过期或者是 0 的不处理，避免减成负数
+for _, key in ipairs(KEYS) do
+    local cnt = tonumber(redis.call("get", key) or "0")
+    if cnt > 0 then
+        redis.call("decr", key)
+    end
+end
+return 0
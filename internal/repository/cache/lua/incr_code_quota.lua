@@ -0,0 +1,16 @@
+-- KEYS 是各个计数的 key，ARGV[i] 是 KEYS[i] 的上限，0 表示不限制，最后一个 ARGV 是过期时间
+-- 有一个超过上限就都不加，返回超过上限的是第几个 key
+local ttl = tonumber(ARGV[#ARGV])
+for i, key in ipairs(KEYS) do
+    local limit = tonumber(ARGV[i])
+    local cnt = tonumber(redis.call("get", key) or "0")
+    if limit > 0 and cnt >= limit then
+        return i
+    end
+end
+for _, key in ipairs(KEYS) do
+    if redis.call("incr", key) == 1 then
+        redis.call("expire", key, ttl)
+    end
+end
+return 0
@@ -0,0 +1,8 @@
+-- 失败次数在一个窗口内累加，窗口从第一次失败开始算
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local cnt = redis.call("incr", key)
+if cnt == 1 then
+    redis.call("expire", key, window)
+end
+return cnt
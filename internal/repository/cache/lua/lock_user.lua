@@ -0,0 +1,20 @@
+-- 锁定账号，每多锁一次时长翻倍，直到上限
+local lockKey = KEYS[1]
+-- 锁定的次数，决定这次锁多久
+local levelKey = KEYS[2]
+-- 失败次数，锁定之后重新计数
+local cntKey = KEYS[3]
+local base = tonumber(ARGV[1])
+local max = tonumber(ARGV[2])
+-- 锁定次数保留多久，过了这么久没有再被锁定就从头计算
+local levelTTL = tonumber(ARGV[3])
+
+local level = redis.call("incr", levelKey)
+redis.call("expire", levelKey, levelTTL)
+local ttl = base * 2 ^ (level - 1)
+if ttl > max then
+    ttl = max
+end
+redis.call("set", lockKey, level, "EX", ttl)
+redis.call("del", cntKey)
+return ttl
@@ -3,22 +3,24 @@ local key = KEYS[1]
 -- 使用次数，也就是验证次数
 local cntKey = key..":cnt"
 local val = ARGV[1]
--- 验证码的有效时间是十分钟，600 秒
+-- 有效期、重发间隔和最多验证次数，由业务场景的策略决定
+local expiration = tonumber(ARGV[2])
+local interval = tonumber(ARGV[3])
+local attempts = tonumber(ARGV[4])
 local ttl = tonumber(redis.call("ttl", key))
 
 -- -1 是 key 存在，但是没有过期时间
 if ttl == -1 then
     -- 有人误操作，导致 key 冲突
     return -2
-    -- -2 是 key 不存在，ttl < 540 是发了一个验证码，已经超过一分钟了，可以重新发送
-elseif ttl == -2 or ttl < 540 then
-    --     后续如果验证码有不同的过期时间，要在这里优化
+    -- -2 是 key 不存在，剩余时间小于 expiration - interval 说明发出去已经超过重发间隔了，可以重新发送
+elseif ttl == -2 or ttl < expiration - interval then
     redis.call("set", key, val)
-    redis.call("expire", key, 600)
-    redis.call("set", cntKey, 3)
-    redis.call("expire", cntKey, 600)
+    redis.call("expire", key, expiration)
+    redis.call("set", cntKey, attempts)
+    redis.call("expire", cntKey, expiration)
     return 0
 else
-    -- 已经发送了一个验证码，但是还不到一分钟
+    -- 已经发送了一个验证码，但是还不到重发间隔
     return -1
-end
\ No newline at end of file
+end
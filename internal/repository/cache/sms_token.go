@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// SMSTokenCache 吊销的短信网关 token，按照 jti 记录
+type SMSTokenCache interface {
+	// Revoke 记录保留 ttl 这么久，不能比 token 本身的有效期短
+	Revoke(ctx context.Context, jti string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type RedisSMSTokenCache struct {
+	cmd redis.Cmdable
+}
+
+func NewRedisSMSTokenCache(cmd redis.Cmdable) SMSTokenCache {
+	return &RedisSMSTokenCache{
+		cmd: cmd,
+	}
+}
+
+func (c *RedisSMSTokenCache) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	return c.cmd.Set(ctx, c.key(jti), 1, ttl).Err()
+}
+
+func (c *RedisSMSTokenCache) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	cnt, err := c.cmd.Exists(ctx, c.key(jti)).Result()
+	return cnt > 0, err
+}
+
+func (c *RedisSMSTokenCache) key(jti string) string {
+	return fmt.Sprintf("sms_gateway:revoked:%s", jti)
+}
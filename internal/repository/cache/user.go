@@ -15,6 +15,7 @@ var ErrKeyNotExist = redis.Nil
 type UserCache interface {
 	Get(ctx context.Context, id int64) (domain.User, error)
 	Set(ctx context.Context, u domain.User) error
+	Delete(ctx context.Context, id int64) error
 }
 
 // RedisUserCache Programing with interface
@@ -57,6 +58,11 @@ func (cache *RedisUserCache) Set(ctx context.Context, u domain.User) error {
 	return err
 }
 
+// Delete removes user info from cache
+func (cache *RedisUserCache) Delete(ctx context.Context, id int64) error {
+	return cache.client.Del(ctx, cache.key(id)).Err()
+}
+
 // key generates key for user info
 func (cache *RedisUserCache) key(id int64) string {
 	return fmt.Sprintf("user:info:%d", id)
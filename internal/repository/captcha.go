@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/repository/cache"
+	"time"
+)
+
+var ErrCaptchaNotFound = cache.ErrKeyNotExist
+
+type CaptchaRepository interface {
+	Store(ctx context.Context, id string, answer string, expiration time.Duration) error
+	// Take 取出答案并且删掉
+	Take(ctx context.Context, id string) (string, error)
+	IncrHit(ctx context.Context, scene string, ip string, window time.Duration) (int64, error)
+}
+
+type CachedCaptchaRepository struct {
+	cache cache.CaptchaCache
+}
+
+func NewCachedCaptchaRepository(cache cache.CaptchaCache) CaptchaRepository {
+	return &CachedCaptchaRepository{
+		cache: cache,
+	}
+}
+
+func (repo *CachedCaptchaRepository) Store(ctx context.Context, id string,
+	answer string, expiration time.Duration) error {
+	return repo.cache.Set(ctx, id, answer, expiration)
+}
+
+func (repo *CachedCaptchaRepository) Take(ctx context.Context, id string) (string, error) {
+	return repo.cache.GetDel(ctx, id)
+}
+
+func (repo *CachedCaptchaRepository) IncrHit(ctx context.Context, scene string,
+	ip string, window time.Duration) (int64, error) {
+	return repo.cache.IncrHit(ctx, scene, ip, window)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"github.com/skcheng003/webook/internal/domain"
 	"github.com/skcheng003/webook/internal/repository/cache"
 )
 
@@ -11,7 +12,7 @@ var (
 )
 
 type CodeRepository interface {
-	Store(ctx context.Context, biz string, phone string, code string) error
+	Store(ctx context.Context, biz string, phone string, code string, policy domain.CodePolicy) error
 	Verify(ctx context.Context, biz string, phone string, inputCode string) (bool, error)
 }
 
@@ -25,8 +26,9 @@ func NewCachedCodeRepository(cache cache.CodeCache) CodeRepository {
 	}
 }
 
-func (repo *CachedCodeRepository) Store(ctx context.Context, biz string, phone string, code string) error {
-	return repo.cache.Set(ctx, biz, phone, code)
+func (repo *CachedCodeRepository) Store(ctx context.Context, biz string, phone string,
+	code string, policy domain.CodePolicy) error {
+	return repo.cache.Set(ctx, biz, phone, code, policy)
 }
 
 func (repo *CachedCodeRepository) Verify(ctx context.Context, biz string, phone string, inputCode string) (bool, error) {
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/repository/cache"
+)
+
+var ErrCodeQuotaExceeded = cache.ErrCodeQuotaExceeded
+
+type CodeQuotaLimit = cache.CodeQuotaLimit
+
+type CodeQuotaRepository interface {
+	Incr(ctx context.Context, biz string, phone string, ip string, limit CodeQuotaLimit) error
+	Decr(ctx context.Context, biz string, phone string, ip string) error
+}
+
+type CachedCodeQuotaRepository struct {
+	cache cache.CodeQuotaCache
+}
+
+func NewCachedCodeQuotaRepository(cache cache.CodeQuotaCache) CodeQuotaRepository {
+	return &CachedCodeQuotaRepository{
+		cache: cache,
+	}
+}
+
+func (repo *CachedCodeQuotaRepository) Incr(ctx context.Context, biz string, phone string,
+	ip string, limit CodeQuotaLimit) error {
+	return repo.cache.Incr(ctx, biz, phone, ip, limit)
+}
+
+func (repo *CachedCodeQuotaRepository) Decr(ctx context.Context, biz string, phone string, ip string) error {
+	return repo.cache.Decr(ctx, biz, phone, ip)
+}
@@ -0,0 +1,144 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrArticleNoFound = gorm.ErrRecordNotFound
+	// ErrArticleNotOwned 文章不存在或者不是这个作者的
+	ErrArticleNotOwned = errors.New("文章不存在或者不属于该作者")
+	// ErrArticleModerated 文章被管理员下架了，作者不能再改
+	ErrArticleModerated = errors.New("文章已经被管理员下架")
+)
+
+// articleStatusModerated 和 domain.ArticleStatusModerated 一致，
+// 作者的所有更新都带上这个条件，管理员下架之后作者不能自己改回去
+const articleStatusModerated uint8 = 4
+
+type ArticleDao interface {
+	Insert(ctx context.Context, art Article) (int64, error)
+	UpdateById(ctx context.Context, art Article) error
+	UpdateStatus(ctx context.Context, id int64, status uint8) error
+	UpdateStatusByAuthor(ctx context.Context, authorId int64, id int64, status uint8) error
+	FindById(ctx context.Context, id int64) (Article, error)
+	// UpdateStatusByAuthorId 修改某个作者的全部文章，注销账号的时候用
+	UpdateStatusByAuthorId(ctx context.Context, authorId int64, status uint8) error
+	DeleteByAuthorId(ctx context.Context, authorId int64) error
+	FindByAuthorId(ctx context.Context, authorId int64, offset int, limit int) ([]Article, error)
+}
+
+type GORMArticleDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMArticleDAO(db *gorm.DB) ArticleDao {
+	return &GORMArticleDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMArticleDAO) Insert(ctx context.Context, art Article) (int64, error) {
+	now := time.Now().UnixMilli()
+	art.Ctime = now
+	art.Utime = now
+	err := dao.db.WithContext(ctx).Create(&art).Error
+	return art.Id, err
+}
+
+// UpdateById 带上 author_id 作为条件，防止改了别人的文章
+func (dao *GORMArticleDAO) UpdateById(ctx context.Context, art Article) error {
+	res := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("id = ? AND author_id = ? AND status <> ?", art.Id, art.AuthorId, articleStatusModerated).
+		Updates(map[string]any{
+			"title":   art.Title,
+			"content": art.Content,
+			"status":  art.Status,
+			"utime":   time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return dao.notUpdated(ctx, art.AuthorId, art.Id)
+	}
+	return nil
+}
+
+func (dao *GORMArticleDAO) UpdateStatus(ctx context.Context, id int64, status uint8) error {
+	res := dao.db.WithContext(ctx).Model(&Article{}).Where("id = ?", id).
+		Updates(map[string]any{"status": status, "utime": time.Now().UnixMilli()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrArticleNoFound
+	}
+	return nil
+}
+
+func (dao *GORMArticleDAO) UpdateStatusByAuthor(ctx context.Context, authorId int64, id int64, status uint8) error {
+	res := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("id = ? AND author_id = ? AND status <> ?", id, authorId, articleStatusModerated).
+		Updates(map[string]any{"status": status, "utime": time.Now().UnixMilli()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return dao.notUpdated(ctx, authorId, id)
+	}
+	return nil
+}
+
+// notUpdated 作者更新没有影响任何行的时候，区分是被下架了还是不是自己的文章
+func (dao *GORMArticleDAO) notUpdated(ctx context.Context, authorId int64, id int64) error {
+	var art Article
+	err := dao.db.WithContext(ctx).Select("status").
+		Where("id = ? AND author_id = ?", id, authorId).First(&art).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrArticleNotOwned
+	case err != nil:
+		return err
+	case art.Status == articleStatusModerated:
+		return ErrArticleModerated
+	}
+	return ErrArticleNotOwned
+}
+
+func (dao *GORMArticleDAO) FindById(ctx context.Context, id int64) (Article, error) {
+	var art Article
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&art).Error
+	return art, err
+}
+
+func (dao *GORMArticleDAO) UpdateStatusByAuthorId(ctx context.Context, authorId int64, status uint8) error {
+	return dao.db.WithContext(ctx).Model(&Article{}).
+		Where("author_id = ? AND status <> ?", authorId, articleStatusModerated).
+		Updates(map[string]any{"status": status, "utime": time.Now().UnixMilli()}).Error
+}
+
+func (dao *GORMArticleDAO) DeleteByAuthorId(ctx context.Context, authorId int64) error {
+	return dao.db.WithContext(ctx).Where("author_id = ?", authorId).Delete(&Article{}).Error
+}
+
+func (dao *GORMArticleDAO) FindByAuthorId(ctx context.Context, authorId int64,
+	offset int, limit int) ([]Article, error) {
+	var res []Article
+	err := dao.db.WithContext(ctx).Where("author_id = ?", authorId).
+		Order("id").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+type Article struct {
+	Id       int64  `gorm:"primaryKey, autoIncrement"`
+	Title    string `gorm:"type:varchar(4096)"`
+	Content  string `gorm:"type:blob"`
+	AuthorId int64  `gorm:"index"`
+	Status   uint8
+	Ctime    int64
+	Utime    int64
+}
@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrWaitingSMSNotFound = gorm.ErrRecordNotFound
+
+// preemptTimeout 抢占之后多久没有上报结果，别的实例就可以再抢
+const preemptTimeout = time.Minute
+
+// AsyncSMSDAO 表里面只有等着重试的短信，发送成功或者重试次数用完就删掉，
+// 短信参数不会一直留在数据库里面
+type AsyncSMSDAO interface {
+	Insert(ctx context.Context, s AsyncSMS) error
+	// GetWaitingSMS 抢占一条到了重试时间的短信
+	GetWaitingSMS(ctx context.Context) (AsyncSMS, error)
+	Delete(ctx context.Context, id int64) error
+	// MarkFailed 重试次数加一，等到 nextTime 再重试。
+	// config 是还要重试的内容，部分号码成功之后只剩下失败的号码
+	MarkFailed(ctx context.Context, id int64, config string, nextTime time.Time) error
+}
+
+type GORMAsyncSMSDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMAsyncSMSDAO(db *gorm.DB) AsyncSMSDAO {
+	return &GORMAsyncSMSDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMAsyncSMSDAO) Insert(ctx context.Context, s AsyncSMS) error {
+	now := time.Now().UnixMilli()
+	s.Ctime = now
+	s.Utime = now
+	s.NextTime = now
+	return dao.db.WithContext(ctx).Create(&s).Error
+}
+
+func (dao *GORMAsyncSMSDAO) GetWaitingSMS(ctx context.Context) (AsyncSMS, error) {
+	var s AsyncSMS
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("next_time <= ?", now.UnixMilli()).
+			Order("next_time").First(&s).Error
+		if err != nil {
+			return err
+		}
+		// 推迟下一次重试的时间，相当于占住这条记录，
+		// 进程崩了也能在超时之后被别人重新抢到
+		return tx.Model(&AsyncSMS{}).Where("id = ?", s.Id).
+			Updates(map[string]any{
+				"next_time": now.Add(preemptTimeout).UnixMilli(),
+				"utime":     now.UnixMilli(),
+			}).Error
+	})
+	return s, err
+}
+
+func (dao *GORMAsyncSMSDAO) Delete(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Where("id = ?", id).Delete(&AsyncSMS{}).Error
+}
+
+func (dao *GORMAsyncSMSDAO) MarkFailed(ctx context.Context, id int64, config string, nextTime time.Time) error {
+	return dao.db.WithContext(ctx).Model(&AsyncSMS{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"config":    config,
+			"retry_cnt": gorm.Expr("retry_cnt + 1"),
+			"next_time": nextTime.UnixMilli(),
+			"utime":     time.Now().UnixMilli(),
+		}).Error
+}
+
+type AsyncSMS struct {
+	Id int64 `gorm:"primaryKey, autoIncrement"`
+	// Config 模板、参数和手机号，JSON 格式
+	Config   string `gorm:"type:text"`
+	RetryCnt int
+	RetryMax int
+	NextTime int64 `gorm:"index"`
+	Ctime    int64
+	Utime    int64
+}
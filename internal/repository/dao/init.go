@@ -4,5 +4,5 @@ import "gorm.io/gorm"
 
 // InitTable 建表，bad design
 func InitTable(db *gorm.DB) error {
-	return db.AutoMigrate(&User{})
+	return db.AutoMigrate(&User{}, &UserTOTP{}, &RecoveryCode{}, &LoginLog{}, &Article{}, &AsyncSMS{}, &SMSDelivery{})
 }
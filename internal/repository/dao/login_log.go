@@ -0,0 +1,66 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+// LoginLogDao 审计日志只能追加，不提供修改和删除
+type LoginLogDao interface {
+	Insert(ctx context.Context, l LoginLog) error
+	FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]LoginLog, error)
+	// CountSuccess 历史成功登录的总次数，以及其中同设备、同地区的次数
+	CountSuccess(ctx context.Context, uid int64, device string, country string) (int64, int64, int64, error)
+}
+
+type GORMLoginLogDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMLoginLogDAO(db *gorm.DB) LoginLogDao {
+	return &GORMLoginLogDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMLoginLogDAO) Insert(ctx context.Context, l LoginLog) error {
+	l.Ctime = time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Create(&l).Error
+}
+
+func (dao *GORMLoginLogDAO) FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]LoginLog, error) {
+	var res []LoginLog
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).
+		Order("ctime DESC").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMLoginLogDAO) CountSuccess(ctx context.Context, uid int64,
+	device string, country string) (int64, int64, int64, error) {
+	var res struct {
+		Total       int64
+		SameDevice  int64
+		SameCountry int64
+	}
+	err := dao.db.WithContext(ctx).Model(&LoginLog{}).
+		Select("COUNT(*) AS total, "+
+			"COALESCE(SUM(device = ?), 0) AS same_device, "+
+			"COALESCE(SUM(country = ?), 0) AS same_country", device, country).
+		Where("uid = ? AND outcome = ?", uid, "success").
+		Scan(&res).Error
+	return res.Total, res.SameDevice, res.SameCountry, err
+}
+
+type LoginLog struct {
+	Id         int64  `gorm:"primaryKey, autoIncrement"`
+	Uid        int64  `gorm:"index:idx_uid_ctime"`
+	IP         string `gorm:"type:varchar(64)"`
+	UserAgent  string `gorm:"type:varchar(512)"`
+	Method     string `gorm:"type:varchar(16)"`
+	Outcome    string `gorm:"type:varchar(16)"`
+	Device     string `gorm:"type:varchar(64)"`
+	Country    string `gorm:"type:varchar(8)"`
+	Suspicious bool
+	Ctime      int64 `gorm:"index:idx_uid_ctime"`
+}
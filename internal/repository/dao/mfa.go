@@ -0,0 +1,119 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrMFANoFound = gorm.ErrRecordNotFound
+
+type MFADao interface {
+	Upsert(ctx context.Context, t UserTOTP) error
+	FindByUid(ctx context.Context, uid int64) (UserTOTP, error)
+	Enable(ctx context.Context, uid int64, hashes []string) error
+	Delete(ctx context.Context, uid int64) error
+	UseRecoveryCode(ctx context.Context, uid int64, hash string) (bool, error)
+	UseStep(ctx context.Context, uid int64, step int64) (bool, error)
+}
+
+type GORMMFADAO struct {
+	db *gorm.DB
+}
+
+func NewGORMMFADAO(db *gorm.DB) MFADao {
+	return &GORMMFADAO{
+		db: db,
+	}
+}
+
+// Upsert 重新绑定时覆盖尚未开启的密钥
+func (dao *GORMMFADAO) Upsert(ctx context.Context, t UserTOTP) error {
+	now := time.Now().UnixMilli()
+	t.Ctime = now
+	t.Utime = now
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "uid"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"secret":  t.Secret,
+			"enabled": t.Enabled,
+			"utime":   now,
+		}),
+	}).Create(&t).Error
+}
+
+func (dao *GORMMFADAO) FindByUid(ctx context.Context, uid int64) (UserTOTP, error) {
+	var t UserTOTP
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).First(&t).Error
+	return t, err
+}
+
+// Enable 开启二次验证，同时替换掉旧的恢复码
+func (dao *GORMMFADAO) Enable(ctx context.Context, uid int64, hashes []string) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&UserTOTP{}).Where("uid = ?", uid).
+			Updates(map[string]any{"enabled": true, "utime": now}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("uid = ?", uid).Delete(&RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		codes := make([]RecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			codes = append(codes, RecoveryCode{Uid: uid, Hash: h, Ctime: now, Utime: now})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (dao *GORMMFADAO) Delete(ctx context.Context, uid int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("uid = ?", uid).Delete(&RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("uid = ?", uid).Delete(&UserTOTP{}).Error
+	})
+}
+
+// UseRecoveryCode 恢复码只能使用一次，用条件更新保证并发安全
+func (dao *GORMMFADAO) UseRecoveryCode(ctx context.Context, uid int64, hash string) (bool, error) {
+	res := dao.db.WithContext(ctx).Model(&RecoveryCode{}).
+		Where("uid = ? AND hash = ? AND used = ?", uid, hash, false).
+		Updates(map[string]any{"used": true, "utime": time.Now().UnixMilli()})
+	return res.RowsAffected > 0, res.Error
+}
+
+// UseStep 同一个时间窗口的验证码只能用一次，只有比上次用过的窗口新才更新成功
+func (dao *GORMMFADAO) UseStep(ctx context.Context, uid int64, step int64) (bool, error) {
+	res := dao.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("uid = ? AND last_step < ?", uid, step).
+		Updates(map[string]any{"last_step": step, "utime": time.Now().UnixMilli()})
+	return res.RowsAffected > 0, res.Error
+}
+
+// UserTOTP 用户绑定的身份验证器，密钥需要还原出来计算验证码，所以只能明文存储
+type UserTOTP struct {
+	Id      int64 `gorm:"primaryKey, autoIncrement"`
+	Uid     int64 `gorm:"unique"`
+	Secret  string
+	Enabled bool
+	// LastStep 最近一次验证通过的时间窗口序号，用来防重放
+	LastStep int64
+	Ctime    int64
+	Utime    int64
+}
+
+// RecoveryCode 恢复码只存 SHA-256 哈希
+type RecoveryCode struct {
+	Id    int64  `gorm:"primaryKey, autoIncrement"`
+	Uid   int64  `gorm:"index"`
+	Hash  string `gorm:"type:char(64)"`
+	Used  bool
+	Ctime int64
+	Utime int64
+}
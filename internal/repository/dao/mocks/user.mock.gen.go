@@ -0,0 +1,255 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/dao/user.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/dao/user.go -package=daomocks -destination=internal/repository/dao/mocks/user.mock.gen.go
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "github.com/skcheng003/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserDao is a mock of UserDao interface.
+type MockUserDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserDaoMockRecorder
+}
+
+// MockUserDaoMockRecorder is the mock recorder for MockUserDao.
+type MockUserDaoMockRecorder struct {
+	mock *MockUserDao
+}
+
+// NewMockUserDao creates a new mock instance.
+func NewMockUserDao(ctrl *gomock.Controller) *MockUserDao {
+	mock := &MockUserDao{ctrl: ctrl}
+	mock.recorder = &MockUserDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserDao) EXPECT() *MockUserDaoMockRecorder {
+	return m.recorder
+}
+
+// Anonymize mocks base method.
+func (m *MockUserDao) Anonymize(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Anonymize", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Anonymize indicates an expected call of Anonymize.
+func (mr *MockUserDaoMockRecorder) Anonymize(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Anonymize", reflect.TypeOf((*MockUserDao)(nil).Anonymize), ctx, uid)
+}
+
+// CancelDeletion mocks base method.
+func (m *MockUserDao) CancelDeletion(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelDeletion", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelDeletion indicates an expected call of CancelDeletion.
+func (mr *MockUserDaoMockRecorder) CancelDeletion(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelDeletion", reflect.TypeOf((*MockUserDao)(nil).CancelDeletion), ctx, uid)
+}
+
+// EditProfile mocks base method.
+func (m *MockUserDao) EditProfile(ctx context.Context, u dao.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EditProfile", ctx, u)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EditProfile indicates an expected call of EditProfile.
+func (mr *MockUserDaoMockRecorder) EditProfile(ctx, u any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditProfile", reflect.TypeOf((*MockUserDao)(nil).EditProfile), ctx, u)
+}
+
+// Enable mocks base method.
+func (m *MockUserDao) Enable(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockUserDaoMockRecorder) Enable(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockUserDao)(nil).Enable), ctx, uid)
+}
+
+// FindByEmail mocks base method.
+func (m *MockUserDao) FindByEmail(ctx context.Context, email string) (dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByEmail", ctx, email)
+	ret0, _ := ret[0].(dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByEmail indicates an expected call of FindByEmail.
+func (mr *MockUserDaoMockRecorder) FindByEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByEmail", reflect.TypeOf((*MockUserDao)(nil).FindByEmail), ctx, email)
+}
+
+// FindByPhone mocks base method.
+func (m *MockUserDao) FindByPhone(ctx context.Context, phone string) (dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByPhone", ctx, phone)
+	ret0, _ := ret[0].(dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByPhone indicates an expected call of FindByPhone.
+func (mr *MockUserDaoMockRecorder) FindByPhone(ctx, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByPhone", reflect.TypeOf((*MockUserDao)(nil).FindByPhone), ctx, phone)
+}
+
+// FindByUid mocks base method.
+func (m *MockUserDao) FindByUid(ctx context.Context, uid int64) (dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].(dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockUserDaoMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockUserDao)(nil).FindByUid), ctx, uid)
+}
+
+// FindDueDeletion mocks base method.
+func (m *MockUserDao) FindDueDeletion(ctx context.Context, now int64, limit int) ([]dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDueDeletion", ctx, now, limit)
+	ret0, _ := ret[0].([]dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDueDeletion indicates an expected call of FindDueDeletion.
+func (mr *MockUserDaoMockRecorder) FindDueDeletion(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDueDeletion", reflect.TypeOf((*MockUserDao)(nil).FindDueDeletion), ctx, now, limit)
+}
+
+// Insert mocks base method.
+func (m *MockUserDao) Insert(ctx context.Context, u dao.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, u)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockUserDaoMockRecorder) Insert(ctx, u any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockUserDao)(nil).Insert), ctx, u)
+}
+
+// Merge mocks base method.
+func (m *MockUserDao) Merge(ctx context.Context, from, to int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, from, to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockUserDaoMockRecorder) Merge(ctx, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockUserDao)(nil).Merge), ctx, from, to)
+}
+
+// ScheduleDeletion mocks base method.
+func (m *MockUserDao) ScheduleDeletion(ctx context.Context, uid, deleteAfter int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleDeletion", ctx, uid, deleteAfter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScheduleDeletion indicates an expected call of ScheduleDeletion.
+func (mr *MockUserDaoMockRecorder) ScheduleDeletion(ctx, uid, deleteAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleDeletion", reflect.TypeOf((*MockUserDao)(nil).ScheduleDeletion), ctx, uid, deleteAfter)
+}
+
+// UpdateEmail mocks base method.
+func (m *MockUserDao) UpdateEmail(ctx context.Context, uid int64, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEmail", ctx, uid, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEmail indicates an expected call of UpdateEmail.
+func (mr *MockUserDaoMockRecorder) UpdateEmail(ctx, uid, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmail", reflect.TypeOf((*MockUserDao)(nil).UpdateEmail), ctx, uid, email)
+}
+
+// UpdatePhone mocks base method.
+func (m *MockUserDao) UpdatePhone(ctx context.Context, uid int64, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePhone", ctx, uid, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePhone indicates an expected call of UpdatePhone.
+func (mr *MockUserDaoMockRecorder) UpdatePhone(ctx, uid, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePhone", reflect.TypeOf((*MockUserDao)(nil).UpdatePhone), ctx, uid, phone)
+}
+
+// UpdateRoles mocks base method.
+func (m *MockUserDao) UpdateRoles(ctx context.Context, uid int64, roles string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRoles", ctx, uid, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRoles indicates an expected call of UpdateRoles.
+func (mr *MockUserDaoMockRecorder) UpdateRoles(ctx, uid, roles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRoles", reflect.TypeOf((*MockUserDao)(nil).UpdateRoles), ctx, uid, roles)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockUserDao) UpdateStatus(ctx context.Context, uid int64, status uint8) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, uid, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockUserDaoMockRecorder) UpdateStatus(ctx, uid, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockUserDao)(nil).UpdateStatus), ctx, uid, status)
+}
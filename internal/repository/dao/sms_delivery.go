@@ -0,0 +1,66 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SMSDeliveryDAO interface {
+	Insert(ctx context.Context, ds []SMSDelivery) error
+	// UpdateBySerialNo 阿里云同一批号码的流水号是一样的，所以可能更新多条
+	UpdateBySerialNo(ctx context.Context, provider string, serialNo string, status string, reason string) error
+	FindByPhoneHash(ctx context.Context, hash string, offset int, limit int) ([]SMSDelivery, error)
+}
+
+type GORMSMSDeliveryDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMSMSDeliveryDAO(db *gorm.DB) SMSDeliveryDAO {
+	return &GORMSMSDeliveryDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMSMSDeliveryDAO) Insert(ctx context.Context, ds []SMSDelivery) error {
+	now := time.Now().UnixMilli()
+	for i := range ds {
+		ds[i].Ctime = now
+		ds[i].Utime = now
+	}
+	return dao.db.WithContext(ctx).Create(&ds).Error
+}
+
+func (dao *GORMSMSDeliveryDAO) UpdateBySerialNo(ctx context.Context, provider string,
+	serialNo string, status string, reason string) error {
+	return dao.db.WithContext(ctx).Model(&SMSDelivery{}).
+		Where("provider = ? AND serial_no = ?", provider, serialNo).
+		Updates(map[string]any{
+			"status": status,
+			"reason": reason,
+			"utime":  time.Now().UnixMilli(),
+		}).Error
+}
+
+func (dao *GORMSMSDeliveryDAO) FindByPhoneHash(ctx context.Context, hash string,
+	offset int, limit int) ([]SMSDelivery, error) {
+	var res []SMSDelivery
+	err := dao.db.WithContext(ctx).Where("phone_hash = ?", hash).
+		Order("ctime DESC").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// SMSDelivery 手机号只存打码之后的，查询用 HMAC 之后的 PhoneHash
+type SMSDelivery struct {
+	Id        int64  `gorm:"primaryKey, autoIncrement"`
+	Provider  string `gorm:"type:varchar(32);index:idx_provider_serial_no"`
+	TplId     string `gorm:"type:varchar(64)"`
+	Phone     string `gorm:"type:varchar(32)"`
+	PhoneHash string `gorm:"type:char(64);index:idx_phone_hash_ctime"`
+	Status    string `gorm:"type:varchar(16)"`
+	SerialNo  string `gorm:"type:varchar(128);index:idx_provider_serial_no"`
+	Reason    string `gorm:"type:varchar(256)"`
+	Ctime     int64  `gorm:"index:idx_phone_hash_ctime"`
+	Utime     int64
+}
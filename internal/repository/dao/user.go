@@ -6,12 +6,28 @@ import (
 	"errors"
 	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
 var (
 	ErrUserDuplicate = errors.New("email address conflict")
 	ErrUserNoFound   = gorm.ErrRecordNotFound
+	// ErrUserNotPendingDeletion 账号没有在注销冷静期内
+	ErrUserNotPendingDeletion = errors.New("user is not pending deletion")
+	// ErrUserNotDisabled 账号没有被禁用
+	ErrUserNotDisabled = errors.New("user is not disabled")
+	// ErrUserNotMergeable 两个账号里面有一个已经不能参与合并了
+	ErrUserNotMergeable = errors.New("user can not be merged")
+)
+
+// 和 domain.UserStatus 保持一致
+const (
+	StatusActive          uint8 = 0
+	StatusDisabled        uint8 = 1
+	StatusPendingDeletion uint8 = 3
+	StatusDeleted         uint8 = 4
+	StatusMerged          uint8 = 5
 )
 
 type UserDao interface {
@@ -20,6 +36,24 @@ type UserDao interface {
 	FindByUid(ctx context.Context, uid int64) (User, error)
 	Insert(ctx context.Context, u User) error
 	EditProfile(ctx context.Context, u User) error
+	UpdateStatus(ctx context.Context, uid int64, status uint8) error
+	// Enable 只能解禁被禁用的账号，否则会悄悄撤销用户的注销申请或者停用
+	Enable(ctx context.Context, uid int64) error
+	UpdateRoles(ctx context.Context, uid int64, roles string) error
+	// UpdatePhone 和 UpdateEmail 撞上唯一索引的时候返回 ErrUserDuplicate
+	UpdatePhone(ctx context.Context, uid int64, phone string) error
+	UpdateEmail(ctx context.Context, uid int64, email string) error
+	// ScheduleDeletion 标记为待删除，deleteAfter 是冷静期截止时间
+	ScheduleDeletion(ctx context.Context, uid int64, deleteAfter int64) error
+	// CancelDeletion 只有还在冷静期内的账号才能撤销
+	CancelDeletion(ctx context.Context, uid int64) error
+	// FindDueDeletion 找出冷静期已经结束的账号
+	FindDueDeletion(ctx context.Context, now int64, limit int) ([]User, error)
+	// Anonymize 抹掉个人信息，email 和 phone 置为 NULL 让唯一索引可以被重新使用
+	Anonymize(ctx context.Context, uid int64) error
+	// Merge 把 from 的文章转给 to，to 缺少的邮箱、手机号和密码从 from 继承，
+	// from 只留下一条指向 to 的记录
+	Merge(ctx context.Context, from int64, to int64) error
 }
 
 type GORMUserDAO struct {
@@ -55,23 +89,197 @@ func (dao *GORMUserDAO) Insert(ctx context.Context, u User) error {
 	u.Ctime = now
 	u.Utime = now
 	err := dao.db.WithContext(ctx).Create(&u).Error
+	if isDuplicate(err) {
+		// 邮箱或者手机号冲突
+		return ErrUserDuplicate
+	}
+	return err
+}
+
+func isDuplicate(err error) bool {
 	var mysqlErr *mysql.MySQLError
 	if errors.As(err, &mysqlErr) {
 		const uniqueConflictsErrNo uint16 = 1062
-		if mysqlErr.Number == uniqueConflictsErrNo {
-			// 邮箱冲突
-			return ErrUserDuplicate
-		}
+		return mysqlErr.Number == uniqueConflictsErrNo
 	}
-	return err
+	return false
 }
 
 func (dao *GORMUserDAO) EditProfile(ctx context.Context, u User) error {
 	err := dao.db.WithContext(ctx).Where("Id = ?", u.Id).
-		Updates(User{Nickname: u.Nickname, Birth: u.Birth, Bio: u.Bio}).Error
+		Updates(User{Nickname: u.Nickname, Birth: u.Birth, Bio: u.Bio, Locale: u.Locale}).Error
+	return err
+}
+
+func (dao *GORMUserDAO) UpdateStatus(ctx context.Context, uid int64, status uint8) error {
+	return dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{"status": status, "utime": time.Now().UnixMilli()}).Error
+}
+
+func (dao *GORMUserDAO) Enable(ctx context.Context, uid int64) error {
+	res := dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND status = ?", uid, StatusDisabled).
+		Updates(map[string]any{"status": StatusActive, "utime": time.Now().UnixMilli()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotDisabled
+	}
+	return nil
+}
+
+func (dao *GORMUserDAO) UpdateRoles(ctx context.Context, uid int64, roles string) error {
+	return dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{"roles": roles, "utime": time.Now().UnixMilli()}).Error
+}
+
+func (dao *GORMUserDAO) UpdatePhone(ctx context.Context, uid int64, phone string) error {
+	return dao.updateUnique(ctx, uid, "phone", phone)
+}
+
+func (dao *GORMUserDAO) UpdateEmail(ctx context.Context, uid int64, email string) error {
+	return dao.updateUnique(ctx, uid, "email", email)
+}
+
+func (dao *GORMUserDAO) updateUnique(ctx context.Context, uid int64, column string, val string) error {
+	err := dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{
+			column:  sql.NullString{String: val, Valid: val != ""},
+			"utime": time.Now().UnixMilli(),
+		}).Error
+	if isDuplicate(err) {
+		return ErrUserDuplicate
+	}
 	return err
 }
 
+func (dao *GORMUserDAO) ScheduleDeletion(ctx context.Context, uid int64, deleteAfter int64) error {
+	return dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{
+			"status":       StatusPendingDeletion,
+			"delete_after": deleteAfter,
+			"utime":        time.Now().UnixMilli(),
+		}).Error
+}
+
+func (dao *GORMUserDAO) CancelDeletion(ctx context.Context, uid int64) error {
+	res := dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND status = ?", uid, StatusPendingDeletion).
+		Updates(map[string]any{
+			"status":       StatusActive,
+			"delete_after": 0,
+			"utime":        time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotPendingDeletion
+	}
+	return nil
+}
+
+func (dao *GORMUserDAO) FindDueDeletion(ctx context.Context, now int64, limit int) ([]User, error) {
+	var res []User
+	err := dao.db.WithContext(ctx).
+		Where("status = ? AND delete_after <= ?", StatusPendingDeletion, now).
+		Order("delete_after").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMUserDAO) Anonymize(ctx context.Context, uid int64) error {
+	// 用 map 才能把字段更新成零值
+	return dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{
+			"email":        sql.NullString{},
+			"phone":        sql.NullString{},
+			"password":     "",
+			"nickname":     "",
+			"birth":        "",
+			"bio":          "",
+			"roles":        "",
+			"status":       StatusDeleted,
+			"delete_after": 0,
+			"utime":        time.Now().UnixMilli(),
+		}).Error
+}
+
+func (dao *GORMUserDAO) Merge(ctx context.Context, from int64, to int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users []User
+		// 锁住两条记录，防止并发合并或者同时在改手机号
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []int64{from, to}).Order("id").Find(&users).Error
+		if err != nil {
+			return err
+		}
+		if len(users) != 2 {
+			return ErrUserNoFound
+		}
+		src, dst := users[0], users[1]
+		if src.Id != from {
+			src, dst = dst, src
+		}
+		if !mergeable(src.Status) || !mergeable(dst.Status) {
+			return ErrUserNotMergeable
+		}
+		now := time.Now().UnixMilli()
+
+		err = tx.Model(&Article{}).Where("author_id = ?", from).
+			Updates(map[string]any{"author_id": to, "utime": now}).Error
+		if err != nil {
+			return err
+		}
+		// 二次验证以保留下来的账号为准
+		err = tx.Where("uid = ?", from).Delete(&UserTOTP{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("uid = ?", from).Delete(&RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		// 先把 from 的唯一索引字段清掉，to 才能用
+		err = tx.Model(&User{}).Where("id = ?", from).
+			Updates(map[string]any{
+				"email":       sql.NullString{},
+				"phone":       sql.NullString{},
+				"password":    "",
+				"roles":       "",
+				"status":      StatusMerged,
+				"merged_into": to,
+				"utime":       now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		updates := map[string]any{"utime": now}
+		if !dst.Email.Valid && src.Email.Valid {
+			updates["email"] = src.Email
+		}
+		if !dst.Phone.Valid && src.Phone.Valid {
+			updates["phone"] = src.Phone
+		}
+		// 手机号注册的账号没有密码，继承之后才能继续用邮箱登录
+		if dst.Password == "" && src.Password != "" {
+			updates["password"] = src.Password
+		}
+		return tx.Model(&User{}).Where("id = ?", to).Updates(updates).Error
+	})
+}
+
+// mergeable 被禁用的账号也不能合并，否则可以借合并绕开封禁
+func mergeable(status uint8) bool {
+	switch status {
+	case StatusDisabled, StatusPendingDeletion, StatusDeleted, StatusMerged:
+		return false
+	default:
+		return true
+	}
+}
+
 // User 直接对应数据库表结构，entity 或 Model
 // PO(persistent object)
 type User struct {
@@ -83,6 +291,14 @@ type User struct {
 	Nickname string `gorm:"size: 16"`
 	Birth    string
 	Bio      string `gorm:"size: 256"`
-	Ctime    int64
-	Utime    int64
+	Locale   string `gorm:"size: 16"`
+	// Roles 逗号分隔，空字符串就是普通用户
+	Roles  string `gorm:"size: 128"`
+	Status uint8  `gorm:"index:idx_status_delete_after"`
+	// DeleteAfter 注销冷静期截止时间，毫秒数
+	DeleteAfter int64 `gorm:"index:idx_status_delete_after"`
+	// MergedInto 合并之后被保留的账号 id
+	MergedInto int64
+	Ctime      int64
+	Utime      int64
 }
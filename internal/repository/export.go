@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository/cache"
+	"time"
+)
+
+var ErrExportTaskNotFound = cache.ErrKeyNotExist
+
+type ExportTaskRepository interface {
+	FindByUid(ctx context.Context, uid int64) (domain.ExportTask, error)
+	// Save 覆盖这个用户之前的任务
+	Save(ctx context.Context, task domain.ExportTask, expiration time.Duration) error
+}
+
+type CachedExportTaskRepository struct {
+	cache cache.ExportTaskCache
+}
+
+func NewCachedExportTaskRepository(c cache.ExportTaskCache) ExportTaskRepository {
+	return &CachedExportTaskRepository{
+		cache: c,
+	}
+}
+
+func (r *CachedExportTaskRepository) FindByUid(ctx context.Context, uid int64) (domain.ExportTask, error) {
+	return r.cache.Get(ctx, uid)
+}
+
+func (r *CachedExportTaskRepository) Save(ctx context.Context, task domain.ExportTask, expiration time.Duration) error {
+	return r.cache.Set(ctx, task, expiration)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/repository/cache"
+	"time"
+)
+
+type LoginAttemptRepository interface {
+	IncrFailure(ctx context.Context, uid int64, ip string, window time.Duration) (int64, int64, error)
+	LockUser(ctx context.Context, uid int64, base time.Duration, max time.Duration) (time.Duration, error)
+	LockIP(ctx context.Context, ip string, duration time.Duration) error
+	UserLockTTL(ctx context.Context, uid int64) (time.Duration, error)
+	IPLockTTL(ctx context.Context, ip string) (time.Duration, error)
+	ResetFailure(ctx context.Context, uid int64) error
+	UnlockUser(ctx context.Context, uid int64) error
+}
+
+type CachedLoginAttemptRepository struct {
+	cache cache.LoginAttemptCache
+}
+
+func NewCachedLoginAttemptRepository(cache cache.LoginAttemptCache) LoginAttemptRepository {
+	return &CachedLoginAttemptRepository{
+		cache: cache,
+	}
+}
+
+func (repo *CachedLoginAttemptRepository) IncrFailure(ctx context.Context, uid int64,
+	ip string, window time.Duration) (int64, int64, error) {
+	return repo.cache.IncrFailure(ctx, uid, ip, window)
+}
+
+func (repo *CachedLoginAttemptRepository) LockUser(ctx context.Context, uid int64,
+	base time.Duration, max time.Duration) (time.Duration, error) {
+	return repo.cache.LockUser(ctx, uid, base, max)
+}
+
+func (repo *CachedLoginAttemptRepository) LockIP(ctx context.Context, ip string, duration time.Duration) error {
+	return repo.cache.LockIP(ctx, ip, duration)
+}
+
+func (repo *CachedLoginAttemptRepository) UserLockTTL(ctx context.Context, uid int64) (time.Duration, error) {
+	return repo.cache.UserLockTTL(ctx, uid)
+}
+
+func (repo *CachedLoginAttemptRepository) IPLockTTL(ctx context.Context, ip string) (time.Duration, error) {
+	return repo.cache.IPLockTTL(ctx, ip)
+}
+
+func (repo *CachedLoginAttemptRepository) ResetFailure(ctx context.Context, uid int64) error {
+	return repo.cache.ResetFailure(ctx, uid)
+}
+
+func (repo *CachedLoginAttemptRepository) UnlockUser(ctx context.Context, uid int64) error {
+	return repo.cache.UnlockUser(ctx, uid)
+}
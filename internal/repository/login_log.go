@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository/dao"
+	"time"
+)
+
+type LoginLogRepository interface {
+	Create(ctx context.Context, l domain.LoginLog) error
+	FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]domain.LoginLog, error)
+	CountSuccess(ctx context.Context, uid int64, device string, country string) (int64, int64, int64, error)
+}
+
+type loginLogRepository struct {
+	dao dao.LoginLogDao
+}
+
+func NewLoginLogRepository(dao dao.LoginLogDao) LoginLogRepository {
+	return &loginLogRepository{
+		dao: dao,
+	}
+}
+
+func (r *loginLogRepository) Create(ctx context.Context, l domain.LoginLog) error {
+	return r.dao.Insert(ctx, dao.LoginLog{
+		Uid:        l.Uid,
+		IP:         l.IP,
+		UserAgent:  l.UserAgent,
+		Method:     string(l.Method),
+		Outcome:    string(l.Outcome),
+		Device:     l.Device,
+		Country:    l.Country,
+		Suspicious: l.Suspicious,
+	})
+}
+
+func (r *loginLogRepository) FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]domain.LoginLog, error) {
+	logs, err := r.dao.FindByUid(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.LoginLog, domain.LoginLog](logs, func(idx int, src dao.LoginLog) domain.LoginLog {
+		return domain.LoginLog{
+			Id:         src.Id,
+			Uid:        src.Uid,
+			IP:         src.IP,
+			UserAgent:  src.UserAgent,
+			Method:     domain.LoginMethod(src.Method),
+			Outcome:    domain.LoginOutcome(src.Outcome),
+			Device:     src.Device,
+			Country:    src.Country,
+			Suspicious: src.Suspicious,
+			Ctime:      time.UnixMilli(src.Ctime),
+		}
+	}), nil
+}
+
+func (r *loginLogRepository) CountSuccess(ctx context.Context, uid int64,
+	device string, country string) (int64, int64, int64, error) {
+	return r.dao.CountSuccess(ctx, uid, device, country)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository/dao"
+)
+
+var ErrMFANoFound = dao.ErrMFANoFound
+
+type MFARepository interface {
+	SaveTOTP(ctx context.Context, t domain.TOTP) error
+	FindTOTP(ctx context.Context, uid int64) (domain.TOTP, error)
+	EnableTOTP(ctx context.Context, uid int64, recoveryHashes []string) error
+	DeleteTOTP(ctx context.Context, uid int64) error
+	UseRecoveryCode(ctx context.Context, uid int64, hash string) (bool, error)
+	// UseTOTPStep 记录验证通过的时间窗口，返回 false 说明这个窗口或者更新的窗口已经用过了
+	UseTOTPStep(ctx context.Context, uid int64, step int64) (bool, error)
+}
+
+type mfaRepository struct {
+	dao dao.MFADao
+}
+
+func NewMFARepository(dao dao.MFADao) MFARepository {
+	return &mfaRepository{
+		dao: dao,
+	}
+}
+
+func (r *mfaRepository) SaveTOTP(ctx context.Context, t domain.TOTP) error {
+	return r.dao.Upsert(ctx, dao.UserTOTP{
+		Uid:     t.Uid,
+		Secret:  t.Secret,
+		Enabled: t.Enabled,
+	})
+}
+
+func (r *mfaRepository) FindTOTP(ctx context.Context, uid int64) (domain.TOTP, error) {
+	t, err := r.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return domain.TOTP{}, err
+	}
+	return domain.TOTP{
+		Uid:     t.Uid,
+		Secret:  t.Secret,
+		Enabled: t.Enabled,
+	}, nil
+}
+
+func (r *mfaRepository) EnableTOTP(ctx context.Context, uid int64, recoveryHashes []string) error {
+	return r.dao.Enable(ctx, uid, recoveryHashes)
+}
+
+func (r *mfaRepository) DeleteTOTP(ctx context.Context, uid int64) error {
+	return r.dao.Delete(ctx, uid)
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, uid int64, hash string) (bool, error) {
+	return r.dao.UseRecoveryCode(ctx, uid, hash)
+}
+
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, uid int64, step int64) (bool, error) {
+	return r.dao.UseStep(ctx, uid, step)
+}
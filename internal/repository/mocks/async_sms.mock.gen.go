@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/async_sms.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/async_sms.go -package=repomocks -destination=internal/repository/mocks/async_sms.mock.gen.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/skcheng003/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAsyncSMSRepository is a mock of AsyncSMSRepository interface.
+type MockAsyncSMSRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAsyncSMSRepositoryMockRecorder
+}
+
+// MockAsyncSMSRepositoryMockRecorder is the mock recorder for MockAsyncSMSRepository.
+type MockAsyncSMSRepositoryMockRecorder struct {
+	mock *MockAsyncSMSRepository
+}
+
+// NewMockAsyncSMSRepository creates a new mock instance.
+func NewMockAsyncSMSRepository(ctrl *gomock.Controller) *MockAsyncSMSRepository {
+	mock := &MockAsyncSMSRepository{ctrl: ctrl}
+	mock.recorder = &MockAsyncSMSRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAsyncSMSRepository) EXPECT() *MockAsyncSMSRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockAsyncSMSRepository) Add(ctx context.Context, s domain.AsyncSMS) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", ctx, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockAsyncSMSRepositoryMockRecorder) Add(ctx, s any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockAsyncSMSRepository)(nil).Add), ctx, s)
+}
+
+// Delete mocks base method.
+func (m *MockAsyncSMSRepository) Delete(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockAsyncSMSRepositoryMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockAsyncSMSRepository)(nil).Delete), ctx, id)
+}
+
+// MarkFailed mocks base method.
+func (m *MockAsyncSMSRepository) MarkFailed(ctx context.Context, s domain.AsyncSMS, nextTime time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkFailed", ctx, s, nextTime)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkFailed indicates an expected call of MarkFailed.
+func (mr *MockAsyncSMSRepositoryMockRecorder) MarkFailed(ctx, s, nextTime any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkFailed", reflect.TypeOf((*MockAsyncSMSRepository)(nil).MarkFailed), ctx, s, nextTime)
+}
+
+// PreemptWaitingSMS mocks base method.
+func (m *MockAsyncSMSRepository) PreemptWaitingSMS(ctx context.Context) (domain.AsyncSMS, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PreemptWaitingSMS", ctx)
+	ret0, _ := ret[0].(domain.AsyncSMS)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PreemptWaitingSMS indicates an expected call of PreemptWaitingSMS.
+func (mr *MockAsyncSMSRepositoryMockRecorder) PreemptWaitingSMS(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreemptWaitingSMS", reflect.TypeOf((*MockAsyncSMSRepository)(nil).PreemptWaitingSMS), ctx)
+}
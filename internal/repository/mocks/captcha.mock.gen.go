@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/captcha.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/captcha.go -package=repomocks -destination=internal/repository/mocks/captcha.mock.gen.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCaptchaRepository is a mock of CaptchaRepository interface.
+type MockCaptchaRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCaptchaRepositoryMockRecorder
+}
+
+// MockCaptchaRepositoryMockRecorder is the mock recorder for MockCaptchaRepository.
+type MockCaptchaRepositoryMockRecorder struct {
+	mock *MockCaptchaRepository
+}
+
+// NewMockCaptchaRepository creates a new mock instance.
+func NewMockCaptchaRepository(ctrl *gomock.Controller) *MockCaptchaRepository {
+	mock := &MockCaptchaRepository{ctrl: ctrl}
+	mock.recorder = &MockCaptchaRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCaptchaRepository) EXPECT() *MockCaptchaRepositoryMockRecorder {
+	return m.recorder
+}
+
+// IncrHit mocks base method.
+func (m *MockCaptchaRepository) IncrHit(ctx context.Context, scene, ip string, window time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrHit", ctx, scene, ip, window)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrHit indicates an expected call of IncrHit.
+func (mr *MockCaptchaRepositoryMockRecorder) IncrHit(ctx, scene, ip, window any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrHit", reflect.TypeOf((*MockCaptchaRepository)(nil).IncrHit), ctx, scene, ip, window)
+}
+
+// Store mocks base method.
+func (m *MockCaptchaRepository) Store(ctx context.Context, id, answer string, expiration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Store", ctx, id, answer, expiration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Store indicates an expected call of Store.
+func (mr *MockCaptchaRepositoryMockRecorder) Store(ctx, id, answer, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockCaptchaRepository)(nil).Store), ctx, id, answer, expiration)
+}
+
+// Take mocks base method.
+func (m *MockCaptchaRepository) Take(ctx context.Context, id string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Take", ctx, id)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Take indicates an expected call of Take.
+func (mr *MockCaptchaRepositoryMockRecorder) Take(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Take", reflect.TypeOf((*MockCaptchaRepository)(nil).Take), ctx, id)
+}
@@ -0,0 +1,69 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/code_quota.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/code_quota.go -package=repomocks -destination=internal/repository/mocks/code_quota.mock.gen.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	repository "github.com/skcheng003/webook/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCodeQuotaRepository is a mock of CodeQuotaRepository interface.
+type MockCodeQuotaRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCodeQuotaRepositoryMockRecorder
+}
+
+// MockCodeQuotaRepositoryMockRecorder is the mock recorder for MockCodeQuotaRepository.
+type MockCodeQuotaRepositoryMockRecorder struct {
+	mock *MockCodeQuotaRepository
+}
+
+// NewMockCodeQuotaRepository creates a new mock instance.
+func NewMockCodeQuotaRepository(ctrl *gomock.Controller) *MockCodeQuotaRepository {
+	mock := &MockCodeQuotaRepository{ctrl: ctrl}
+	mock.recorder = &MockCodeQuotaRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCodeQuotaRepository) EXPECT() *MockCodeQuotaRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Decr mocks base method.
+func (m *MockCodeQuotaRepository) Decr(ctx context.Context, biz, phone, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decr", ctx, biz, phone, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Decr indicates an expected call of Decr.
+func (mr *MockCodeQuotaRepositoryMockRecorder) Decr(ctx, biz, phone, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decr", reflect.TypeOf((*MockCodeQuotaRepository)(nil).Decr), ctx, biz, phone, ip)
+}
+
+// Incr mocks base method.
+func (m *MockCodeQuotaRepository) Incr(ctx context.Context, biz, phone, ip string, limit repository.CodeQuotaLimit) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Incr", ctx, biz, phone, ip, limit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Incr indicates an expected call of Incr.
+func (mr *MockCodeQuotaRepositoryMockRecorder) Incr(ctx, biz, phone, ip, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Incr", reflect.TypeOf((*MockCodeQuotaRepository)(nil).Incr), ctx, biz, phone, ip, limit)
+}
@@ -0,0 +1,144 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/login_attempt.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/login_attempt.go -package=repomocks -destination=internal/repository/mocks/login_attempt.mock.gen.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginAttemptRepository is a mock of LoginAttemptRepository interface.
+type MockLoginAttemptRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginAttemptRepositoryMockRecorder
+}
+
+// MockLoginAttemptRepositoryMockRecorder is the mock recorder for MockLoginAttemptRepository.
+type MockLoginAttemptRepositoryMockRecorder struct {
+	mock *MockLoginAttemptRepository
+}
+
+// NewMockLoginAttemptRepository creates a new mock instance.
+func NewMockLoginAttemptRepository(ctrl *gomock.Controller) *MockLoginAttemptRepository {
+	mock := &MockLoginAttemptRepository{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginAttemptRepository) EXPECT() *MockLoginAttemptRepositoryMockRecorder {
+	return m.recorder
+}
+
+// IPLockTTL mocks base method.
+func (m *MockLoginAttemptRepository) IPLockTTL(ctx context.Context, ip string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IPLockTTL", ctx, ip)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IPLockTTL indicates an expected call of IPLockTTL.
+func (mr *MockLoginAttemptRepositoryMockRecorder) IPLockTTL(ctx, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IPLockTTL", reflect.TypeOf((*MockLoginAttemptRepository)(nil).IPLockTTL), ctx, ip)
+}
+
+// IncrFailure mocks base method.
+func (m *MockLoginAttemptRepository) IncrFailure(ctx context.Context, uid int64, ip string, window time.Duration) (int64, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrFailure", ctx, uid, ip, window)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IncrFailure indicates an expected call of IncrFailure.
+func (mr *MockLoginAttemptRepositoryMockRecorder) IncrFailure(ctx, uid, ip, window any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrFailure", reflect.TypeOf((*MockLoginAttemptRepository)(nil).IncrFailure), ctx, uid, ip, window)
+}
+
+// LockIP mocks base method.
+func (m *MockLoginAttemptRepository) LockIP(ctx context.Context, ip string, duration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockIP", ctx, ip, duration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockIP indicates an expected call of LockIP.
+func (mr *MockLoginAttemptRepositoryMockRecorder) LockIP(ctx, ip, duration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockIP", reflect.TypeOf((*MockLoginAttemptRepository)(nil).LockIP), ctx, ip, duration)
+}
+
+// LockUser mocks base method.
+func (m *MockLoginAttemptRepository) LockUser(ctx context.Context, uid int64, base, max time.Duration) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUser", ctx, uid, base, max)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockUser indicates an expected call of LockUser.
+func (mr *MockLoginAttemptRepositoryMockRecorder) LockUser(ctx, uid, base, max any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUser", reflect.TypeOf((*MockLoginAttemptRepository)(nil).LockUser), ctx, uid, base, max)
+}
+
+// ResetFailure mocks base method.
+func (m *MockLoginAttemptRepository) ResetFailure(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetFailure", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetFailure indicates an expected call of ResetFailure.
+func (mr *MockLoginAttemptRepositoryMockRecorder) ResetFailure(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetFailure", reflect.TypeOf((*MockLoginAttemptRepository)(nil).ResetFailure), ctx, uid)
+}
+
+// UnlockUser mocks base method.
+func (m *MockLoginAttemptRepository) UnlockUser(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockUser indicates an expected call of UnlockUser.
+func (mr *MockLoginAttemptRepositoryMockRecorder) UnlockUser(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockLoginAttemptRepository)(nil).UnlockUser), ctx, uid)
+}
+
+// UserLockTTL mocks base method.
+func (m *MockLoginAttemptRepository) UserLockTTL(ctx context.Context, uid int64) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserLockTTL", ctx, uid)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserLockTTL indicates an expected call of UserLockTTL.
+func (mr *MockLoginAttemptRepositoryMockRecorder) UserLockTTL(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserLockTTL", reflect.TypeOf((*MockLoginAttemptRepository)(nil).UserLockTTL), ctx, uid)
+}
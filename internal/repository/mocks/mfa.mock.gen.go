@@ -0,0 +1,128 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/mfa.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/mfa.go -package=repomocks -destination=internal/repository/mocks/mfa.mock.gen.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/skcheng003/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMFARepository is a mock of MFARepository interface.
+type MockMFARepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockMFARepositoryMockRecorder
+}
+
+// MockMFARepositoryMockRecorder is the mock recorder for MockMFARepository.
+type MockMFARepositoryMockRecorder struct {
+	mock *MockMFARepository
+}
+
+// NewMockMFARepository creates a new mock instance.
+func NewMockMFARepository(ctrl *gomock.Controller) *MockMFARepository {
+	mock := &MockMFARepository{ctrl: ctrl}
+	mock.recorder = &MockMFARepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMFARepository) EXPECT() *MockMFARepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteTOTP mocks base method.
+func (m *MockMFARepository) DeleteTOTP(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTP", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTP indicates an expected call of DeleteTOTP.
+func (mr *MockMFARepositoryMockRecorder) DeleteTOTP(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTP", reflect.TypeOf((*MockMFARepository)(nil).DeleteTOTP), ctx, uid)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockMFARepository) EnableTOTP(ctx context.Context, uid int64, recoveryHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, uid, recoveryHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockMFARepositoryMockRecorder) EnableTOTP(ctx, uid, recoveryHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockMFARepository)(nil).EnableTOTP), ctx, uid, recoveryHashes)
+}
+
+// FindTOTP mocks base method.
+func (m *MockMFARepository) FindTOTP(ctx context.Context, uid int64) (domain.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTOTP", ctx, uid)
+	ret0, _ := ret[0].(domain.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTOTP indicates an expected call of FindTOTP.
+func (mr *MockMFARepositoryMockRecorder) FindTOTP(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTOTP", reflect.TypeOf((*MockMFARepository)(nil).FindTOTP), ctx, uid)
+}
+
+// SaveTOTP mocks base method.
+func (m *MockMFARepository) SaveTOTP(ctx context.Context, t domain.TOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTP", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTP indicates an expected call of SaveTOTP.
+func (mr *MockMFARepositoryMockRecorder) SaveTOTP(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTP", reflect.TypeOf((*MockMFARepository)(nil).SaveTOTP), ctx, t)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, uid int64, hash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, uid, hash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockMFARepositoryMockRecorder) UseRecoveryCode(ctx, uid, hash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockMFARepository)(nil).UseRecoveryCode), ctx, uid, hash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockMFARepository) UseTOTPStep(ctx context.Context, uid, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, uid, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockMFARepositoryMockRecorder) UseTOTPStep(ctx, uid, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockMFARepository)(nil).UseTOTPStep), ctx, uid, step)
+}
@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/sms_delivery.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/sms_delivery.go -package=repomocks -destination=internal/repository/mocks/sms_delivery.mock.gen.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/skcheng003/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSMSDeliveryRepository is a mock of SMSDeliveryRepository interface.
+type MockSMSDeliveryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSMSDeliveryRepositoryMockRecorder
+}
+
+// MockSMSDeliveryRepositoryMockRecorder is the mock recorder for MockSMSDeliveryRepository.
+type MockSMSDeliveryRepositoryMockRecorder struct {
+	mock *MockSMSDeliveryRepository
+}
+
+// NewMockSMSDeliveryRepository creates a new mock instance.
+func NewMockSMSDeliveryRepository(ctrl *gomock.Controller) *MockSMSDeliveryRepository {
+	mock := &MockSMSDeliveryRepository{ctrl: ctrl}
+	mock.recorder = &MockSMSDeliveryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSMSDeliveryRepository) EXPECT() *MockSMSDeliveryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockSMSDeliveryRepository) Create(ctx context.Context, ds []domain.SMSDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, ds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockSMSDeliveryRepositoryMockRecorder) Create(ctx, ds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSMSDeliveryRepository)(nil).Create), ctx, ds)
+}
+
+// FindByPhone mocks base method.
+func (m *MockSMSDeliveryRepository) FindByPhone(ctx context.Context, phone string, offset, limit int) ([]domain.SMSDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByPhone", ctx, phone, offset, limit)
+	ret0, _ := ret[0].([]domain.SMSDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByPhone indicates an expected call of FindByPhone.
+func (mr *MockSMSDeliveryRepositoryMockRecorder) FindByPhone(ctx, phone, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByPhone", reflect.TypeOf((*MockSMSDeliveryRepository)(nil).FindByPhone), ctx, phone, offset, limit)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockSMSDeliveryRepository) UpdateStatus(ctx context.Context, provider, serialNo string, status domain.SMSDeliveryStatus, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, provider, serialNo, status, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockSMSDeliveryRepositoryMockRecorder) UpdateStatus(ctx, provider, serialNo, status, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockSMSDeliveryRepository)(nil).UpdateStatus), ctx, provider, serialNo, status, reason)
+}
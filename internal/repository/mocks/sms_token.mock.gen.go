@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/sms_token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/repository/sms_token.go -package=repomocks -destination=internal/repository/mocks/sms_token.mock.gen.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSMSTokenRepository is a mock of SMSTokenRepository interface.
+type MockSMSTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSMSTokenRepositoryMockRecorder
+}
+
+// MockSMSTokenRepositoryMockRecorder is the mock recorder for MockSMSTokenRepository.
+type MockSMSTokenRepositoryMockRecorder struct {
+	mock *MockSMSTokenRepository
+}
+
+// NewMockSMSTokenRepository creates a new mock instance.
+func NewMockSMSTokenRepository(ctrl *gomock.Controller) *MockSMSTokenRepository {
+	mock := &MockSMSTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockSMSTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSMSTokenRepository) EXPECT() *MockSMSTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// IsRevoked mocks base method.
+func (m *MockSMSTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsRevoked", ctx, jti)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsRevoked indicates an expected call of IsRevoked.
+func (mr *MockSMSTokenRepositoryMockRecorder) IsRevoked(ctx, jti any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsRevoked", reflect.TypeOf((*MockSMSTokenRepository)(nil).IsRevoked), ctx, jti)
+}
+
+// Revoke mocks base method.
+func (m *MockSMSTokenRepository) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, jti, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockSMSTokenRepositoryMockRecorder) Revoke(ctx, jti, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockSMSTokenRepository)(nil).Revoke), ctx, jti, ttl)
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository/dao"
+	"time"
+)
+
+type SMSDeliveryRepository interface {
+	Create(ctx context.Context, ds []domain.SMSDelivery) error
+	UpdateStatus(ctx context.Context, provider string, serialNo string,
+		status domain.SMSDeliveryStatus, reason string) error
+	FindByPhone(ctx context.Context, phone string, offset int, limit int) ([]domain.SMSDelivery, error)
+}
+
+type smsDeliveryRepository struct {
+	dao dao.SMSDeliveryDAO
+	// hashKey 手机号的 HMAC 密钥，手机号的空间很小，直接 hash 很容易被穷举
+	hashKey []byte
+}
+
+func NewSMSDeliveryRepository(dao dao.SMSDeliveryDAO, hashKey string) SMSDeliveryRepository {
+	return &smsDeliveryRepository{
+		dao:     dao,
+		hashKey: []byte(hashKey),
+	}
+}
+
+func (r *smsDeliveryRepository) Create(ctx context.Context, ds []domain.SMSDelivery) error {
+	return r.dao.Insert(ctx, slice.Map[domain.SMSDelivery, dao.SMSDelivery](ds,
+		func(idx int, src domain.SMSDelivery) dao.SMSDelivery {
+			return dao.SMSDelivery{
+				Provider:  src.Provider,
+				TplId:     src.TplId,
+				Phone:     domain.MaskPhone(src.Phone),
+				PhoneHash: r.hash(src.Phone),
+				Status:    string(src.Status),
+				SerialNo:  src.SerialNo,
+				Reason:    truncate(src.Reason, 256),
+			}
+		}))
+}
+
+func (r *smsDeliveryRepository) UpdateStatus(ctx context.Context, provider string, serialNo string,
+	status domain.SMSDeliveryStatus, reason string) error {
+	return r.dao.UpdateBySerialNo(ctx, provider, serialNo, string(status), truncate(reason, 256))
+}
+
+func (r *smsDeliveryRepository) FindByPhone(ctx context.Context, phone string,
+	offset int, limit int) ([]domain.SMSDelivery, error) {
+	ds, err := r.dao.FindByPhoneHash(ctx, r.hash(phone), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.SMSDelivery, domain.SMSDelivery](ds, func(idx int, src dao.SMSDelivery) domain.SMSDelivery {
+		return domain.SMSDelivery{
+			Id:       src.Id,
+			Provider: src.Provider,
+			TplId:    src.TplId,
+			Phone:    src.Phone,
+			Status:   domain.SMSDeliveryStatus(src.Status),
+			SerialNo: src.SerialNo,
+			Reason:   src.Reason,
+			Ctime:    time.UnixMilli(src.Ctime),
+			Utime:    time.UnixMilli(src.Utime),
+		}
+	}), nil
+}
+
+func (r *smsDeliveryRepository) hash(phone string) string {
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(phone))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// truncate 按字节截断会截断中文，按 rune 截
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
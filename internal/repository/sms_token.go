@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/repository/cache"
+	"time"
+)
+
+type SMSTokenRepository interface {
+	Revoke(ctx context.Context, jti string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type CachedSMSTokenRepository struct {
+	cache cache.SMSTokenCache
+}
+
+func NewCachedSMSTokenRepository(cache cache.SMSTokenCache) SMSTokenRepository {
+	return &CachedSMSTokenRepository{
+		cache: cache,
+	}
+}
+
+func (repo *CachedSMSTokenRepository) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	return repo.cache.Revoke(ctx, jti, ttl)
+}
+
+func (repo *CachedSMSTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return repo.cache.IsRevoked(ctx, jti)
+}
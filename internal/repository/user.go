@@ -3,14 +3,20 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/skcheng003/webook/internal/domain"
 	"github.com/skcheng003/webook/internal/repository/cache"
 	"github.com/skcheng003/webook/internal/repository/dao"
+	"go.uber.org/zap"
+	"strings"
 	"time"
 )
 
 var ErrUserDuplicate = dao.ErrUserDuplicate
 var ErrUserNoFound = dao.ErrUserNoFound
+var ErrUserNotPendingDeletion = dao.ErrUserNotPendingDeletion
+var ErrUserNotMergeable = dao.ErrUserNotMergeable
+var ErrUserNotDisabled = dao.ErrUserNotDisabled
 
 type UserRepository interface {
 	CreateUser(ctx context.Context, u domain.User) error
@@ -18,6 +24,18 @@ type UserRepository interface {
 	FindByPhone(ctx context.Context, phone string) (domain.User, error)
 	FindByUid(ctx context.Context, uid int64) (domain.User, error)
 	EditProfile(ctx context.Context, user domain.User) error
+	UpdateStatus(ctx context.Context, uid int64, status domain.UserStatus) error
+	// Enable 只能解禁被禁用的账号，其它状态返回 ErrUserNotDisabled
+	Enable(ctx context.Context, uid int64) error
+	UpdateRoles(ctx context.Context, uid int64, roles []string) error
+	UpdatePhone(ctx context.Context, uid int64, phone string) error
+	UpdateEmail(ctx context.Context, uid int64, email string) error
+	ScheduleDeletion(ctx context.Context, uid int64, deleteAfter time.Time) error
+	CancelDeletion(ctx context.Context, uid int64) error
+	FindDueDeletion(ctx context.Context, now time.Time, limit int) ([]domain.User, error)
+	// Anonymize 抹掉个人信息，同时清掉缓存
+	Anonymize(ctx context.Context, uid int64) error
+	Merge(ctx context.Context, from int64, to int64) error
 }
 
 type userRepository struct {
@@ -44,14 +62,37 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (domain.
 	return r.entityToDomain(u), err
 }
 
+// FindByPhone phone 是 E.164 格式，找不到的话再按旧格式找一次，
+// 不然旧账号用 +86 登录的时候会被当成新用户，再注册一个空账号
 func (r *userRepository) FindByPhone(ctx context.Context, phone string) (domain.User, error) {
 	u, err := r.dao.FindByPhone(ctx, phone)
+	if errors.Is(err, dao.ErrUserNoFound) {
+		if legacy, ok := legacyPhone(phone); ok {
+			u, err = r.findLegacyPhone(ctx, legacy, phone)
+		}
+	}
 	if err != nil {
 		return domain.User{}, err
 	}
 	return r.entityToDomain(u), err
 }
 
+// findLegacyPhone 找到了就顺手把号码改成 E.164，改失败了不影响这次查询，下次查到的时候再改
+func (r *userRepository) findLegacyPhone(ctx context.Context, legacy string, phone string) (dao.User, error) {
+	u, err := r.dao.FindByPhone(ctx, legacy)
+	if err != nil {
+		return dao.User{}, err
+	}
+	err = r.dao.UpdatePhone(ctx, u.Id, phone)
+	if err == nil {
+		err = r.cache.Delete(ctx, u.Id)
+	}
+	if err != nil {
+		zap.L().Warn("旧格式的手机号改成 E.164 失败", zap.Int64("uid", u.Id), zap.Error(err))
+	}
+	return u, nil
+}
+
 func (r *userRepository) FindByUid(ctx context.Context, uid int64) (domain.User, error) {
 	u, err := r.cache.Get(ctx, uid)
 	if err == nil {
@@ -66,14 +107,12 @@ func (r *userRepository) FindByUid(ctx context.Context, uid int64) (domain.User,
 		return domain.User{}, err
 	}
 	u = r.entityToDomain(ue)
-	go func() {
-		// 异步写入缓存
-		err = r.cache.Set(ctx, u)
-		if err != nil {
-			// TODO: add some log, set cache failed
-		}
-	}()
-	return u, err
+	// 同步写入缓存：异步写可能落在 Disable、UpdateRoles 之后的 Delete 后面，
+	// 把旧的状态和角色重新放回缓存，一直留到过期
+	if err = r.cache.Set(ctx, u); err != nil {
+		// TODO: add some log, set cache failed
+	}
+	return u, nil
 }
 
 func (r *userRepository) EditProfile(ctx context.Context, user domain.User) error {
@@ -87,8 +126,112 @@ func (r *userRepository) EditProfile(ctx context.Context, user domain.User) erro
 		Nickname: user.Nickname,
 		Birth:    user.Birth,
 		Bio:      user.Bio,
+		Locale:   user.Locale,
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, user.Id)
+}
+
+func (r *userRepository) UpdateStatus(ctx context.Context, uid int64, status domain.UserStatus) error {
+	err := r.dao.UpdateStatus(ctx, uid, uint8(status))
+	if err != nil {
+		return err
+	}
+	// 状态关系到能不能登录，缓存必须删掉
+	return r.cache.Delete(ctx, uid)
+}
+
+func (r *userRepository) Enable(ctx context.Context, uid int64) error {
+	err := r.dao.Enable(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, uid)
+}
+
+func (r *userRepository) UpdateRoles(ctx context.Context, uid int64, roles []string) error {
+	err := r.dao.UpdateRoles(ctx, uid, strings.Join(roles, ","))
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, uid)
+}
+
+func (r *userRepository) UpdatePhone(ctx context.Context, uid int64, phone string) error {
+	// 旧格式的号码和 E.164 的号码是同一个手机号，唯一索引拦不住，要自己查
+	if legacy, ok := legacyPhone(phone); ok {
+		u, err := r.dao.FindByPhone(ctx, legacy)
+		switch {
+		case err == nil && u.Id != uid:
+			return ErrUserDuplicate
+		case err != nil && !errors.Is(err, dao.ErrUserNoFound):
+			return err
+		}
+	}
+	err := r.dao.UpdatePhone(ctx, uid, phone)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, uid)
+}
+
+func (r *userRepository) UpdateEmail(ctx context.Context, uid int64, email string) error {
+	err := r.dao.UpdateEmail(ctx, uid, email)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, uid)
+}
+
+func (r *userRepository) ScheduleDeletion(ctx context.Context, uid int64, deleteAfter time.Time) error {
+	err := r.dao.ScheduleDeletion(ctx, uid, deleteAfter.UnixMilli())
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, uid)
+}
+
+func (r *userRepository) CancelDeletion(ctx context.Context, uid int64) error {
+	err := r.dao.CancelDeletion(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, uid)
+}
+
+func (r *userRepository) FindDueDeletion(ctx context.Context, now time.Time, limit int) ([]domain.User, error) {
+	users, err := r.dao.FindDueDeletion(ctx, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.User, 0, len(users))
+	for _, u := range users {
+		res = append(res, r.entityToDomain(u))
+	}
+	return res, nil
+}
+
+func (r *userRepository) Anonymize(ctx context.Context, uid int64) error {
+	err := r.dao.Anonymize(ctx, uid)
+	if err != nil {
+		return err
+	}
+	// 缓存里面还有邮箱手机号，必须删掉
+	return r.cache.Delete(ctx, uid)
+}
+
+func (r *userRepository) Merge(ctx context.Context, from int64, to int64) error {
+	err := r.dao.Merge(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	err = r.cache.Delete(ctx, from)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, to)
 }
 
 func (r *userRepository) domainToEntity(user domain.User) dao.User {
@@ -103,16 +246,50 @@ func (r *userRepository) domainToEntity(user domain.User) dao.User {
 			Valid:  user.Phone != "",
 		},
 		Password: user.Password,
+		Locale:   user.Locale,
+		Roles:    strings.Join(user.Roles, ","),
+		Status:   uint8(user.Status),
 		Ctime:    user.Ctime.UnixMilli(),
 	}
 }
 
 func (r *userRepository) entityToDomain(user dao.User) domain.User {
+	var roles []string
+	if user.Roles != "" {
+		roles = strings.Split(user.Roles, ",")
+	}
+	var deleteAfter time.Time
+	if user.DeleteAfter > 0 {
+		deleteAfter = time.UnixMilli(user.DeleteAfter)
+	}
 	return domain.User{
-		Id:       user.Id,
-		Email:    user.Email.String,
-		Phone:    user.Phone.String,
-		Password: user.Password,
-		Ctime:    time.UnixMilli(user.Ctime),
+		Id:          user.Id,
+		Email:       user.Email.String,
+		Phone:       normalizePhone(user.Phone.String),
+		Password:    user.Password,
+		Nickname:    user.Nickname,
+		Birth:       user.Birth,
+		Bio:         user.Bio,
+		Locale:      user.Locale,
+		Roles:       roles,
+		Status:      domain.UserStatus(user.Status),
+		DeleteAfter: deleteAfter,
+		MergedInto:  user.MergedInto,
+		Ctime:       time.UnixMilli(user.Ctime),
+	}
+}
+
+// legacyPhone E.164 格式的大陆号码对应的旧格式。手机号改成 E.164 之前存的是不带国家码的大陆号码，
+// 这些旧数据在 FindByPhone 查到的时候才会改过来
+func legacyPhone(phone string) (string, bool) {
+	legacy, ok := strings.CutPrefix(phone, "+86")
+	return legacy, ok && legacy != ""
+}
+
+// normalizePhone 还没有改过来的旧格式号码，返回给上层之前补上国家码
+func normalizePhone(phone string) string {
+	if phone == "" || strings.HasPrefix(phone, "+") {
+		return phone
 	}
+	return "+86" + phone
 }
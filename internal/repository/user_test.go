@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository/cache"
+	cachemocks "github.com/skcheng003/webook/internal/repository/cache/mocks"
+	"github.com/skcheng003/webook/internal/repository/dao"
+	daomocks "github.com/skcheng003/webook/internal/repository/dao/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestUserRepository_FindByPhone(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache)
+		phone string
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "E.164 格式直接找到",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").
+					Return(dao.User{Id: 123, Phone: sql.NullString{String: "+8613800138000", Valid: true}}, nil)
+				return d, cachemocks.NewMockUserCache(ctrl)
+			},
+			phone:    "+8613800138000",
+			wantUser: domain.User{Id: 123, Phone: "+8613800138000"},
+		},
+		{
+			name: "旧格式的号码找到之后改成 E.164",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").Return(dao.User{}, dao.ErrUserNoFound)
+				d.EXPECT().FindByPhone(gomock.Any(), "13800138000").
+					Return(dao.User{Id: 123, Phone: sql.NullString{String: "13800138000", Valid: true}}, nil)
+				d.EXPECT().UpdatePhone(gomock.Any(), int64(123), "+8613800138000").Return(nil)
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Delete(gomock.Any(), int64(123)).Return(nil)
+				return d, c
+			},
+			phone:    "+8613800138000",
+			wantUser: domain.User{Id: 123, Phone: "+8613800138000"},
+		},
+		{
+			name: "旧格式的号码改格式失败也能登录",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").Return(dao.User{}, dao.ErrUserNoFound)
+				d.EXPECT().FindByPhone(gomock.Any(), "13800138000").
+					Return(dao.User{Id: 123, Phone: sql.NullString{String: "13800138000", Valid: true}}, nil)
+				d.EXPECT().UpdatePhone(gomock.Any(), int64(123), "+8613800138000").Return(dao.ErrUserDuplicate)
+				return d, cachemocks.NewMockUserCache(ctrl)
+			},
+			phone:    "+8613800138000",
+			wantUser: domain.User{Id: 123, Phone: "+8613800138000"},
+		},
+		{
+			name: "两种格式都没有",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").Return(dao.User{}, dao.ErrUserNoFound)
+				d.EXPECT().FindByPhone(gomock.Any(), "13800138000").Return(dao.User{}, dao.ErrUserNoFound)
+				return d, cachemocks.NewMockUserCache(ctrl)
+			},
+			phone:   "+8613800138000",
+			wantErr: ErrUserNoFound,
+		},
+		{
+			name: "其它国家的号码没有旧格式",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "+14155552671").Return(dao.User{}, dao.ErrUserNoFound)
+				return d, cachemocks.NewMockUserCache(ctrl)
+			},
+			phone:   "+14155552671",
+			wantErr: ErrUserNoFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewUserRepository(d, c)
+			u, err := repo.FindByPhone(context.Background(), tc.phone)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantUser.Id, u.Id)
+			assert.Equal(t, tc.wantUser.Phone, u.Phone)
+		})
+	}
+}
+
+func TestUserRepository_UpdatePhone(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache)
+
+		wantErr error
+	}{
+		{
+			name: "旧格式的号码属于别的账号",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "13800138000").
+					Return(dao.User{Id: 456, Phone: sql.NullString{String: "13800138000", Valid: true}}, nil)
+				return d, cachemocks.NewMockUserCache(ctrl)
+			},
+			wantErr: ErrUserDuplicate,
+		},
+		{
+			name: "旧格式的号码是自己的",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "13800138000").
+					Return(dao.User{Id: 123, Phone: sql.NullString{String: "13800138000", Valid: true}}, nil)
+				d.EXPECT().UpdatePhone(gomock.Any(), int64(123), "+8613800138000").Return(nil)
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Delete(gomock.Any(), int64(123)).Return(nil)
+				return d, c
+			},
+		},
+		{
+			name: "没有旧格式的号码",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "13800138000").Return(dao.User{}, dao.ErrUserNoFound)
+				d.EXPECT().UpdatePhone(gomock.Any(), int64(123), "+8613800138000").Return(nil)
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Delete(gomock.Any(), int64(123)).Return(nil)
+				return d, c
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewUserRepository(d, c)
+			err := repo.UpdatePhone(context.Background(), 123, "+8613800138000")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
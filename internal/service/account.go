@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrAccountNotActive     = errors.New("账号当前状态不允许这个操作")
+	ErrNotPendingDeletion   = repository.ErrUserNotPendingDeletion
+	ErrUnknownArticlePolicy = errors.New("未知的文章处理策略")
+	ErrMergeSelf            = errors.New("不能和自己合并")
+	ErrUserNotMergeable     = repository.ErrUserNotMergeable
+)
+
+// ArticlePolicy 注销账号之后怎么处理用户的文章
+type ArticlePolicy string
+
+const (
+	// ArticlePolicyKeep 文章原样保留，只有作者的账号被匿名化
+	ArticlePolicyKeep ArticlePolicy = "keep"
+	// ArticlePolicyHide 文章全部设为仅自己可见，实际上就是谁都看不到了
+	ArticlePolicyHide ArticlePolicy = "hide"
+	// ArticlePolicyDelete 直接删除
+	ArticlePolicyDelete ArticlePolicy = "delete"
+)
+
+// Valid 策略写错了要等冷静期结束、真正删除账号的时候才会报错，所以启动的时候就要校验
+func (p ArticlePolicy) Valid() bool {
+	switch p {
+	case ArticlePolicyKeep, ArticlePolicyHide, ArticlePolicyDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+type DeletionConfig struct {
+	// GracePeriod 冷静期，期间用户可以撤销注销申请
+	GracePeriod   time.Duration
+	ArticlePolicy ArticlePolicy
+	// BatchSize 每次最多处理多少个到期的账号
+	BatchSize int
+}
+
+// SessionRevoker 删除账号的时候要踢掉所有设备，由 jwt.Handler 实现
+type SessionRevoker interface {
+	RevokeAllSessions(ctx context.Context, uid int64) error
+}
+
+// AccountService 账号停用和注销
+type AccountService interface {
+	// Deactivate 停用账号，重新登录就恢复
+	Deactivate(ctx context.Context, uid int64) error
+	// RequestDeletion 申请注销，返回真正删除的时间
+	RequestDeletion(ctx context.Context, uid int64) (time.Time, error)
+	CancelDeletion(ctx context.Context, uid int64) error
+	// PurgeDue 删除冷静期已经结束的账号，返回处理了多少个
+	PurgeDue(ctx context.Context) (int, error)
+	// MergeByPhone 把手机号对应的账号合并到 uid，调用之前要先验证手机号属于当前用户，
+	// 对方账号还要用 cred 重新认证，只证明手机号不够
+	MergeByPhone(ctx context.Context, uid int64, phone string, cred domain.Credential) error
+	// MergeByEmail 把邮箱对应的账号合并到 uid，要求和 MergeByPhone 一样
+	MergeByEmail(ctx context.Context, uid int64, email string, cred domain.Credential) error
+}
+
+type accountService struct {
+	userRepo    repository.UserRepository
+	articleRepo repository.ArticleRepository
+	userSvc     UserService
+	mfaSvc      MFAService
+	sessions    SessionRevoker
+	cfg         DeletionConfig
+}
+
+func NewAccountService(userRepo repository.UserRepository, articleRepo repository.ArticleRepository,
+	userSvc UserService, mfaSvc MFAService, sessions SessionRevoker, cfg DeletionConfig) AccountService {
+	return &accountService{
+		userRepo:    userRepo,
+		articleRepo: articleRepo,
+		userSvc:     userSvc,
+		mfaSvc:      mfaSvc,
+		sessions:    sessions,
+		cfg:         cfg,
+	}
+}
+
+func (svc *accountService) Deactivate(ctx context.Context, uid int64) error {
+	u, err := svc.userRepo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Status != domain.UserStatusActive {
+		return ErrAccountNotActive
+	}
+	err = svc.userRepo.UpdateStatus(ctx, uid, domain.UserStatusDeactivated)
+	if err != nil {
+		return err
+	}
+	return svc.sessions.RevokeAllSessions(ctx, uid)
+}
+
+func (svc *accountService) RequestDeletion(ctx context.Context, uid int64) (time.Time, error) {
+	u, err := svc.userRepo.FindByUid(ctx, uid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch u.Status {
+	case domain.UserStatusPendingDeletion:
+		// 重复申请不重新计算冷静期
+		return u.DeleteAfter, nil
+	case domain.UserStatusActive, domain.UserStatusDeactivated:
+	default:
+		return time.Time{}, ErrAccountNotActive
+	}
+	deleteAfter := time.Now().Add(svc.cfg.GracePeriod)
+	err = svc.userRepo.ScheduleDeletion(ctx, uid, deleteAfter)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return deleteAfter, svc.sessions.RevokeAllSessions(ctx, uid)
+}
+
+func (svc *accountService) CancelDeletion(ctx context.Context, uid int64) error {
+	return svc.userRepo.CancelDeletion(ctx, uid)
+}
+
+func (svc *accountService) PurgeDue(ctx context.Context) (int, error) {
+	users, err := svc.userRepo.FindDueDeletion(ctx, time.Now(), svc.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	cnt := 0
+	for _, u := range users {
+		err = svc.purge(ctx, u.Id)
+		if err != nil {
+			// 单个失败不影响其它账号，下一轮还会再捞出来
+			zap.L().Error("删除账号失败", zap.Int64("uid", u.Id), zap.Error(err))
+			continue
+		}
+		cnt++
+	}
+	return cnt, nil
+}
+
+// purge 每一步都是幂等的，中途失败了下次重跑就可以
+func (svc *accountService) purge(ctx context.Context, uid int64) error {
+	var err error
+	switch svc.cfg.ArticlePolicy {
+	case ArticlePolicyKeep:
+	case ArticlePolicyHide:
+		err = svc.articleRepo.UpdateStatusByAuthor(ctx, uid, domain.ArticleStatusPrivate)
+	case ArticlePolicyDelete:
+		err = svc.articleRepo.DeleteByAuthor(ctx, uid)
+	default:
+		err = ErrUnknownArticlePolicy
+	}
+	if err != nil {
+		return err
+	}
+	err = svc.sessions.RevokeAllSessions(ctx, uid)
+	if err != nil {
+		return err
+	}
+	// 最后再匿名化，匿名化之后就不会再被捞出来了
+	return svc.userRepo.Anonymize(ctx, uid)
+}
+
+func (svc *accountService) MergeByPhone(ctx context.Context, uid int64, phone string, cred domain.Credential) error {
+	u, err := svc.userRepo.FindByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	return svc.merge(ctx, u.Id, uid, cred)
+}
+
+func (svc *accountService) MergeByEmail(ctx context.Context, uid int64, email string, cred domain.Credential) error {
+	u, err := svc.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	return svc.merge(ctx, u.Id, uid, cred)
+}
+
+// merge 保留当前登录的账号，另一个账号合并之后所有设备都要退出
+func (svc *accountService) merge(ctx context.Context, from int64, to int64, cred domain.Credential) error {
+	if from == to {
+		return ErrMergeSelf
+	}
+	// 被合并的账号里面的数据都会归当前账号所有，要求和登录这个账号一样的认证强度
+	err := svc.userSvc.Reauthenticate(ctx, from, cred)
+	if err != nil {
+		return err
+	}
+	// 合并会删掉被合并账号的二次验证，它的密码却可能被搬到当前账号上，
+	// 当前账号没有开启二次验证的话，这个密码就能绕过二次验证登录，所以直接拒绝
+	fromMFA, err := svc.mfaSvc.IsEnabled(ctx, from)
+	if err != nil {
+		return err
+	}
+	if fromMFA {
+		toMFA, err := svc.mfaSvc.IsEnabled(ctx, to)
+		if err != nil {
+			return err
+		}
+		if !toMFA {
+			return ErrUserNotMergeable
+		}
+	}
+	err = svc.userRepo.Merge(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	zap.L().Info("合并账号", zap.Int64("from", from), zap.Int64("to", to))
+	return svc.sessions.RevokeAllSessions(ctx, from)
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	svcmocks "github.com/skcheng003/webook/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+// recordRevoker 记下被踢掉所有设备的用户
+type recordRevoker struct {
+	uids []int64
+}
+
+func (r *recordRevoker) RevokeAllSessions(ctx context.Context, uid int64) error {
+	r.uids = append(r.uids, uid)
+	return nil
+}
+
+func TestAccountService_PurgeDue(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy ArticlePolicy
+		mock   func(ctrl *gomock.Controller) (repository.UserRepository, repository.ArticleRepository)
+
+		wantCnt     int
+		wantRevoked []int64
+	}{
+		{
+			name:   "保留文章",
+			policy: ArticlePolicyKeep,
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.ArticleRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindDueDeletion(gomock.Any(), gomock.Any(), 100).
+					Return([]domain.User{{Id: 123}}, nil)
+				userRepo.EXPECT().Anonymize(gomock.Any(), int64(123)).Return(nil)
+				return userRepo, repomocks.NewMockArticleRepository(ctrl)
+			},
+			wantCnt:     1,
+			wantRevoked: []int64{123},
+		},
+		{
+			name:   "隐藏文章",
+			policy: ArticlePolicyHide,
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.ArticleRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindDueDeletion(gomock.Any(), gomock.Any(), 100).
+					Return([]domain.User{{Id: 123}}, nil)
+				userRepo.EXPECT().Anonymize(gomock.Any(), int64(123)).Return(nil)
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				artRepo.EXPECT().UpdateStatusByAuthor(gomock.Any(), int64(123), domain.ArticleStatusPrivate).Return(nil)
+				return userRepo, artRepo
+			},
+			wantCnt:     1,
+			wantRevoked: []int64{123},
+		},
+		{
+			name:   "删除文章",
+			policy: ArticlePolicyDelete,
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.ArticleRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindDueDeletion(gomock.Any(), gomock.Any(), 100).
+					Return([]domain.User{{Id: 123}}, nil)
+				userRepo.EXPECT().Anonymize(gomock.Any(), int64(123)).Return(nil)
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				artRepo.EXPECT().DeleteByAuthor(gomock.Any(), int64(123)).Return(nil)
+				return userRepo, artRepo
+			},
+			wantCnt:     1,
+			wantRevoked: []int64{123},
+		},
+		{
+			name:   "未知的策略不删除账号",
+			policy: "archive",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.ArticleRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindDueDeletion(gomock.Any(), gomock.Any(), 100).
+					Return([]domain.User{{Id: 123}}, nil)
+				return userRepo, repomocks.NewMockArticleRepository(ctrl)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, artRepo := tc.mock(ctrl)
+			revoker := &recordRevoker{}
+			svc := NewAccountService(userRepo, artRepo, nil, nil, revoker,
+				DeletionConfig{ArticlePolicy: tc.policy, BatchSize: 100})
+			cnt, err := svc.PurgeDue(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantCnt, cnt)
+			assert.Equal(t, tc.wantRevoked, revoker.uids)
+		})
+	}
+}
+
+func TestAccountService_MergeByPhone(t *testing.T) {
+	cred := domain.Credential{Password: "hello#world123", MFACode: "123456", IP: "127.0.0.1"}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, UserService, MFAService)
+
+		wantErr     error
+		wantRevoked []int64
+	}{
+		{
+			name: "合并成功",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, UserService, MFAService) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").
+					Return(domain.User{Id: 456}, nil)
+				userRepo.EXPECT().Merge(gomock.Any(), int64(456), int64(123)).Return(nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Reauthenticate(gomock.Any(), int64(456), cred).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(456)).Return(false, nil)
+				return userRepo, userSvc, mfaSvc
+			},
+			wantRevoked: []int64{456},
+		},
+		{
+			name: "两个账号都开启了二次验证",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, UserService, MFAService) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").
+					Return(domain.User{Id: 456}, nil)
+				userRepo.EXPECT().Merge(gomock.Any(), int64(456), int64(123)).Return(nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Reauthenticate(gomock.Any(), int64(456), cred).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(456)).Return(true, nil)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(123)).Return(true, nil)
+				return userRepo, userSvc, mfaSvc
+			},
+			wantRevoked: []int64{456},
+		},
+		{
+			name: "被合并的账号开启了二次验证，当前账号没有",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, UserService, MFAService) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").
+					Return(domain.User{Id: 456}, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Reauthenticate(gomock.Any(), int64(456), cred).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(456)).Return(true, nil)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(123)).Return(false, nil)
+				return userRepo, userSvc, mfaSvc
+			},
+			wantErr: ErrUserNotMergeable,
+		},
+		{
+			name: "重新认证失败",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, UserService, MFAService) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").
+					Return(domain.User{Id: 456}, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Reauthenticate(gomock.Any(), int64(456), cred).
+					Return(ErrInvalidUserOrPassword)
+				return userRepo, userSvc, svcmocks.NewMockMFAService(ctrl)
+			},
+			wantErr: ErrInvalidUserOrPassword,
+		},
+		{
+			name: "和自己合并",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, UserService, MFAService) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "+8613800138000").
+					Return(domain.User{Id: 123}, nil)
+				return userRepo, svcmocks.NewMockUserService(ctrl), svcmocks.NewMockMFAService(ctrl)
+			},
+			wantErr: ErrMergeSelf,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, userSvc, mfaSvc := tc.mock(ctrl)
+			revoker := &recordRevoker{}
+			svc := NewAccountService(userRepo, repomocks.NewMockArticleRepository(ctrl), userSvc, mfaSvc,
+				revoker, DeletionConfig{})
+			err := svc.MergeByPhone(context.Background(), 123, "+8613800138000", cred)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRevoked, revoker.uids)
+		})
+	}
+}
+
+func TestArticlePolicy_Valid(t *testing.T) {
+	for _, p := range []ArticlePolicy{ArticlePolicyKeep, ArticlePolicyHide, ArticlePolicyDelete} {
+		assert.True(t, p.Valid(), p)
+	}
+	for _, p := range []ArticlePolicy{"", "Keep", "archive"} {
+		assert.False(t, p.Valid(), p)
+	}
+}
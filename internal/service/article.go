@@ -6,39 +6,69 @@ import (
 	"github.com/skcheng003/webook/internal/repository"
 )
 
+var (
+	ErrArticleNoFound   = repository.ErrArticleNoFound
+	ErrArticleNotOwned  = repository.ErrArticleNotOwned
+	ErrArticleModerated = repository.ErrArticleModerated
+)
+
 type ArticleService interface {
 	Save(ctx context.Context, art domain.Article) (int64, error)
 	Publish(ctx context.Context, art domain.Article) (int64, error)
 	Withdraw(ctx context.Context, uid int64, articleId int64) error
 	GetById(ctx context.Context, id int64) (art domain.Article, err error)
 	GetPubById(ctx context.Context, id int64) (art domain.Article, err error)
+	// Unpublish 管理员下架文章，不校验作者，下架之后作者不能再修改或者重新发表
+	Unpublish(ctx context.Context, articleId int64) error
 }
 
 type articleService struct {
-	repo repository.CachedArticleRepository
+	repo repository.ArticleRepository
+}
+
+func NewArticleService(repo repository.ArticleRepository) ArticleService {
+	return &articleService{
+		repo: repo,
+	}
 }
 
 func (svc *articleService) Save(ctx context.Context, art domain.Article) (int64, error) {
-	return repo.
+	art.Status = domain.ArticleStatusUnPublished
+	return svc.save(ctx, art)
 }
 
 func (svc *articleService) Publish(ctx context.Context, art domain.Article) (int64, error) {
-	// TODO implement me
-	panic("implement me")
+	art.Status = domain.ArticleStatusPublished
+	return svc.save(ctx, art)
 }
 
 func (svc *articleService) Withdraw(ctx context.Context, uid int64, articleId int64) error {
-	// TODO implement me
-	panic("implement me")
+	return svc.repo.SyncStatus(ctx, uid, articleId, domain.ArticleStatusPrivate)
 }
 
 func (svc *articleService) GetById(ctx context.Context, id int64) (art domain.Article, err error) {
-	// TODO implement me
-	panic("implement me")
+	return svc.repo.FindById(ctx, id)
 }
 
 func (svc *articleService) GetPubById(ctx context.Context, id int64) (art domain.Article, err error) {
-	// TODO implement me
-	panic("implement me")
+	art, err = svc.repo.FindById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	// 没有发表的文章对读者来说就是不存在
+	if art.Status != domain.ArticleStatusPublished {
+		return domain.Article{}, ErrArticleNoFound
+	}
+	return art, nil
 }
 
+func (svc *articleService) Unpublish(ctx context.Context, articleId int64) error {
+	return svc.repo.UpdateStatus(ctx, articleId, domain.ArticleStatusModerated)
+}
+
+func (svc *articleService) save(ctx context.Context, art domain.Article) (int64, error) {
+	if art.Id > 0 {
+		return art.Id, svc.repo.Update(ctx, art)
+	}
+	return svc.repo.Create(ctx, art)
+}
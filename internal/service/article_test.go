@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestArticleService_Moderation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockArticleRepository(ctrl)
+	// 管理员下架用单独的状态，不是作者自己撤回的仅自己可见
+	repo.EXPECT().UpdateStatus(gomock.Any(), int64(1), domain.ArticleStatusModerated).Return(nil)
+	// 下架之后作者重新发表会被 dao 拒绝
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(repository.ErrArticleModerated)
+	svc := NewArticleService(repo)
+
+	err := svc.Unpublish(context.Background(), 1)
+	assert.NoError(t, err)
+	_, err = svc.Publish(context.Background(), domain.Article{Id: 1, Author: domain.Author{Id: 2}})
+	assert.ErrorIs(t, err, ErrArticleModerated)
+}
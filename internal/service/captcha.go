@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/pkg/captcha"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrCaptchaRequired = errors.New("请求太频繁，需要先输入图形验证码")
+	ErrCaptchaInvalid  = errors.New("图形验证码错误或者已经过期")
+)
+
+type CaptchaConfig struct {
+	// TTL 图形验证码的有效期
+	TTL time.Duration
+	// Threshold 同一个 IP 在 Window 内请求超过这么多次，就要先输入图形验证码
+	Threshold int64
+	Window    time.Duration
+}
+
+type CaptchaService interface {
+	// Generate 生成一个图形验证码，返回 id 和 PNG 图片
+	Generate(ctx context.Context) (string, []byte, error)
+	// Verify 不管对不对，验证码都只能用一次
+	Verify(ctx context.Context, id string, answer string) error
+	// Check 记录 ip 在 scene 下的一次请求，请求太多的时候要带上正确的图形验证码
+	Check(ctx context.Context, scene string, ip string, id string, answer string) error
+}
+
+type captchaService struct {
+	repo repository.CaptchaRepository
+	cfg  CaptchaConfig
+}
+
+func NewCaptchaService(repo repository.CaptchaRepository, cfg CaptchaConfig) CaptchaService {
+	return &captchaService{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+func (svc *captchaService) Generate(ctx context.Context) (string, []byte, error) {
+	c, err := captcha.NewArithmetic()
+	if err != nil {
+		return "", nil, err
+	}
+	img, err := captcha.Render(c.Question)
+	if err != nil {
+		return "", nil, err
+	}
+	id := uuid.New().String()
+	err = svc.repo.Store(ctx, id, c.Answer, svc.cfg.TTL)
+	return id, img, err
+}
+
+func (svc *captchaService) Verify(ctx context.Context, id string, answer string) error {
+	if id == "" || answer == "" {
+		return ErrCaptchaInvalid
+	}
+	want, err := svc.repo.Take(ctx, id)
+	if errors.Is(err, repository.ErrCaptchaNotFound) {
+		return ErrCaptchaInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if want != answer {
+		return ErrCaptchaInvalid
+	}
+	return nil
+}
+
+func (svc *captchaService) Check(ctx context.Context, scene string, ip string,
+	id string, answer string) error {
+	cnt, err := svc.repo.IncrHit(ctx, scene, ip, svc.cfg.Window)
+	if err != nil {
+		// Redis 出问题的时候放行，发验证码还有每天的次数限制兜底
+		zap.L().Error("统计图形验证码触发次数失败", zap.String("scene", scene), zap.Error(err))
+		return nil
+	}
+	if cnt <= svc.cfg.Threshold {
+		return nil
+	}
+	if id == "" {
+		return ErrCaptchaRequired
+	}
+	return svc.Verify(ctx, id, answer)
+}
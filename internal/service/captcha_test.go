@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestCaptchaService_Check(t *testing.T) {
+	cfg := CaptchaConfig{TTL: time.Minute, Threshold: 5, Window: time.Hour}
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) repository.CaptchaRepository
+		id     string
+		answer string
+
+		wantErr error
+	}{
+		{
+			name: "没有超过阈值",
+			mock: func(ctrl *gomock.Controller) repository.CaptchaRepository {
+				repo := repomocks.NewMockCaptchaRepository(ctrl)
+				repo.EXPECT().IncrHit(gomock.Any(), "/users/login_sms/code/send", "10.0.0.1", time.Hour).
+					Return(int64(5), nil)
+				return repo
+			},
+		},
+		{
+			name: "超过阈值没有带验证码",
+			mock: func(ctrl *gomock.Controller) repository.CaptchaRepository {
+				repo := repomocks.NewMockCaptchaRepository(ctrl)
+				repo.EXPECT().IncrHit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(6), nil)
+				return repo
+			},
+			wantErr: ErrCaptchaRequired,
+		},
+		{
+			name: "超过阈值验证码正确",
+			mock: func(ctrl *gomock.Controller) repository.CaptchaRepository {
+				repo := repomocks.NewMockCaptchaRepository(ctrl)
+				repo.EXPECT().IncrHit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(6), nil)
+				repo.EXPECT().Take(gomock.Any(), "abc").Return("12", nil)
+				return repo
+			},
+			id:     "abc",
+			answer: "12",
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) repository.CaptchaRepository {
+				repo := repomocks.NewMockCaptchaRepository(ctrl)
+				repo.EXPECT().IncrHit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(6), nil)
+				repo.EXPECT().Take(gomock.Any(), "abc").Return("12", nil)
+				return repo
+			},
+			id:      "abc",
+			answer:  "13",
+			wantErr: ErrCaptchaInvalid,
+		},
+		{
+			name: "验证码已经用过或者过期",
+			mock: func(ctrl *gomock.Controller) repository.CaptchaRepository {
+				repo := repomocks.NewMockCaptchaRepository(ctrl)
+				repo.EXPECT().IncrHit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(6), nil)
+				repo.EXPECT().Take(gomock.Any(), "abc").Return("", repository.ErrCaptchaNotFound)
+				return repo
+			},
+			id:      "abc",
+			answer:  "12",
+			wantErr: ErrCaptchaInvalid,
+		},
+		{
+			name: "Redis 出错放行",
+			mock: func(ctrl *gomock.Controller) repository.CaptchaRepository {
+				repo := repomocks.NewMockCaptchaRepository(ctrl)
+				repo.EXPECT().IncrHit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("redis 错误"))
+				return repo
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCaptchaService(tc.mock(ctrl), cfg)
+			err := svc.Check(context.Background(), "/users/login_sms/code/send", "10.0.0.1", tc.id, tc.answer)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
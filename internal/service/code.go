@@ -2,17 +2,50 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"github.com/skcheng003/webook/internal/domain"
 	"github.com/skcheng003/webook/internal/repository"
 	"github.com/skcheng003/webook/internal/service/sms"
-	"math/rand"
+	"github.com/skcheng003/webook/internal/service/sms/template"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"go.uber.org/zap"
+	"math/big"
+	"strings"
 )
 
-var ErrCodeSendTooMany = repository.ErrCodeSendTooMany
+var (
+	ErrCodeSendTooMany   = repository.ErrCodeSendTooMany
+	ErrCodeQuotaExceeded = repository.ErrCodeQuotaExceeded
+	// ErrCodeVerifyTooManyTimes 验证码已经作废，只能重新获取
+	ErrCodeVerifyTooManyTimes = repository.ErrCodeVerifyTooManyTimes
+	ErrPhoneBlocked           = errors.New("这个号段不能接收验证码")
+)
+
+// ClientIPContextKey 客户端 IP 由 web 层放进 context，用字符串做 key，
+// 这样 gin.Context 里面 Set 的值也能通过 Value 取到
+const ClientIPContextKey = "client_ip"
+
+// CodeLimitConfig 防短信轰炸，短信是要花钱的
+type CodeLimitConfig struct {
+	Daily repository.CodeQuotaLimit
+	// BlockedPrefixes 不发验证码的号段，带国家码，比如虚拟运营商的 +86170
+	BlockedPrefixes []string
+}
 
-// const codeTplId = "1877556"
-const codeTplId = "1110"
+// CodePolicies 按业务场景取验证码策略，没有单独配置的用 Default
+type CodePolicies struct {
+	Default domain.CodePolicy
+	Biz     map[string]domain.CodePolicy
+}
+
+func (p CodePolicies) Get(biz string) domain.CodePolicy {
+	if policy, ok := p.Biz[biz]; ok {
+		return policy
+	}
+	return p.Default
+}
 
 var _ CodeService = (*SMSCodeService)(nil)
 
@@ -22,42 +55,87 @@ type CodeService interface {
 }
 
 type SMSCodeService struct {
-	sms  sms.Service
-	repo repository.CodeRepository
+	sms      sms.Service
+	repo     repository.CodeRepository
+	quota    repository.CodeQuotaRepository
+	limit    CodeLimitConfig
+	policies CodePolicies
 }
 
-func NewSMSCodeService(svc sms.Service, repo repository.CodeRepository) CodeService {
+func NewSMSCodeService(svc sms.Service, repo repository.CodeRepository,
+	quota repository.CodeQuotaRepository, limit CodeLimitConfig, policies CodePolicies) CodeService {
 	return &SMSCodeService{
-		sms:  svc,
-		repo: repo,
+		sms:      svc,
+		repo:     repo,
+		quota:    quota,
+		limit:    limit,
+		policies: policies,
 	}
 }
 
-// Send 发送验证码，用 biz 来对业务场景进行区分
+// Send 发送验证码，用 biz 来对业务场景进行区分。
+// 号段黑名单、每天的次数和重发间隔都检查过了才会调用服务商
 func (svc *SMSCodeService) Send(ctx context.Context, biz string, phone string) error {
-	code := svc.generate()
-	err := svc.repo.Store(ctx, biz, phone, code)
+	if svc.blocked(phone) {
+		return ErrPhoneBlocked
+	}
+	policy := svc.policies.Get(biz)
+	code, err := generateCode(policy.Length)
 	if err != nil {
 		return err
 	}
-	err = svc.sms.Send(ctx, codeTplId, []string{code}, phone)
+	// 先占用次数再写验证码，超过上限的时候不会覆盖掉用户手上还有效的验证码
+	ip, _ := ctx.Value(ClientIPContextKey).(string)
+	err = svc.quota.Incr(ctx, biz, phone, ip, svc.limit.Daily)
+	if errors.Is(err, ErrCodeQuotaExceeded) {
+		// 接入告警之后这里要告警
+		zap.L().Warn("验证码发送次数超过上限", zap.String("biz", biz),
+			zap.String("phone", domain.MaskPhone(phone)), zap.String("ip", ip))
+	}
+	if err != nil {
+		return err
+	}
+	err = svc.repo.Store(ctx, biz, phone, code, policy)
+	if err != nil {
+		// 重发间隔内的请求不算次数，真正发出去的才算，把占用的次数还回去
+		if er := svc.quota.Decr(ctx, biz, phone, ip); er != nil {
+			zap.L().Warn("归还验证码发送次数失败", zap.String("biz", biz), zap.Error(er))
+		}
+		return err
+	}
+	// 具体用哪个模板由当前的服务商决定，见 template.Registry
+	err = svc.sms.Send(ctx, template.Key(biz, i18n.FromContext(ctx)), []string{code}, phone)
 	// 如果 err != nil, 可以考虑设计一个 retrySendService 来进行重试，不管也行
 	return err
 }
 
-// Verify 对验证码进行验证
+// Verify 对验证码进行验证，次数用完之后返回 ErrCodeVerifyTooManyTimes，提示用户重新获取
 func (svc *SMSCodeService) Verify(ctx context.Context, biz string, phone string, inputCode string) (bool, error) {
 	ok, err := svc.repo.Verify(ctx, biz, phone, inputCode)
-	// 这里我们在 service 层面上对 Handler 屏蔽了最为特殊的错误
-	if errors.Is(err, ErrCodeSendTooMany) {
+	if errors.Is(err, ErrCodeVerifyTooManyTimes) {
 		// 在接入了告警之后，这边要告警
 		// 因为这意味着有人在搞你
-		return false, nil
+		zap.L().Warn("验证码验证次数用完", zap.String("biz", biz),
+			zap.String("phone", domain.MaskPhone(phone)))
 	}
 	return ok, err
 }
 
-func (svc *SMSCodeService) generate() string {
-	num := rand.Intn(999999)
-	return fmt.Sprintf("%06d", num)
+func (svc *SMSCodeService) blocked(phone string) bool {
+	for _, prefix := range svc.limit.BlockedPrefixes {
+		if strings.HasPrefix(phone, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateCode 生成 length 位的数字验证码，用 crypto/rand，不能被预测
+func generateCode(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	num, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, num), nil
 }
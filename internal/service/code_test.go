@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	smsmocks "github.com/skcheng003/webook/internal/service/sms/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestSMSCodeService_Send(t *testing.T) {
+	limit := CodeLimitConfig{
+		Daily:           repository.CodeQuotaLimit{PhoneBiz: 10, Phone: 20, IP: 100},
+		BlockedPrefixes: []string{"+86170"},
+	}
+	policies := CodePolicies{
+		Default: domain.CodePolicy{Length: 6, Expiration: time.Minute * 10, ResendInterval: time.Minute, MaxAttempts: 3},
+		Biz: map[string]domain.CodePolicy{
+			"user/unlock": {Length: 8, Expiration: time.Minute * 5, ResendInterval: time.Minute, MaxAttempts: 5},
+		},
+	}
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository)
+		ctx   context.Context
+		biz   string
+		phone string
+
+		wantErr error
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "user/login#zh-CN", gomock.Any(), "+8613800000000").Return(nil)
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "user/login", "+8613800000000", gomock.Any(), policies.Default).Return(nil)
+				quota := repomocks.NewMockCodeQuotaRepository(ctrl)
+				quota.EXPECT().Incr(gomock.Any(), "user/login", "+8613800000000", "10.0.0.1", limit.Daily).Return(nil)
+				return svc, repo, quota
+			},
+			ctx:   context.WithValue(context.Background(), ClientIPContextKey, "10.0.0.1"),
+			biz:   "user/login",
+			phone: "+8613800000000",
+		},
+		{
+			name: "按业务场景的策略生成验证码",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "user/unlock#zh-CN", gomock.Any(), "+8613800000000").
+					DoAndReturn(func(ctx context.Context, tplId string, args []string, numbers ...string) error {
+						assert.Len(t, args[0], 8)
+						return nil
+					})
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "user/unlock", "+8613800000000", gomock.Any(),
+					policies.Biz["user/unlock"]).Return(nil)
+				quota := repomocks.NewMockCodeQuotaRepository(ctrl)
+				quota.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				return svc, repo, quota
+			},
+			ctx:   context.Background(),
+			biz:   "user/unlock",
+			phone: "+8613800000000",
+		},
+		{
+			name: "号段被拉黑",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				return smsmocks.NewMockService(ctrl), repomocks.NewMockCodeRepository(ctrl),
+					repomocks.NewMockCodeQuotaRepository(ctrl)
+			},
+			ctx:     context.Background(),
+			biz:     "user/login",
+			phone:   "+8617000000000",
+			wantErr: ErrPhoneBlocked,
+		},
+		{
+			name: "一分钟内重发不算次数",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(ErrCodeSendTooMany)
+				quota := repomocks.NewMockCodeQuotaRepository(ctrl)
+				incr := quota.EXPECT().Incr(gomock.Any(), "user/login", "+8613800000000", "", limit.Daily).Return(nil)
+				quota.EXPECT().Decr(gomock.Any(), "user/login", "+8613800000000", "").Return(nil).After(incr)
+				return smsmocks.NewMockService(ctrl), repo, quota
+			},
+			ctx:     context.Background(),
+			biz:     "user/login",
+			phone:   "+8613800000000",
+			wantErr: ErrCodeSendTooMany,
+		},
+		{
+			name: "超过每天的上限不覆盖验证码也不调用服务商",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				quota := repomocks.NewMockCodeQuotaRepository(ctrl)
+				// 没有 IP 的时候不按 IP 统计
+				quota.EXPECT().Incr(gomock.Any(), "user/login", "+8613800000000", "", limit.Daily).
+					Return(ErrCodeQuotaExceeded)
+				return smsmocks.NewMockService(ctrl), repo, quota
+			},
+			ctx:     context.Background(),
+			biz:     "user/login",
+			phone:   "+8613800000000",
+			wantErr: ErrCodeQuotaExceeded,
+		},
+		{
+			name: "Redis 出错",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				quota := repomocks.NewMockCodeQuotaRepository(ctrl)
+				quota.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("redis 错误"))
+				return smsmocks.NewMockService(ctrl), repo, quota
+			},
+			ctx:     context.Background(),
+			biz:     "user/login",
+			phone:   "+8613800000000",
+			wantErr: errors.New("redis 错误"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			smsSvc, repo, quota := tc.mock(ctrl)
+			svc := NewSMSCodeService(smsSvc, repo, quota, limit, policies)
+			err := svc.Send(tc.ctx, tc.biz, tc.phone)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestSMSCodeService_Verify(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) *repomocks.MockCodeRepository
+
+		wantOk  bool
+		wantErr error
+	}{
+		{
+			name: "验证通过",
+			mock: func(ctrl *gomock.Controller) *repomocks.MockCodeRepository {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Verify(gomock.Any(), "user/login", "+8613800000000", "123456").Return(true, nil)
+				return repo
+			},
+			wantOk: true,
+		},
+		{
+			name: "验证次数用完",
+			mock: func(ctrl *gomock.Controller) *repomocks.MockCodeRepository {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Verify(gomock.Any(), "user/login", "+8613800000000", "123456").
+					Return(false, repository.ErrCodeVerifyTooManyTimes)
+				return repo
+			},
+			wantErr: ErrCodeVerifyTooManyTimes,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewSMSCodeService(nil, tc.mock(ctrl), nil, CodeLimitConfig{}, CodePolicies{})
+			ok, err := svc.Verify(context.Background(), "user/login", "+8613800000000", "123456")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantOk, ok)
+		})
+	}
+}
+
+func TestGenerateCode(t *testing.T) {
+	for _, length := range []int{4, 6, 10} {
+		code, err := generateCode(length)
+		assert.NoError(t, err)
+		assert.Regexp(t, fmt.Sprintf(`^\d{%d}$`, length), code)
+	}
+}
+
+// recordEmail 记下发出去的邮件
+type recordEmail struct {
+	to []string
+}
+
+func (r *recordEmail) Send(ctx context.Context, to string, subject string, body string) error {
+	r.to = append(r.to, to)
+	return nil
+}
+
+func TestMailCodeService_Send(t *testing.T) {
+	limit := CodeLimitConfig{
+		Daily: repository.CodeQuotaLimit{PhoneBiz: 10, Phone: 20, IP: 100},
+	}
+	policies := CodePolicies{
+		Default: domain.CodePolicy{Length: 6, Expiration: time.Minute * 10, ResendInterval: time.Minute, MaxAttempts: 3},
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (*repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository)
+
+		wantErr  error
+		wantSent []string
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (*repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "user/bind_email", "a@example.com", gomock.Any(), policies.Default).Return(nil)
+				quota := repomocks.NewMockCodeQuotaRepository(ctrl)
+				quota.EXPECT().Incr(gomock.Any(), "user/bind_email", "a@example.com", "10.0.0.1", limit.Daily).Return(nil)
+				return repo, quota
+			},
+			wantSent: []string{"a@example.com"},
+		},
+		{
+			name: "一分钟内重发不算次数",
+			mock: func(ctrl *gomock.Controller) (*repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(ErrCodeSendTooMany)
+				quota := repomocks.NewMockCodeQuotaRepository(ctrl)
+				incr := quota.EXPECT().Incr(gomock.Any(), "user/bind_email", "a@example.com", "10.0.0.1", limit.Daily).Return(nil)
+				quota.EXPECT().Decr(gomock.Any(), "user/bind_email", "a@example.com", "10.0.0.1").Return(nil).After(incr)
+				return repo, quota
+			},
+			wantErr: ErrCodeSendTooMany,
+		},
+		{
+			name: "超过每天的上限不覆盖验证码也不发邮件",
+			mock: func(ctrl *gomock.Controller) (*repomocks.MockCodeRepository, *repomocks.MockCodeQuotaRepository) {
+				quota := repomocks.NewMockCodeQuotaRepository(ctrl)
+				quota.EXPECT().Incr(gomock.Any(), "user/bind_email", "a@example.com", "10.0.0.1", limit.Daily).
+					Return(ErrCodeQuotaExceeded)
+				return repomocks.NewMockCodeRepository(ctrl), quota
+			},
+			wantErr: ErrCodeQuotaExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, quota := tc.mock(ctrl)
+			sender := &recordEmail{}
+			svc := NewMailCodeService(sender, repo, quota, limit, policies)
+			ctx := context.WithValue(context.Background(), ClientIPContextKey, "10.0.0.1")
+			err := svc.Send(ctx, "user/bind_email", "a@example.com")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantSent, sender.to)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package email
+
+import "context"
+
+// Service 发送邮件的抽象，和 sms.Service 一样用来适配不同的供应商
+type Service interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/internal/service/email"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"go.uber.org/zap"
+)
+
+// EmailCodeService 和 CodeService 一样，只是验证码发到邮箱。
+// 单独定义一个类型，方便 wire 区分两种实现
+type EmailCodeService interface {
+	CodeService
+}
+
+type emailTpl struct {
+	subject string
+	body    string
+}
+
+var emailCodeTpls = map[i18n.Lang]emailTpl{
+	i18n.ZhCN: {
+		subject: "webook 验证码",
+		body:    "你的验证码是 %s，%d 分钟内有效。如果不是你本人操作，请忽略这封邮件。",
+	},
+	i18n.EnUS: {
+		subject: "Your webook verification code",
+		body:    "Your verification code is %s. It is valid for %d minutes. If you did not request it, please ignore this email.",
+	},
+}
+
+type MailCodeService struct {
+	email    email.Service
+	repo     repository.CodeRepository
+	quota    repository.CodeQuotaRepository
+	limit    CodeLimitConfig
+	policies CodePolicies
+}
+
+func NewMailCodeService(svc email.Service, repo repository.CodeRepository,
+	quota repository.CodeQuotaRepository, limit CodeLimitConfig, policies CodePolicies) EmailCodeService {
+	return &MailCodeService{
+		email:    svc,
+		repo:     repo,
+		quota:    quota,
+		limit:    limit,
+		policies: policies,
+	}
+}
+
+// Send 和短信验证码共用存储和每天的次数上限，biz 和地址不一样所以不会冲突，
+// 按 IP 的次数是短信和邮件加起来算的
+func (svc *MailCodeService) Send(ctx context.Context, biz string, addr string) error {
+	policy := svc.policies.Get(biz)
+	code, err := generateCode(policy.Length)
+	if err != nil {
+		return err
+	}
+	// 和短信一样先占用次数再写验证码，不然可以拿这个接口往任意邮箱里面灌邮件
+	ip, _ := ctx.Value(ClientIPContextKey).(string)
+	err = svc.quota.Incr(ctx, biz, addr, ip, svc.limit.Daily)
+	if errors.Is(err, ErrCodeQuotaExceeded) {
+		zap.L().Warn("邮件验证码发送次数超过上限", zap.String("biz", biz), zap.String("ip", ip))
+	}
+	if err != nil {
+		return err
+	}
+	err = svc.repo.Store(ctx, biz, addr, code, policy)
+	if err != nil {
+		if er := svc.quota.Decr(ctx, biz, addr, ip); er != nil {
+			zap.L().Warn("归还验证码发送次数失败", zap.String("biz", biz), zap.Error(er))
+		}
+		return err
+	}
+	tpl := emailCodeTpls[i18n.FromContext(ctx)]
+	return svc.email.Send(ctx, addr, tpl.subject,
+		fmt.Sprintf(tpl.body, code, int(policy.Expiration.Minutes())))
+}
+
+// Verify 和短信一样，次数用完之后返回 ErrCodeVerifyTooManyTimes
+func (svc *MailCodeService) Verify(ctx context.Context, biz string, addr string, inputCode string) (bool, error) {
+	return svc.repo.Verify(ctx, biz, addr, inputCode)
+}
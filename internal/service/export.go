@@ -0,0 +1,370 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/internal/service/email"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"go.uber.org/zap"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrExportInProgress  = errors.New("上一次导出还没有完成")
+	ErrExportNotFound    = errors.New("没有可以下载的导出文件")
+	ErrExportLinkInvalid = errors.New("下载链接无效或者已经过期")
+)
+
+type ExportConfig struct {
+	// Dir 导出文件存放的目录
+	Dir string
+	// Secret 下载链接的签名密钥
+	Secret string
+	// LinkTTL 下载链接的有效期，过期之后文件也会被清理
+	LinkTTL time.Duration
+	// BaseURL 拼下载链接用，比如 https://webook.com
+	BaseURL string
+}
+
+// ExportNotifier 导出完成之后通知用户
+type ExportNotifier interface {
+	Notify(ctx context.Context, uid int64, link string, expireAt time.Time) error
+}
+
+var exportReadyTpls = map[i18n.Lang]emailTpl{
+	i18n.ZhCN: {
+		subject: "webook 个人数据导出完成",
+		body:    "你申请导出的个人数据已经生成好了，下载链接：%s\n链接在 %s 之前有效，请不要转发给别人。",
+	},
+	i18n.EnUS: {
+		subject: "Your webook data export is ready",
+		body:    "Your personal data export is ready. Download it here: %s\nThe link is valid until %s. Please do not share it.",
+	},
+}
+
+// EmailExportNotifier 用用户绑定的邮箱通知，语言跟着用户的设置。
+// 没有绑定邮箱的用户只能自己查询导出状态
+type EmailExportNotifier struct {
+	email    email.Service
+	userRepo repository.UserRepository
+}
+
+func NewEmailExportNotifier(svc email.Service, userRepo repository.UserRepository) ExportNotifier {
+	return &EmailExportNotifier{
+		email:    svc,
+		userRepo: userRepo,
+	}
+}
+
+func (n *EmailExportNotifier) Notify(ctx context.Context, uid int64, link string, expireAt time.Time) error {
+	u, err := n.userRepo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Email == "" {
+		zap.L().Info("用户没有绑定邮箱，不发送导出通知", zap.Int64("uid", uid))
+		return nil
+	}
+	lang, ok := i18n.Parse(u.Locale)
+	if !ok {
+		lang = i18n.Default
+	}
+	tpl := exportReadyTpls[lang]
+	return n.email.Send(ctx, u.Email, tpl.subject,
+		fmt.Sprintf(tpl.body, link, expireAt.Format(time.DateTime)))
+}
+
+// ExportService 导出用户的个人数据
+type ExportService interface {
+	// Request 异步生成导出文件，完成之后通过 ExportNotifier 通知
+	Request(ctx context.Context, uid int64) (domain.ExportTask, error)
+	// Status 查询最近一次导出，ready 的时候会带上下载链接
+	Status(ctx context.Context, uid int64) (domain.ExportTask, string, error)
+	// Open 校验下载链接，返回文件路径
+	Open(ctx context.Context, uid int64, taskId string, expires int64, sig string) (string, error)
+	// Cleanup 删除已经过期的导出文件，返回删了多少个
+	Cleanup(ctx context.Context) (int, error)
+}
+
+type exportService struct {
+	repo         repository.ExportTaskRepository
+	userRepo     repository.UserRepository
+	articleRepo  repository.ArticleRepository
+	loginLogRepo repository.LoginLogRepository
+	notifier     ExportNotifier
+	cfg          ExportConfig
+}
+
+func NewExportService(repo repository.ExportTaskRepository, userRepo repository.UserRepository,
+	articleRepo repository.ArticleRepository, loginLogRepo repository.LoginLogRepository,
+	notifier ExportNotifier, cfg ExportConfig) ExportService {
+	return &exportService{
+		repo:         repo,
+		userRepo:     userRepo,
+		articleRepo:  articleRepo,
+		loginLogRepo: loginLogRepo,
+		notifier:     notifier,
+		cfg:          cfg,
+	}
+}
+
+func (svc *exportService) Request(ctx context.Context, uid int64) (domain.ExportTask, error) {
+	task, err := svc.repo.FindByUid(ctx, uid)
+	if err == nil && task.Status == domain.ExportStatusPending {
+		return task, ErrExportInProgress
+	}
+	if err != nil && !errors.Is(err, repository.ErrExportTaskNotFound) {
+		return domain.ExportTask{}, err
+	}
+	task = domain.ExportTask{
+		Id:     uuid.New().String(),
+		Uid:    uid,
+		Status: domain.ExportStatusPending,
+		Ctime:  time.Now(),
+	}
+	// pending 状态也用 LinkTTL 过期，防止进程挂了任务一直卡在 pending
+	err = svc.repo.Save(ctx, task, svc.cfg.LinkTTL)
+	if err != nil {
+		return domain.ExportTask{}, err
+	}
+	go svc.build(task)
+	return task, nil
+}
+
+func (svc *exportService) Status(ctx context.Context, uid int64) (domain.ExportTask, string, error) {
+	task, err := svc.repo.FindByUid(ctx, uid)
+	if errors.Is(err, repository.ErrExportTaskNotFound) {
+		return domain.ExportTask{}, "", ErrExportNotFound
+	}
+	if err != nil {
+		return domain.ExportTask{}, "", err
+	}
+	if task.Status != domain.ExportStatusReady {
+		return task, "", nil
+	}
+	return task, svc.link(task), nil
+}
+
+func (svc *exportService) Open(ctx context.Context, uid int64, taskId string, expires int64, sig string) (string, error) {
+	if time.Now().UnixMilli() > expires {
+		return "", ErrExportLinkInvalid
+	}
+	expected := svc.sign(uid, taskId, expires)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return "", ErrExportLinkInvalid
+	}
+	path := svc.path(uid, taskId)
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrExportLinkInvalid
+	}
+	return path, nil
+}
+
+func (svc *exportService) Cleanup(ctx context.Context) (int, error) {
+	entries, err := os.ReadDir(svc.cfg.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	deadline := time.Now().Add(-svc.cfg.LinkTTL)
+	cnt := 0
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || e.IsDir() || info.ModTime().After(deadline) {
+			continue
+		}
+		if err = os.Remove(filepath.Join(svc.cfg.Dir, e.Name())); err != nil {
+			zap.L().Warn("删除过期导出文件失败", zap.String("file", e.Name()), zap.Error(err))
+			continue
+		}
+		cnt++
+	}
+	return cnt, nil
+}
+
+// build 在后台跑，所以不能用请求的 ctx
+func (svc *exportService) build(task domain.ExportTask) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
+	defer cancel()
+	err := svc.writeArchive(ctx, task)
+	if err != nil {
+		zap.L().Error("生成导出文件失败", zap.Int64("uid", task.Uid), zap.Error(err))
+		task.Status = domain.ExportStatusFailed
+		_ = svc.repo.Save(ctx, task, svc.cfg.LinkTTL)
+		return
+	}
+	task.Status = domain.ExportStatusReady
+	task.ExpireAt = time.Now().Add(svc.cfg.LinkTTL)
+	err = svc.repo.Save(ctx, task, svc.cfg.LinkTTL)
+	if err != nil {
+		zap.L().Error("保存导出任务失败", zap.Int64("uid", task.Uid), zap.Error(err))
+		return
+	}
+	err = svc.notifier.Notify(ctx, task.Uid, svc.link(task), task.ExpireAt)
+	if err != nil {
+		// 用户还可以自己查询导出状态
+		zap.L().Warn("通知导出完成失败", zap.Int64("uid", task.Uid), zap.Error(err))
+	}
+}
+
+// writeArchive 先写临时文件再改名，避免下载到写了一半的文件
+func (svc *exportService) writeArchive(ctx context.Context, task domain.ExportTask) error {
+	err := os.MkdirAll(svc.cfg.Dir, 0o700)
+	if err != nil {
+		return err
+	}
+	path := svc.path(task.Uid, task.Id)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	zw := zip.NewWriter(f)
+	err = svc.writeEntries(ctx, zw, task.Uid)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (svc *exportService) writeEntries(ctx context.Context, zw *zip.Writer, uid int64) error {
+	u, err := svc.userRepo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	err = svc.writeJSON(zw, "profile.json", exportProfile{
+		Id:       u.Id,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Nickname: u.Nickname,
+		Birth:    u.Birth,
+		Bio:      u.Bio,
+		Roles:    u.Roles,
+		Ctime:    u.Ctime,
+	})
+	if err != nil {
+		return err
+	}
+
+	const batchSize = 100
+	var arts []exportArticle
+	for offset := 0; ; offset += batchSize {
+		batch, err := svc.articleRepo.ListByAuthor(ctx, uid, offset, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, art := range batch {
+			name := fmt.Sprintf("articles/%d.md", art.Id)
+			w, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "# %s\n\n%s\n", art.Title, art.Content)
+			if err != nil {
+				return err
+			}
+			arts = append(arts, exportArticle{
+				Id:     art.Id,
+				Title:  art.Title,
+				Status: art.Status,
+				File:   name,
+				Ctime:  art.CreateTime,
+				Utime:  art.UpdateTime,
+			})
+		}
+		if len(batch) < batchSize {
+			break
+		}
+	}
+	err = svc.writeJSON(zw, "articles.json", arts)
+	if err != nil {
+		return err
+	}
+
+	var logs []domain.LoginLog
+	for offset := 0; ; offset += batchSize {
+		batch, err := svc.loginLogRepo.FindByUid(ctx, uid, offset, batchSize)
+		if err != nil {
+			return err
+		}
+		logs = append(logs, batch...)
+		if len(batch) < batchSize {
+			break
+		}
+	}
+	return svc.writeJSON(zw, "login_history.json", logs)
+}
+
+func (svc *exportService) writeJSON(zw *zip.Writer, name string, val any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(val)
+}
+
+func (svc *exportService) link(task domain.ExportTask) string {
+	expires := task.ExpireAt.UnixMilli()
+	q := url.Values{}
+	q.Set("uid", strconv.FormatInt(task.Uid, 10))
+	q.Set("task", task.Id)
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("sig", svc.sign(task.Uid, task.Id, expires))
+	return svc.cfg.BaseURL + "/users/export/download?" + q.Encode()
+}
+
+func (svc *exportService) sign(uid int64, taskId string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(svc.cfg.Secret))
+	mac.Write([]byte(fmt.Sprintf("%d:%s:%d", uid, taskId, expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (svc *exportService) path(uid int64, taskId string) string {
+	return filepath.Join(svc.cfg.Dir, fmt.Sprintf("%d-%s.zip", uid, taskId))
+}
+
+// exportProfile 不导出密码哈希
+type exportProfile struct {
+	Id       int64     `json:"id"`
+	Email    string    `json:"email"`
+	Phone    string    `json:"phone"`
+	Nickname string    `json:"nickname"`
+	Birth    string    `json:"birth"`
+	Bio      string    `json:"bio"`
+	Roles    []string  `json:"roles"`
+	Ctime    time.Time `json:"ctime"`
+}
+
+type exportArticle struct {
+	Id     int64                `json:"id"`
+	Title  string               `json:"title"`
+	Status domain.ArticleStatus `json:"status"`
+	// File 正文在压缩包里面的路径
+	File  string    `json:"file"`
+	Ctime time.Time `json:"ctime"`
+	Utime time.Time `json:"utime"`
+}
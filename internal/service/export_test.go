@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// sentMail 记下发出去的邮件
+type sentMail struct {
+	to      string
+	subject string
+	body    string
+}
+
+type recordMailer struct {
+	mails []sentMail
+}
+
+func (m *recordMailer) Send(ctx context.Context, to string, subject string, body string) error {
+	m.mails = append(m.mails, sentMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+func TestEmailExportNotifier_Notify(t *testing.T) {
+	expireAt := time.Date(2026, 10, 20, 8, 0, 0, 0, time.Local)
+	const link = "https://webook.com/users/export/download?sig=abc"
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantErr     error
+		wantMails   int
+		wantTo      string
+		wantSubject string
+	}{
+		{
+			name: "按用户的语言发邮件",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "a@qq.com", Locale: "en-US"}, nil)
+				return repo
+			},
+			wantMails:   1,
+			wantTo:      "a@qq.com",
+			wantSubject: "Your webook data export is ready",
+		},
+		{
+			name: "没有设置语言用中文",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "a@qq.com"}, nil)
+				return repo
+			},
+			wantMails:   1,
+			wantTo:      "a@qq.com",
+			wantSubject: "webook 个人数据导出完成",
+		},
+		{
+			name: "没有绑定邮箱",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Phone: "+8613800000000"}, nil)
+				return repo
+			},
+		},
+		{
+			name: "查询用户失败",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{}, errors.New("db 错误"))
+				return repo
+			},
+			wantErr: errors.New("db 错误"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mailer := &recordMailer{}
+			err := NewEmailExportNotifier(mailer, tc.mock(ctrl)).
+				Notify(context.Background(), 123, link, expireAt)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Len(t, mailer.mails, tc.wantMails)
+			if tc.wantMails == 0 {
+				return
+			}
+			mail := mailer.mails[0]
+			assert.Equal(t, tc.wantTo, mail.to)
+			assert.Equal(t, tc.wantSubject, mail.subject)
+			assert.Contains(t, mail.body, link)
+			assert.Contains(t, mail.body, "2026-10-20 08:00:00")
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"time"
+)
+
+// IPLocator 把 IP 解析成国家或地区代码，解析不出来返回空字符串
+type IPLocator interface {
+	Country(ctx context.Context, ip string) string
+}
+
+// NopIPLocator 没有配置 IP 库的时候用，解析不出地区，可疑登录只按设备判断
+type NopIPLocator struct {
+}
+
+func NewNopIPLocator() IPLocator {
+	return &NopIPLocator{}
+}
+
+func (l *NopIPLocator) Country(ctx context.Context, ip string) string {
+	return ""
+}
+
+type LoginLogService interface {
+	// Record 记录一次登录尝试，成功登录的设备或者地区以前没有出现过就标记为可疑
+	Record(ctx context.Context, l domain.LoginLog) error
+	List(ctx context.Context, uid int64, offset int, limit int) ([]domain.LoginLog, error)
+}
+
+type loginLogService struct {
+	repo    repository.LoginLogRepository
+	locator IPLocator
+	emitter SecurityEventEmitter
+}
+
+func NewLoginLogService(repo repository.LoginLogRepository, locator IPLocator,
+	emitter SecurityEventEmitter) LoginLogService {
+	return &loginLogService{
+		repo:    repo,
+		locator: locator,
+		emitter: emitter,
+	}
+}
+
+func (svc *loginLogService) Record(ctx context.Context, l domain.LoginLog) error {
+	l.Device = svc.fingerprint(l.UserAgent)
+	l.Country = svc.locator.Country(ctx, l.IP)
+	if l.Uid != 0 && l.Outcome == domain.LoginOutcomeSuccess {
+		suspicious, err := svc.isSuspicious(ctx, l)
+		if err != nil {
+			return err
+		}
+		l.Suspicious = suspicious
+	}
+	err := svc.repo.Create(ctx, l)
+	if err != nil {
+		return err
+	}
+	if l.Suspicious {
+		svc.emitter.Emit(ctx, domain.SecurityEvent{
+			Type:   domain.SecurityEventSuspicious,
+			Uid:    l.Uid,
+			IP:     l.IP,
+			Detail: fmt.Sprintf("%s 登录，设备 %s，地区 %s", l.Method, l.UserAgent, l.Country),
+			Time:   time.Now(),
+		})
+	}
+	return nil
+}
+
+// isSuspicious 设备和地区只要有一个是新的就算可疑。
+// 解析不出地区的时候只看设备；第一次登录没有历史可以比较，不算可疑
+func (svc *loginLogService) isSuspicious(ctx context.Context, l domain.LoginLog) (bool, error) {
+	total, sameDevice, sameCountry, err := svc.repo.CountSuccess(ctx, l.Uid, l.Device, l.Country)
+	if err != nil {
+		return false, err
+	}
+	if total == 0 {
+		return false, nil
+	}
+	newDevice := sameDevice == 0
+	newCountry := l.Country != "" && sameCountry == 0
+	return newDevice || newCountry, nil
+}
+
+func (svc *loginLogService) List(ctx context.Context, uid int64, offset int, limit int) ([]domain.LoginLog, error) {
+	return svc.repo.FindByUid(ctx, uid, offset, limit)
+}
+
+func (svc *loginLogService) fingerprint(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	"github.com/skcheng003/webook/pkg/geoip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"strings"
+	"testing"
+)
+
+type recordEmitter struct {
+	events []domain.SecurityEvent
+}
+
+func (e *recordEmitter) Emit(ctx context.Context, evt domain.SecurityEvent) {
+	e.events = append(e.events, evt)
+}
+
+func TestLoginLogService_Record(t *testing.T) {
+	locator, err := geoip.NewCSVLocator(strings.NewReader("1.0.0.0,1.0.0.255,AU\n1.0.1.0,1.0.3.255,CN\n"))
+	require.NoError(t, err)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.LoginLogRepository
+		ip   string
+
+		wantSuspicious bool
+	}{
+		{
+			name: "第一次登录",
+			mock: func(ctrl *gomock.Controller) repository.LoginLogRepository {
+				repo := repomocks.NewMockLoginLogRepository(ctrl)
+				repo.EXPECT().CountSuccess(gomock.Any(), int64(123), gomock.Any(), "CN").
+					Return(int64(0), int64(0), int64(0), nil)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			ip: "1.0.1.1",
+		},
+		{
+			name: "熟悉的设备和地区",
+			mock: func(ctrl *gomock.Controller) repository.LoginLogRepository {
+				repo := repomocks.NewMockLoginLogRepository(ctrl)
+				repo.EXPECT().CountSuccess(gomock.Any(), int64(123), gomock.Any(), "CN").
+					Return(int64(5), int64(5), int64(5), nil)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			ip: "1.0.1.1",
+		},
+		{
+			name: "熟悉的设备，新的地区",
+			mock: func(ctrl *gomock.Controller) repository.LoginLogRepository {
+				repo := repomocks.NewMockLoginLogRepository(ctrl)
+				repo.EXPECT().CountSuccess(gomock.Any(), int64(123), gomock.Any(), "AU").
+					Return(int64(5), int64(5), int64(0), nil)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			ip:             "1.0.0.1",
+			wantSuspicious: true,
+		},
+		{
+			name: "新的设备，熟悉的地区",
+			mock: func(ctrl *gomock.Controller) repository.LoginLogRepository {
+				repo := repomocks.NewMockLoginLogRepository(ctrl)
+				repo.EXPECT().CountSuccess(gomock.Any(), int64(123), gomock.Any(), "CN").
+					Return(int64(5), int64(0), int64(5), nil)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			ip:             "1.0.1.1",
+			wantSuspicious: true,
+		},
+		{
+			name: "解析不出地区只看设备",
+			mock: func(ctrl *gomock.Controller) repository.LoginLogRepository {
+				repo := repomocks.NewMockLoginLogRepository(ctrl)
+				repo.EXPECT().CountSuccess(gomock.Any(), int64(123), gomock.Any(), "").
+					Return(int64(5), int64(5), int64(0), nil)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			ip: "10.0.0.1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			emitter := &recordEmitter{}
+			svc := NewLoginLogService(tc.mock(ctrl), locator, emitter)
+			err := svc.Record(context.Background(), domain.LoginLog{
+				Uid:       123,
+				IP:        tc.ip,
+				UserAgent: "Mozilla/5.0",
+				Method:    domain.LoginMethodPassword,
+				Outcome:   domain.LoginOutcomeSuccess,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSuspicious, len(emitter.events) == 1)
+		})
+	}
+}
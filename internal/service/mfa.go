@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/pkg/totp"
+	"strings"
+	"time"
+)
+
+const (
+	totpIssuer        = "webook"
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFANotEnrolled    = errors.New("未绑定身份验证器")
+	ErrMFAAlreadyEnabled = errors.New("已经开启二次验证")
+	ErrMFAInvalidCode    = errors.New("二次验证码错误")
+)
+
+var _ MFAService = (*TOTPService)(nil)
+
+type MFAService interface {
+	// Enroll 生成新的密钥，返回密钥和用于生成二维码的 URI，此时还没有开启二次验证
+	Enroll(ctx context.Context, uid int64) (string, string, error)
+	// Enable 用身份验证器上的验证码确认绑定，返回只展示一次的恢复码
+	Enable(ctx context.Context, uid int64, code string) ([]string, error)
+	Disable(ctx context.Context, uid int64, code string) error
+	IsEnabled(ctx context.Context, uid int64) (bool, error)
+	// Verify 校验验证码，code 也可以是恢复码。
+	// 验证码和恢复码都只能用一次，同一个时间窗口的验证码重复提交会失败
+	Verify(ctx context.Context, uid int64, code string) (bool, error)
+}
+
+type TOTPService struct {
+	repo     repository.MFARepository
+	userRepo repository.UserRepository
+}
+
+func NewTOTPService(repo repository.MFARepository, userRepo repository.UserRepository) MFAService {
+	return &TOTPService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (svc *TOTPService) Enroll(ctx context.Context, uid int64) (string, string, error) {
+	t, err := svc.repo.FindTOTP(ctx, uid)
+	if err != nil && !errors.Is(err, repository.ErrMFANoFound) {
+		return "", "", err
+	}
+	if t.Enabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+	u, err := svc.userRepo.FindByUid(ctx, uid)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	err = svc.repo.SaveTOTP(ctx, domain.TOTP{
+		Uid:    uid,
+		Secret: secret,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	account := u.Email
+	if account == "" {
+		account = u.Phone
+	}
+	return secret, totp.URI(totpIssuer, account, secret), nil
+}
+
+func (svc *TOTPService) Enable(ctx context.Context, uid int64, code string) ([]string, error) {
+	t, err := svc.repo.FindTOTP(ctx, uid)
+	if errors.Is(err, repository.ErrMFANoFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok := totp.ValidateStep(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrMFAInvalidCode
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		c, err := svc.generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, svc.hashRecoveryCode(c))
+	}
+	err = svc.repo.EnableTOTP(ctx, uid, hashes)
+	if err != nil {
+		return nil, err
+	}
+	// 绑定时用过的验证码也不能再拿去登录
+	_, err = svc.repo.UseTOTPStep(ctx, uid, step)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (svc *TOTPService) Disable(ctx context.Context, uid int64, code string) error {
+	ok, err := svc.Verify(ctx, uid, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMFAInvalidCode
+	}
+	return svc.repo.DeleteTOTP(ctx, uid)
+}
+
+func (svc *TOTPService) IsEnabled(ctx context.Context, uid int64) (bool, error) {
+	t, err := svc.repo.FindTOTP(ctx, uid)
+	if errors.Is(err, repository.ErrMFANoFound) {
+		return false, nil
+	}
+	return t.Enabled, err
+}
+
+func (svc *TOTPService) Verify(ctx context.Context, uid int64, code string) (bool, error) {
+	t, err := svc.repo.FindTOTP(ctx, uid)
+	if errors.Is(err, repository.ErrMFANoFound) {
+		return false, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return false, err
+	}
+	if !t.Enabled {
+		return false, ErrMFANotEnrolled
+	}
+	if step, ok := totp.ValidateStep(t.Secret, code, time.Now(), totpSkew); ok {
+		// 条件更新保证并发提交同一个验证码也只有一个能成功
+		return svc.repo.UseTOTPStep(ctx, uid, step)
+	}
+	// 不是身份验证器上的验证码，再当作恢复码试一次
+	return svc.repo.UseRecoveryCode(ctx, uid, svc.hashRecoveryCode(code))
+}
+
+// generateRecoveryCode 恢复码形如 abcde-fghij
+func (svc *TOTPService) generateRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.EncodeToString(buf))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+func (svc *TOTPService) hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
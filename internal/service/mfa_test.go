@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	"github.com/skcheng003/webook/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestTOTPService_Verify(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.MFARepository
+		code string
+
+		wantOk bool
+	}{
+		{
+			name: "验证码正确",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), int64(123)).
+					Return(domain.TOTP{Uid: 123, Secret: secret, Enabled: true}, nil)
+				repo.EXPECT().UseTOTPStep(gomock.Any(), int64(123), gomock.Any()).Return(true, nil)
+				return repo
+			},
+			code:   code,
+			wantOk: true,
+		},
+		{
+			name: "同一个验证码重放",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), int64(123)).
+					Return(domain.TOTP{Uid: 123, Secret: secret, Enabled: true}, nil)
+				// 这个时间窗口已经用过了
+				repo.EXPECT().UseTOTPStep(gomock.Any(), int64(123), gomock.Any()).Return(false, nil)
+				return repo
+			},
+			code:   code,
+			wantOk: false,
+		},
+		{
+			name: "恢复码",
+			mock: func(ctrl *gomock.Controller) repository.MFARepository {
+				repo := repomocks.NewMockMFARepository(ctrl)
+				repo.EXPECT().FindTOTP(gomock.Any(), int64(123)).
+					Return(domain.TOTP{Uid: 123, Secret: secret, Enabled: true}, nil)
+				repo.EXPECT().UseRecoveryCode(gomock.Any(), int64(123), gomock.Any()).Return(true, nil)
+				return repo
+			},
+			code:   "abcde-fghij",
+			wantOk: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewTOTPService(tc.mock(ctrl), nil)
+			ok, err := svc.Verify(context.Background(), 123, tc.code)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantOk, ok)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/mfa.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/mfa.go -package=svcmocks -destination=internal/service/mocks/mfa.mock.gen.go
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMFAService is a mock of MFAService interface.
+type MockMFAService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMFAServiceMockRecorder
+}
+
+// MockMFAServiceMockRecorder is the mock recorder for MockMFAService.
+type MockMFAServiceMockRecorder struct {
+	mock *MockMFAService
+}
+
+// NewMockMFAService creates a new mock instance.
+func NewMockMFAService(ctrl *gomock.Controller) *MockMFAService {
+	mock := &MockMFAService{ctrl: ctrl}
+	mock.recorder = &MockMFAServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMFAService) EXPECT() *MockMFAServiceMockRecorder {
+	return m.recorder
+}
+
+// Disable mocks base method.
+func (m *MockMFAService) Disable(ctx context.Context, uid int64, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, uid, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockMFAServiceMockRecorder) Disable(ctx, uid, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockMFAService)(nil).Disable), ctx, uid, code)
+}
+
+// Enable mocks base method.
+func (m *MockMFAService) Enable(ctx context.Context, uid int64, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, uid, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockMFAServiceMockRecorder) Enable(ctx, uid, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockMFAService)(nil).Enable), ctx, uid, code)
+}
+
+// Enroll mocks base method.
+func (m *MockMFAService) Enroll(ctx context.Context, uid int64) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enroll", ctx, uid)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Enroll indicates an expected call of Enroll.
+func (mr *MockMFAServiceMockRecorder) Enroll(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enroll", reflect.TypeOf((*MockMFAService)(nil).Enroll), ctx, uid)
+}
+
+// IsEnabled mocks base method.
+func (m *MockMFAService) IsEnabled(ctx context.Context, uid int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsEnabled", ctx, uid)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsEnabled indicates an expected call of IsEnabled.
+func (mr *MockMFAServiceMockRecorder) IsEnabled(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsEnabled", reflect.TypeOf((*MockMFAService)(nil).IsEnabled), ctx, uid)
+}
+
+// Verify mocks base method.
+func (m *MockMFAService) Verify(ctx context.Context, uid int64, code string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, uid, code)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockMFAServiceMockRecorder) Verify(ctx, uid, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockMFAService)(nil).Verify), ctx, uid, code)
+}
@@ -40,6 +40,48 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 	return m.recorder
 }
 
+// BindEmail mocks base method.
+func (m *MockUserService) BindEmail(ctx context.Context, uid int64, email string, cred domain.Credential) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindEmail", ctx, uid, email, cred)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindEmail indicates an expected call of BindEmail.
+func (mr *MockUserServiceMockRecorder) BindEmail(ctx, uid, email, cred any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindEmail", reflect.TypeOf((*MockUserService)(nil).BindEmail), ctx, uid, email, cred)
+}
+
+// BindPhone mocks base method.
+func (m *MockUserService) BindPhone(ctx context.Context, uid int64, phone string, cred domain.Credential) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindPhone", ctx, uid, phone, cred)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindPhone indicates an expected call of BindPhone.
+func (mr *MockUserServiceMockRecorder) BindPhone(ctx, uid, phone, cred any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindPhone", reflect.TypeOf((*MockUserService)(nil).BindPhone), ctx, uid, phone, cred)
+}
+
+// Disable mocks base method.
+func (m *MockUserService) Disable(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockUserServiceMockRecorder) Disable(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockUserService)(nil).Disable), ctx, uid)
+}
+
 // EditProfile mocks base method.
 func (m *MockUserService) EditProfile(ctx context.Context, user domain.User) error {
 	m.ctrl.T.Helper()
@@ -54,6 +96,20 @@ func (mr *MockUserServiceMockRecorder) EditProfile(ctx, user any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditProfile", reflect.TypeOf((*MockUserService)(nil).EditProfile), ctx, user)
 }
 
+// Enable mocks base method.
+func (m *MockUserService) Enable(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockUserServiceMockRecorder) Enable(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockUserService)(nil).Enable), ctx, uid)
+}
+
 // FindOrCreate mocks base method.
 func (m *MockUserService) FindOrCreate(ctx context.Context, phone string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -100,18 +156,75 @@ func (mr *MockUserServiceMockRecorder) FindProfileJWT(ctx, uid any) *gomock.Call
 }
 
 // Login mocks base method.
-func (m *MockUserService) Login(ctx context.Context, email, password string) (domain.User, error) {
+func (m *MockUserService) Login(ctx context.Context, email, password, ip string) (domain.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Login", ctx, email, password)
+	ret := m.ctrl.Call(m, "Login", ctx, email, password, ip)
 	ret0, _ := ret[0].(domain.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Login indicates an expected call of Login.
-func (mr *MockUserServiceMockRecorder) Login(ctx, email, password any) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) Login(ctx, email, password, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserService)(nil).Login), ctx, email, password, ip)
+}
+
+// LoginMFA mocks base method.
+func (m *MockUserService) LoginMFA(ctx context.Context, uid int64, code, ip string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginMFA", ctx, uid, code, ip)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginMFA indicates an expected call of LoginMFA.
+func (mr *MockUserServiceMockRecorder) LoginMFA(ctx, uid, code, ip any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserService)(nil).Login), ctx, email, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginMFA", reflect.TypeOf((*MockUserService)(nil).LoginMFA), ctx, uid, code, ip)
+}
+
+// Reauthenticate mocks base method.
+func (m *MockUserService) Reauthenticate(ctx context.Context, uid int64, cred domain.Credential) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reauthenticate", ctx, uid, cred)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reauthenticate indicates an expected call of Reauthenticate.
+func (mr *MockUserServiceMockRecorder) Reauthenticate(ctx, uid, cred any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reauthenticate", reflect.TypeOf((*MockUserService)(nil).Reauthenticate), ctx, uid, cred)
+}
+
+// SendRebindCode mocks base method.
+func (m *MockUserService) SendRebindCode(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendRebindCode", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendRebindCode indicates an expected call of SendRebindCode.
+func (mr *MockUserServiceMockRecorder) SendRebindCode(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendRebindCode", reflect.TypeOf((*MockUserService)(nil).SendRebindCode), ctx, uid)
+}
+
+// SendUnlockCode mocks base method.
+func (m *MockUserService) SendUnlockCode(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendUnlockCode", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendUnlockCode indicates an expected call of SendUnlockCode.
+func (mr *MockUserServiceMockRecorder) SendUnlockCode(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendUnlockCode", reflect.TypeOf((*MockUserService)(nil).SendUnlockCode), ctx, email)
 }
 
 // SignUp mocks base method.
@@ -127,3 +240,31 @@ func (mr *MockUserServiceMockRecorder) SignUp(ctx, u any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockUserService)(nil).SignUp), ctx, u)
 }
+
+// UnlockAccount mocks base method.
+func (m *MockUserService) UnlockAccount(ctx context.Context, email, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockAccount", ctx, email, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockAccount indicates an expected call of UnlockAccount.
+func (mr *MockUserServiceMockRecorder) UnlockAccount(ctx, email, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockAccount", reflect.TypeOf((*MockUserService)(nil).UnlockAccount), ctx, email, code)
+}
+
+// UpdateRoles mocks base method.
+func (m *MockUserService) UpdateRoles(ctx context.Context, uid int64, roles []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRoles", ctx, uid, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRoles indicates an expected call of UpdateRoles.
+func (mr *MockUserServiceMockRecorder) UpdateRoles(ctx, uid, roles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRoles", reflect.TypeOf((*MockUserService)(nil).UpdateRoles), ctx, uid, roles)
+}
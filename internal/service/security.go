@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"go.uber.org/zap"
+)
+
+// SecurityEventEmitter 发出安全事件，后续可以换成消息队列，接入告警系统
+type SecurityEventEmitter interface {
+	Emit(ctx context.Context, evt domain.SecurityEvent)
+}
+
+// LogSecurityEventEmitter 打到日志里面，由日志告警规则来匹配 security_event
+type LogSecurityEventEmitter struct {
+}
+
+func NewLogSecurityEventEmitter() SecurityEventEmitter {
+	return &LogSecurityEventEmitter{}
+}
+
+func (e *LogSecurityEventEmitter) Emit(ctx context.Context, evt domain.SecurityEvent) {
+	zap.L().Warn("security_event",
+		zap.String("type", string(evt.Type)),
+		zap.Int64("uid", evt.Uid),
+		zap.String("ip", evt.IP),
+		zap.String("detail", evt.Detail),
+		zap.Time("time", evt.Time))
+}
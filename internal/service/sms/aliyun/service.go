@@ -0,0 +1,198 @@
+package aliyun
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultEndpoint = "https://dysmsapi.aliyuncs.com"
+
+type Config struct {
+	// Endpoint 不配置就用公网的地址，测试的时候换成本地的
+	Endpoint        string
+	AccessKeyId     string
+	AccessKeySecret string
+	SignName        string
+	// ParamNames 阿里云的模板参数是有名字的，按顺序把 args 对应到这些名字上，
+	// key 是模板 id
+	ParamNames map[string][]string
+}
+
+// Service 直接调用阿里云短信的 RPC 接口，没有引入 SDK，
+// 签名算法见 https://help.aliyun.com/document_detail/315526.html
+type Service struct {
+	client *http.Client
+	cfg    Config
+}
+
+func NewService(client *http.Client, cfg Config) *Service {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = defaultEndpoint
+	}
+	return &Service{
+		client: client,
+		cfg:    cfg,
+	}
+}
+
+type response struct {
+	Code      string
+	Message   string
+	BizId     string
+	RequestId string
+}
+
+func (s *Service) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	_, err := s.SendWithResult(ctx, tplId, args, numbers...)
+	return err
+}
+
+// SendWithResult 阿里云一次请求只有一个 BizId，同一批号码的流水号是一样的
+func (s *Service) SendWithResult(ctx context.Context, tplId string,
+	args []string, numbers ...string) ([]sms.SendResult, error) {
+	tplParam, err := s.templateParam(tplId, args)
+	if err != nil {
+		return nil, err
+	}
+	phones := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		// 阿里云的国际号码不要 +，国内号码带 86 也可以
+		phones = append(phones, strings.TrimPrefix(n, "+"))
+	}
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("Action", "SendSms")
+	params.Set("Version", "2017-05-25")
+	params.Set("Format", "JSON")
+	params.Set("RegionId", "cn-hangzhou")
+	params.Set("AccessKeyId", s.cfg.AccessKeyId)
+	params.Set("SignatureMethod", "HMAC-SHA1")
+	params.Set("SignatureVersion", "1.0")
+	params.Set("SignatureNonce", nonce)
+	params.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	params.Set("PhoneNumbers", strings.Join(phones, ","))
+	params.Set("SignName", s.cfg.SignName)
+	params.Set("TemplateCode", tplId)
+	params.Set("TemplateParam", tplParam)
+	params.Set("Signature", Sign(http.MethodGet, params, s.cfg.AccessKeySecret))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		s.cfg.Endpoint+"/?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var res response
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("阿里云短信响应解析失败，HTTP 状态码 %d: %w", resp.StatusCode, err)
+	}
+	if res.Code != "OK" {
+		return nil, &sms.ResponseError{StatusCode: resp.StatusCode, Code: res.Code, Msg: res.Message}
+	}
+	results := make([]sms.SendResult, 0, len(numbers))
+	for _, n := range numbers {
+		results = append(results, sms.SendResult{Number: n, SerialNo: res.BizId, Success: true})
+	}
+	return results, nil
+}
+
+// receipt 阿里云短信回执消息的一条记录，推送过来的是一个数组
+type receipt struct {
+	BizId   string `json:"biz_id"`
+	Success bool   `json:"success"`
+	ErrCode string `json:"err_code"`
+	ErrMsg  string `json:"err_msg"`
+}
+
+func (s *Service) ParseReceipts(body []byte) ([]sms.Receipt, error) {
+	var receipts []receipt
+	if err := json.Unmarshal(body, &receipts); err != nil {
+		return nil, err
+	}
+	res := make([]sms.Receipt, 0, len(receipts))
+	for _, r := range receipts {
+		rec := sms.Receipt{SerialNo: r.BizId, Delivered: r.Success}
+		if !r.Success {
+			rec.Reason = r.ErrCode + " " + r.ErrMsg
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+func (s *Service) ReceiptAck() any {
+	return map[string]any{"code": 0, "msg": "成功"}
+}
+
+func (s *Service) templateParam(tplId string, args []string) (string, error) {
+	if len(args) == 0 {
+		return "{}", nil
+	}
+	names, ok := s.cfg.ParamNames[tplId]
+	if !ok || len(names) != len(args) {
+		return "", fmt.Errorf("阿里云模板 %s 的参数名没有配置或者数量不对", tplId)
+	}
+	m := make(map[string]string, len(args))
+	for i, name := range names {
+		m[name] = args[i]
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+// Sign 计算 Signature，params 里面不能包含 Signature 本身
+func Sign(method string, params url.Values, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k == "Signature" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params.Get(k)))
+	}
+	str := method + "&" + percentEncode("/") + "&" + percentEncode(strings.Join(pairs, "&"))
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(str))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// percentEncode 阿里云要求的 RFC 3986 编码，和 url.QueryEscape 有几个字符不一样
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	s = strings.ReplaceAll(s, "%7E", "~")
+	return s
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,107 @@
+package aliyun
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler func(t *testing.T) http.HandlerFunc
+		tplId   string
+		args    []string
+
+		wantErr bool
+	}{
+		{
+			name: "发送成功",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					q := r.URL.Query()
+					assert.Equal(t, Sign(http.MethodGet, q, "secret"), q.Get("Signature"))
+					assert.Equal(t, "SendSms", q.Get("Action"))
+					assert.Equal(t, "8613800000000", q.Get("PhoneNumbers"))
+					assert.Equal(t, "webook", q.Get("SignName"))
+					assert.Equal(t, "SMS_1", q.Get("TemplateCode"))
+					assert.Equal(t, `{"code":"123456"}`, q.Get("TemplateParam"))
+					_, _ = w.Write([]byte(`{"Code":"OK","Message":"OK","BizId":"1"}`))
+				}
+			},
+			tplId: "SMS_1",
+			args:  []string{"123456"},
+		},
+		{
+			name: "服务商返回错误",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte(`{"Code":"isv.BUSINESS_LIMIT_CONTROL","Message":"触发流控"}`))
+				}
+			},
+			tplId:   "SMS_1",
+			args:    []string{"123456"},
+			wantErr: true,
+		},
+		{
+			name: "响应不是 JSON",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadGateway)
+				}
+			},
+			tplId:   "SMS_1",
+			args:    []string{"123456"},
+			wantErr: true,
+		},
+		{
+			name: "模板参数名没有配置",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					t.Error("不应该发请求")
+				}
+			},
+			tplId:   "SMS_2",
+			args:    []string{"123456"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler(t))
+			defer server.Close()
+			svc := NewService(server.Client(), Config{
+				Endpoint:        server.URL,
+				AccessKeyId:     "id",
+				AccessKeySecret: "secret",
+				SignName:        "webook",
+				ParamNames:      map[string][]string{"SMS_1": {"code"}},
+			})
+			err := svc.Send(context.Background(), tc.tplId, tc.args, "+8613800000000")
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestService_ParseReceipts(t *testing.T) {
+	svc := NewService(http.DefaultClient, Config{})
+	receipts, err := svc.ParseReceipts([]byte(`[
+		{"phone_number":"13800000000","success":true,"err_code":"DELIVERED","biz_id":"1"},
+		{"phone_number":"13800000001","success":false,"err_code":"MK:0001","err_msg":"空号","biz_id":"2"}
+	]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []sms.Receipt{
+		{SerialNo: "1", Delivered: true},
+		{SerialNo: "2", Reason: "MK:0001 空号"},
+	}, receipts)
+
+	_, err = svc.ParseReceipts([]byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestPercentEncode(t *testing.T) {
+	assert.Equal(t, "a%20b%2Ac~d%2F", percentEncode("a b*c~d/"))
+}
@@ -0,0 +1,203 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"github.com/skcheng003/webook/internal/service/sms/ratelimit"
+	"github.com/skcheng003/webook/internal/service/sms/template"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Config struct {
+	// WindowSize 根据最近多少次发送的结果判断服务商是否正常
+	WindowSize int
+	// MaxErrRate 错误率超过这个值就切换到异步
+	MaxErrRate float64
+	// MaxLatency 平均响应时间超过这个值也切换到异步
+	MaxLatency time.Duration
+	// RetryMax 异步最多重试几次
+	RetryMax int
+	// BaseBackoff 第一次重试的间隔，之后每次翻倍，最多 MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// SyncOnly 以这些前缀开头的模板只同步发送，失败了直接返回给调用方。
+	// 验证码过一会就过期了，重发没有意义，也不能把明文验证码存到数据库里面
+	SyncOnly []string
+}
+
+// Service 同步发送失败或者被限流的短信存到数据库里面，由 job.AsyncSMSJob 异步重试。
+// 服务商的错误率或者响应时间超过阈值之后，所有短信都直接走异步，
+// 等重试的结果恢复正常再切回同步
+type Service struct {
+	svc   sms.Service
+	repo  repository.AsyncSMSRepository
+	cfg   Config
+	async atomic.Bool
+	stats *window
+}
+
+func NewService(svc sms.Service, repo repository.AsyncSMSRepository, cfg Config) *Service {
+	return &Service{
+		svc:   svc,
+		repo:  repo,
+		cfg:   cfg,
+		stats: newWindow(cfg.WindowSize),
+	}
+}
+
+func (s *Service) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	if s.syncOnly(tplId) {
+		return s.send(ctx, tplId, args, numbers)
+	}
+	if s.async.Load() {
+		return s.store(ctx, tplId, args, numbers)
+	}
+	err := s.send(ctx, tplId, args, numbers)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, template.ErrTemplateNotFound) ||
+		errors.Is(err, template.ErrInvalidArgs) {
+		// 调用方自己放弃了，或者是配置问题，重试也没用
+		return err
+	}
+	zap.L().Warn("同步发送短信失败，转为异步重试", zap.Error(err))
+	if serr := s.store(ctx, tplId, args, failedNumbers(err, numbers)); serr != nil {
+		zap.L().Error("保存异步短信失败", zap.Error(serr))
+		return err
+	}
+	return nil
+}
+
+// RetryOnce 抢占一条到期的短信重试一次，没有可以重试的短信返回 false
+func (s *Service) RetryOnce(ctx context.Context) (bool, error) {
+	msg, err := s.repo.PreemptWaitingSMS(ctx)
+	if errors.Is(err, repository.ErrWaitingSMSNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	err = s.send(ctx, msg.TplId, msg.Args, msg.Numbers)
+	if err == nil {
+		return true, s.repo.Delete(ctx, msg.Id)
+	}
+	if msg.RetryCnt+1 >= msg.RetryMax {
+		zap.L().Error("异步短信重试次数用完，放弃发送", zap.Int64("id", msg.Id),
+			zap.String("tplId", msg.TplId), zap.Error(err))
+		return true, s.repo.Delete(ctx, msg.Id)
+	}
+	zap.L().Warn("异步重试短信失败", zap.Int64("id", msg.Id),
+		zap.Int("retryCnt", msg.RetryCnt), zap.Error(err))
+	// 已经受理的号码不再重发
+	msg.Numbers = failedNumbers(err, msg.Numbers)
+	return true, s.repo.MarkFailed(ctx, msg, time.Now().Add(s.backoff(msg.RetryCnt)))
+}
+
+// send 调用服务商，并且记录结果用来判断要不要切换模式
+func (s *Service) send(ctx context.Context, tplId string, args []string, numbers []string) error {
+	start := time.Now()
+	err := s.svc.Send(ctx, tplId, args, numbers...)
+	if errors.Is(err, ratelimit.ErrLimited) {
+		// 被我们自己限流，请求都没有到服务商
+		return err
+	}
+	errRate, latency, ok := s.stats.add(sms.IsProviderFailure(err), time.Since(start))
+	if ok {
+		async := errRate > s.cfg.MaxErrRate || latency > s.cfg.MaxLatency
+		if s.async.Swap(async) != async {
+			zap.L().Info("短信发送模式切换", zap.Bool("async", async),
+				zap.Float64("errRate", errRate), zap.Duration("latency", latency))
+		}
+	}
+	return err
+}
+
+func (s *Service) store(ctx context.Context, tplId string, args []string, numbers []string) error {
+	return s.repo.Add(ctx, domain.AsyncSMS{
+		TplId:    tplId,
+		Args:     args,
+		Numbers:  numbers,
+		RetryMax: s.cfg.RetryMax,
+	})
+}
+
+func (s *Service) syncOnly(tplId string) bool {
+	for _, prefix := range s.cfg.SyncOnly {
+		if strings.HasPrefix(tplId, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// failedNumbers 部分号码没有受理的时候只返回这些号码，其它错误返回全部
+func failedNumbers(err error, numbers []string) []string {
+	var rejected *sms.RejectedError
+	if errors.As(err, &rejected) {
+		return rejected.Numbers
+	}
+	return numbers
+}
+
+func (s *Service) backoff(retryCnt int) time.Duration {
+	d := s.cfg.BaseBackoff
+	for i := 0; i < retryCnt && d < s.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.cfg.MaxBackoff {
+		d = s.cfg.MaxBackoff
+	}
+	return d
+}
+
+// window 最近 size 次发送结果的环形缓冲区
+type window struct {
+	mu      sync.Mutex
+	failed  []bool
+	latency []time.Duration
+	idx     int
+	full    bool
+}
+
+func newWindow(size int) *window {
+	return &window{
+		failed:  make([]bool, size),
+		latency: make([]time.Duration, size),
+	}
+}
+
+// add 记录一次结果，样本还不够的时候 ok 是 false
+func (w *window) add(failed bool, latency time.Duration) (float64, time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failed[w.idx] = failed
+	w.latency[w.idx] = latency
+	w.idx++
+	if w.idx == len(w.failed) {
+		w.idx = 0
+		w.full = true
+	}
+	if !w.full {
+		return 0, 0, false
+	}
+	var cnt int
+	var total time.Duration
+	for i := range w.failed {
+		if w.failed[i] {
+			cnt++
+		}
+		total += w.latency[i]
+	}
+	size := len(w.failed)
+	return float64(cnt) / float64(size), total / time.Duration(size), true
+}
@@ -0,0 +1,304 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	"github.com/skcheng003/webook/internal/service/sms"
+	smsmocks "github.com/skcheng003/webook/internal/service/sms/mocks"
+	"github.com/skcheng003/webook/internal/service/sms/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+var testCfg = Config{
+	WindowSize:  2,
+	MaxErrRate:  0.4,
+	MaxLatency:  time.Second,
+	RetryMax:    3,
+	BaseBackoff: time.Second * 10,
+	MaxBackoff:  time.Minute,
+	SyncOnly:    []string{"user/"},
+}
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository)
+		async bool
+		// history 之前发送的结果，用来填满统计窗口
+		history []bool
+		tplId   string
+		numbers []string
+
+		wantErr   error
+		wantAsync bool
+	}{
+		{
+			name: "同步发送成功",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "1110", []string{"123456"}, "+8613800000000").Return(nil)
+				return svc, repomocks.NewMockAsyncSMSRepository(ctrl)
+			},
+		},
+		{
+			name: "被限流转异步",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(ratelimit.ErrLimited)
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), domain.AsyncSMS{
+					TplId:    "1110",
+					Args:     []string{"123456"},
+					Numbers:  []string{"+8613800000000"},
+					RetryMax: 3,
+				}).Return(nil)
+				return svc, repo
+			},
+		},
+		{
+			name: "保存失败返回原来的错误",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(ratelimit.ErrLimited)
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(errors.New("db 错误"))
+				return svc, repo
+			},
+			wantErr: ratelimit.ErrLimited,
+		},
+		{
+			name: "错误率太高切换到异步",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("服务商错误"))
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return svc, repo
+			},
+			history:   []bool{false},
+			wantAsync: true,
+		},
+		{
+			name: "被限流不算服务商出错",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(ratelimit.ErrLimited)
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return svc, repo
+			},
+			// 再记一次失败就超过错误率了
+			history: []bool{true},
+		},
+		{
+			name: "只重试没有受理的号码",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "1110", []string{"123456"}, "+8613800000000", "+8613800000001").
+					Return(&sms.RejectedError{Numbers: []string{"+8613800000001"}, Reason: "号码格式错误"})
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), domain.AsyncSMS{
+					TplId:    "1110",
+					Args:     []string{"123456"},
+					Numbers:  []string{"+8613800000001"},
+					RetryMax: 3,
+				}).Return(nil)
+				return svc, repo
+			},
+			// 号码被拒绝说明服务商是正常的，算成功的样本
+			history: []bool{false},
+			numbers: []string{"+8613800000000", "+8613800000001"},
+		},
+		{
+			name: "验证码不转异步",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "user/login#zh-CN", gomock.Any(), gomock.Any()).
+					Return(ratelimit.ErrLimited)
+				return svc, repomocks.NewMockAsyncSMSRepository(ctrl)
+			},
+			tplId:   "user/login#zh-CN",
+			wantErr: ratelimit.ErrLimited,
+		},
+		{
+			name: "异步模式下验证码也同步发送",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "user/login#zh-CN", gomock.Any(), gomock.Any()).Return(nil)
+				return svc, repomocks.NewMockAsyncSMSRepository(ctrl)
+			},
+			async:     true,
+			tplId:     "user/login#zh-CN",
+			wantAsync: true,
+		},
+		{
+			name: "异步模式不调用服务商",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return smsmocks.NewMockService(ctrl), repo
+			},
+			async:     true,
+			wantAsync: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, repo := tc.mock(ctrl)
+			s := NewService(svc, repo, testCfg)
+			s.async.Store(tc.async)
+			for _, failed := range tc.history {
+				s.stats.add(failed, time.Millisecond)
+			}
+			tplId, numbers := tc.tplId, tc.numbers
+			if tplId == "" {
+				tplId = "1110"
+			}
+			if numbers == nil {
+				numbers = []string{"+8613800000000"}
+			}
+			err := s.Send(context.Background(), tplId, []string{"123456"}, numbers...)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantAsync, s.async.Load())
+		})
+	}
+}
+
+func TestService_RetryOnce(t *testing.T) {
+	msg := domain.AsyncSMS{
+		Id:       1,
+		TplId:    "1110",
+		Args:     []string{"123456"},
+		Numbers:  []string{"+8613800000000"},
+		RetryCnt: 1,
+		RetryMax: 3,
+	}
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository)
+		async   bool
+		history []bool
+
+		wantOk    bool
+		wantErr   error
+		wantAsync bool
+	}{
+		{
+			name: "没有要重试的短信",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().PreemptWaitingSMS(gomock.Any()).
+					Return(domain.AsyncSMS{}, repository.ErrWaitingSMSNotFound)
+				return smsmocks.NewMockService(ctrl), repo
+			},
+		},
+		{
+			name: "重试成功，服务商恢复切回同步",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "1110", []string{"123456"}, "+8613800000000").Return(nil)
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().PreemptWaitingSMS(gomock.Any()).Return(msg, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+				return svc, repo
+			},
+			async:   true,
+			history: []bool{false},
+			wantOk:  true,
+		},
+		{
+			name: "重试失败，按照次数退避",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("服务商错误"))
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().PreemptWaitingSMS(gomock.Any()).Return(msg, nil)
+				repo.EXPECT().MarkFailed(gomock.Any(), msg, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, s domain.AsyncSMS, nextTime time.Time) error {
+						// 第二次重试，间隔 20s
+						assert.WithinDuration(t, time.Now().Add(time.Second*20), nextTime, time.Second)
+						return nil
+					})
+				return svc, repo
+			},
+			async:     true,
+			history:   []bool{true},
+			wantOk:    true,
+			wantAsync: true,
+		},
+		{
+			name: "部分号码受理，剩下的稍后重试",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				batch := msg
+				batch.Numbers = []string{"+8613800000000", "+8613800000001"}
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "1110", []string{"123456"}, "+8613800000000", "+8613800000001").
+					Return(&sms.RejectedError{Numbers: []string{"+8613800000001"}, Reason: "号码格式错误"})
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().PreemptWaitingSMS(gomock.Any()).Return(batch, nil)
+				remain := msg
+				remain.Numbers = []string{"+8613800000001"}
+				repo.EXPECT().MarkFailed(gomock.Any(), remain, gomock.Any()).Return(nil)
+				return svc, repo
+			},
+			async:     true,
+			history:   []bool{true},
+			wantOk:    true,
+			wantAsync: true,
+		},
+		{
+			name: "重试次数用完删掉",
+			mock: func(ctrl *gomock.Controller) (*smsmocks.MockService, *repomocks.MockAsyncSMSRepository) {
+				last := msg
+				last.RetryCnt = 2
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("服务商错误"))
+				repo := repomocks.NewMockAsyncSMSRepository(ctrl)
+				repo.EXPECT().PreemptWaitingSMS(gomock.Any()).Return(last, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+				return svc, repo
+			},
+			async:     true,
+			history:   []bool{true},
+			wantOk:    true,
+			wantAsync: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, repo := tc.mock(ctrl)
+			s := NewService(svc, repo, testCfg)
+			s.async.Store(tc.async)
+			for _, failed := range tc.history {
+				s.stats.add(failed, time.Millisecond)
+			}
+			ok, err := s.RetryOnce(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.wantAsync, s.async.Load())
+		})
+	}
+}
+
+func TestService_backoff(t *testing.T) {
+	s := NewService(nil, nil, testCfg)
+	assert.Equal(t, time.Second*10, s.backoff(0))
+	assert.Equal(t, time.Second*40, s.backoff(2))
+	assert.Equal(t, time.Minute, s.backoff(10))
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"github.com/skcheng003/webook/internal/service/sms/template"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"github.com/skcheng003/webook/pkg/ratelimit"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken  = errors.New("短信网关 token 无效")
+	ErrQuotaExceeded = errors.New("业务方短信额度已经用完")
+	ErrInvalidTTL    = errors.New("短信网关 token 的有效期不对")
+	// ErrReservedTemplate 验证码这些内部模板不给业务方用，不然可以冒充 webook 发验证码
+	ErrReservedTemplate = errors.New("不能使用内部的短信模板")
+)
+
+// MaxTokenTTL token 最长的有效期，吊销记录也保留这么久
+const MaxTokenTTL = time.Hour * 24 * 90
+
+// reservedTplPrefix 验证码模板的前缀
+const reservedTplPrefix = "user/"
+
+// Claims 发给其它业务方的 token，一个 token 只能用一个模板
+type Claims struct {
+	jwt.RegisteredClaims
+	// Biz 调用方的业务名，额度和审计都按照它来
+	Biz string
+	// Tpl 允许使用的模板，对应 template.Registry 里面的 biz
+	Tpl string
+}
+
+// KeySet 签名和验签，jwt.KeySet 实现了这个接口，这样 service 不用依赖 web 的包
+type KeySet interface {
+	Sign(claims jwt.Claims) (string, error)
+	Parse(signedToken string, claims jwt.Claims) error
+}
+
+// Service 给其它业务方用的短信网关，tplId 传的是 IssueToken 签发的 token。
+// 每个业务方单独计算额度，每个号码占一个名额，每次发送都记审计日志
+type Service struct {
+	svc     sms.Service
+	keys    KeySet
+	limiter ratelimit.Limiter
+	// quotas 个别业务方单独配置的额度，没有配置的用 limiter
+	quotas map[string]ratelimit.Limiter
+	tokens repository.SMSTokenRepository
+}
+
+func NewService(svc sms.Service, keys KeySet, limiter ratelimit.Limiter,
+	tokens repository.SMSTokenRepository) *Service {
+	return &Service{
+		svc:     svc,
+		keys:    keys,
+		limiter: limiter,
+		quotas:  map[string]ratelimit.Limiter{},
+		tokens:  tokens,
+	}
+}
+
+// Quota 给某个业务方单独配置额度
+func (s *Service) Quota(biz string, limiter ratelimit.Limiter) *Service {
+	s.quotas[biz] = limiter
+	return s
+}
+
+// IssueToken ttl 必须在 (0, MaxTokenTTL] 之间，返回 token 和它的 jti，提前停用要用 jti 调用 Revoke
+func (s *Service) IssueToken(biz string, tpl string, ttl time.Duration) (string, string, error) {
+	if ttl <= 0 || ttl > MaxTokenTTL {
+		return "", "", ErrInvalidTTL
+	}
+	if strings.HasPrefix(tpl, reservedTplPrefix) {
+		return "", "", ErrReservedTemplate
+	}
+	now := time.Now()
+	claims := Claims{
+		Biz: biz,
+		Tpl: tpl,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	token, err := s.keys.Sign(claims)
+	return token, claims.ID, err
+}
+
+// Revoke 停用一个 token，token 最多 MaxTokenTTL 就过期了，吊销记录也只需要保留这么久
+func (s *Service) Revoke(ctx context.Context, jti string) error {
+	return s.tokens.Revoke(ctx, jti, MaxTokenTTL)
+}
+
+func (s *Service) Send(ctx context.Context, tplToken string,
+	args []string, numbers ...string) error {
+	c, err := s.parse(ctx, tplToken)
+	if err != nil {
+		s.audit(c, numbers, err)
+		return err
+	}
+	err = s.limit(ctx, c.Biz, len(numbers))
+	if err == nil {
+		err = s.svc.Send(ctx, template.Key(c.Tpl, i18n.FromContext(ctx)), args, numbers...)
+	}
+	s.audit(c, numbers, err)
+	return err
+}
+
+// parse 以前签发的没有 jti、没有过期时间或者用了内部模板的 token 都不认
+func (s *Service) parse(ctx context.Context, tplToken string) (Claims, error) {
+	var c Claims
+	err := s.keys.Parse(tplToken, &c)
+	if err != nil || c.Biz == "" || c.Tpl == "" || c.ID == "" || c.ExpiresAt == nil {
+		return c, ErrInvalidToken
+	}
+	if strings.HasPrefix(c.Tpl, reservedTplPrefix) {
+		return c, ErrReservedTemplate
+	}
+	revoked, err := s.tokens.IsRevoked(ctx, c.ID)
+	if err != nil {
+		// 和额度不一样，查不到吊销记录的时候不能放行
+		return c, err
+	}
+	if revoked {
+		return c, ErrInvalidToken
+	}
+	return c, nil
+}
+
+func (s *Service) limit(ctx context.Context, biz string, n int) error {
+	limiter, ok := s.quotas[biz]
+	if !ok {
+		limiter = s.limiter
+	}
+	limited, err := limiter.LimitN(ctx, "sms_gateway:"+biz, n)
+	if err != nil {
+		// 额度主要是防止业务方失控，redis 出问题的时候放行
+		zap.L().Error("短信网关判断额度失败", zap.String("biz", biz), zap.Error(err))
+		return nil
+	}
+	if limited {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+func (s *Service) audit(c Claims, numbers []string, err error) {
+	masked := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		masked = append(masked, domain.MaskPhone(n))
+	}
+	fields := []zap.Field{
+		zap.String("biz", c.Biz),
+		zap.String("tpl", c.Tpl),
+		zap.String("jti", c.ID),
+		zap.Strings("numbers", masked),
+		zap.Bool("success", err == nil),
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	zap.L().Info("sms_gateway_audit", fields...)
+}
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	"github.com/skcheng003/webook/internal/service/sms"
+	smsmocks "github.com/skcheng003/webook/internal/service/sms/mocks"
+	"github.com/skcheng003/webook/internal/service/sms/template"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"github.com/skcheng003/webook/pkg/ratelimit"
+	limitmocks "github.com/skcheng003/webook/pkg/ratelimit/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// hsKeys 测试用的单个 HS256 密钥
+type hsKeys []byte
+
+func (k hsKeys) Sign(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(k))
+}
+
+func (k hsKeys) Parse(signedToken string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(signedToken, claims, func(token *jwt.Token) (any, error) {
+		return []byte(k), nil
+	}, jwt.WithValidMethods([]string{"HS256"}))
+	return err
+}
+
+func TestService_IssueToken(t *testing.T) {
+	testCases := []struct {
+		name string
+		tpl  string
+		ttl  time.Duration
+
+		wantErr error
+	}{
+		{name: "签发成功", tpl: "promo", ttl: time.Hour},
+		{name: "没有有效期", tpl: "promo", wantErr: ErrInvalidTTL},
+		{name: "有效期太长", tpl: "promo", ttl: MaxTokenTTL + time.Hour, wantErr: ErrInvalidTTL},
+		{name: "验证码模板", tpl: "user/login", ttl: time.Hour, wantErr: ErrReservedTemplate},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys := hsKeys("sms-gateway-secret")
+			token, jti, err := NewService(nil, keys, nil, nil).IssueToken("marketing", tc.tpl, tc.ttl)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			var c Claims
+			require.NoError(t, keys.Parse(token, &c))
+			assert.Equal(t, jti, c.ID)
+			assert.NotEmpty(t, jti)
+			assert.WithinDuration(t, time.Now().Add(tc.ttl), c.ExpiresAt.Time, time.Second)
+		})
+	}
+}
+
+func TestService_Send(t *testing.T) {
+	keys := hsKeys("sms-gateway-secret")
+	issuer := NewService(nil, keys, nil, nil)
+	token, jti, err := issuer.IssueToken("marketing", "promo", time.Hour)
+	require.NoError(t, err)
+	expired, err := keys.Sign(Claims{
+		Biz: "marketing",
+		Tpl: "promo",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti-expired",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	})
+	require.NoError(t, err)
+	// 以前签发的不过期的 token
+	noExpiry, err := keys.Sign(Claims{
+		Biz:              "marketing",
+		Tpl:              "promo",
+		RegisteredClaims: jwt.RegisteredClaims{ID: "jti-legacy"},
+	})
+	require.NoError(t, err)
+	reserved, err := keys.Sign(Claims{
+		Biz: "marketing",
+		Tpl: "user/login",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti-reserved",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	require.NoError(t, err)
+	forged, _, err := NewService(nil, hsKeys("other"), nil, nil).IssueToken("marketing", "promo", time.Hour)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		// mock 返回的第三个是单独配置给 marketing 的额度，useQuota 为 true 才生效
+		mock func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+			ratelimit.Limiter, repository.SMSTokenRepository)
+		token    string
+		numbers  []string
+		useQuota bool
+
+		wantErr error
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), template.Key("promo", i18n.ZhCN),
+					[]string{"618"}, "+8613800000000").Return(nil)
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().LimitN(gomock.Any(), "sms_gateway:marketing", 1).Return(false, nil)
+				return svc, limiter, limitmocks.NewMockLimiter(ctrl), notRevoked(ctrl, jti)
+			},
+			token: token,
+		},
+		{
+			name: "每个号码占一个名额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().LimitN(gomock.Any(), "sms_gateway:marketing", 3).Return(true, nil)
+				return smsmocks.NewMockService(ctrl), limiter, limitmocks.NewMockLimiter(ctrl), notRevoked(ctrl, jti)
+			},
+			token:   token,
+			numbers: []string{"+8613800000000", "+8613800000001", "+8613800000002"},
+			wantErr: ErrQuotaExceeded,
+		},
+		{
+			name: "token 过期",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				return smsmocks.NewMockService(ctrl), limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), repomocks.NewMockSMSTokenRepository(ctrl)
+			},
+			token:   expired,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "没有过期时间",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				return smsmocks.NewMockService(ctrl), limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), repomocks.NewMockSMSTokenRepository(ctrl)
+			},
+			token:   noExpiry,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "验证码模板",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				return smsmocks.NewMockService(ctrl), limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), repomocks.NewMockSMSTokenRepository(ctrl)
+			},
+			token:   reserved,
+			wantErr: ErrReservedTemplate,
+		},
+		{
+			name: "密钥不对",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				return smsmocks.NewMockService(ctrl), limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), repomocks.NewMockSMSTokenRepository(ctrl)
+			},
+			token:   forged,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "已经吊销",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				tokens := repomocks.NewMockSMSTokenRepository(ctrl)
+				tokens.EXPECT().IsRevoked(gomock.Any(), jti).Return(true, nil)
+				return smsmocks.NewMockService(ctrl), limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), tokens
+			},
+			token:   token,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "查询吊销记录出错不放行",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				tokens := repomocks.NewMockSMSTokenRepository(ctrl)
+				tokens.EXPECT().IsRevoked(gomock.Any(), jti).Return(false, errors.New("redis 错误"))
+				return smsmocks.NewMockService(ctrl), limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), tokens
+			},
+			token:   token,
+			wantErr: errors.New("redis 错误"),
+		},
+		{
+			name: "额度用完",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().LimitN(gomock.Any(), "sms_gateway:marketing", 1).Return(true, nil)
+				return smsmocks.NewMockService(ctrl), limiter, limitmocks.NewMockLimiter(ctrl), notRevoked(ctrl, jti)
+			},
+			token:   token,
+			wantErr: ErrQuotaExceeded,
+		},
+		{
+			name: "单独配置的额度",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				quota := limitmocks.NewMockLimiter(ctrl)
+				quota.EXPECT().LimitN(gomock.Any(), "sms_gateway:marketing", 1).Return(true, nil)
+				return smsmocks.NewMockService(ctrl), limitmocks.NewMockLimiter(ctrl), quota, notRevoked(ctrl, jti)
+			},
+			token:    token,
+			useQuota: true,
+			wantErr:  ErrQuotaExceeded,
+		},
+		{
+			name: "判断额度出错放行",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter,
+				ratelimit.Limiter, repository.SMSTokenRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().LimitN(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("redis 错误"))
+				return svc, limiter, limitmocks.NewMockLimiter(ctrl), notRevoked(ctrl, jti)
+			},
+			token: token,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, limiter, quota, tokens := tc.mock(ctrl)
+			s := NewService(svc, keys, limiter, tokens)
+			if tc.useQuota {
+				s.Quota("marketing", quota)
+			}
+			numbers := tc.numbers
+			if numbers == nil {
+				numbers = []string{"+8613800000000"}
+			}
+			err := s.Send(context.Background(), tc.token, []string{"618"}, numbers...)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func notRevoked(ctrl *gomock.Controller, jti string) repository.SMSTokenRepository {
+	tokens := repomocks.NewMockSMSTokenRepository(ctrl)
+	tokens.EXPECT().IsRevoked(gomock.Any(), jti).Return(false, nil)
+	return tokens
+}
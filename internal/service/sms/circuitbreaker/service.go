@@ -0,0 +1,34 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"github.com/skcheng003/webook/pkg/circuitbreaker"
+)
+
+// CircuitBreakerSMSService 服务商连续出问题的时候直接返回 circuitbreaker.ErrOpen，
+// 不再每次都去请求，等冷却之后再放少量请求探测
+type CircuitBreakerSMSService struct {
+	svc     sms.Service
+	breaker *circuitbreaker.Breaker
+}
+
+func NewCircuitBreakerSMSService(svc sms.Service, breaker *circuitbreaker.Breaker) *CircuitBreakerSMSService {
+	return &CircuitBreakerSMSService{
+		svc:     svc,
+		breaker: breaker,
+	}
+}
+
+func (s *CircuitBreakerSMSService) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	done, err := s.breaker.Allow()
+	if err != nil {
+		return err
+	}
+	err = s.svc.Send(ctx, tplId, args, numbers...)
+	// 调用方自己取消的、服务商正常响应但是拒绝了的都不算服务商的问题
+	done(!sms.IsProviderFailure(err) || errors.Is(err, context.Canceled))
+	return err
+}
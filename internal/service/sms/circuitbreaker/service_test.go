@@ -0,0 +1,72 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/service/sms"
+	smsmocks "github.com/skcheng003/webook/internal/service/sms/mocks"
+	"github.com/skcheng003/webook/pkg/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerSMSService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		// err 服务商每次返回的错误
+		err error
+
+		wantState circuitbreaker.State
+	}{
+		{
+			name:      "请求失败熔断",
+			err:       errors.New("connection refused"),
+			wantState: circuitbreaker.StateOpen,
+		},
+		{
+			name:      "5xx 熔断",
+			err:       &sms.ResponseError{StatusCode: 502},
+			wantState: circuitbreaker.StateOpen,
+		},
+		{
+			name:      "4xx 不熔断",
+			err:       &sms.ResponseError{StatusCode: 400, Code: "isv.SMS_SIGNATURE_ILLEGAL"},
+			wantState: circuitbreaker.StateClosed,
+		},
+		{
+			name:      "号码被拒绝不熔断",
+			err:       &sms.RejectedError{Numbers: []string{"+8613800000000"}, Reason: "号码格式错误"},
+			wantState: circuitbreaker.StateClosed,
+		},
+		{
+			name:      "调用方取消不熔断",
+			err:       context.Canceled,
+			wantState: circuitbreaker.StateClosed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := smsmocks.NewMockService(ctrl)
+			svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(tc.err).Times(4)
+			breaker := circuitbreaker.NewBreaker("test", circuitbreaker.Config{
+				Window:       time.Minute,
+				Buckets:      6,
+				MinRequests:  4,
+				FailureRatio: 0.5,
+				Cooldown:     time.Minute,
+				HalfOpenMax:  1,
+			})
+			s := NewCircuitBreakerSMSService(svc, breaker)
+			for i := 0; i < 4; i++ {
+				err := s.Send(context.Background(), "1110", []string{"123456"}, "+8613800000000")
+				assert.Equal(t, tc.err, err)
+			}
+			assert.Equal(t, tc.wantState, breaker.State())
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"go.uber.org/zap"
+	"sync/atomic"
+)
+
+var ErrAllFailed = errors.New("所有短信服务商都发送失败")
+
+// FailOverSMSService 轮询：每次从下一个服务商开始，
+// 失败了就换下一个，直到全部都试过一遍
+type FailOverSMSService struct {
+	svcs []sms.Service
+	idx  uint64
+}
+
+func NewFailOverSMSService(svcs []sms.Service) *FailOverSMSService {
+	return &FailOverSMSService{
+		svcs: svcs,
+	}
+}
+
+func (f *FailOverSMSService) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	// 起点也轮询，不然第一个服务商的压力最大
+	idx := atomic.AddUint64(&f.idx, 1)
+	length := uint64(len(f.svcs))
+	for i := idx; i < idx+length; i++ {
+		svc := f.svcs[i%length]
+		err := svc.Send(ctx, tplId, args, numbers...)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			// 调用方已经不等了，换服务商也没用
+			return err
+		}
+		zap.L().Warn("短信服务商发送失败，切换下一个",
+			zap.Uint64("idx", i%length), zap.Error(err))
+	}
+	return ErrAllFailed
+}
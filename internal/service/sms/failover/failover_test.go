@@ -0,0 +1,184 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/service/sms"
+	smsmocks "github.com/skcheng003/webook/internal/service/sms/mocks"
+	"github.com/skcheng003/webook/pkg/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestFailOverSMSService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) []sms.Service
+
+		wantErr error
+	}{
+		{
+			name: "一次成功",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc1 := smsmocks.NewMockService(ctrl)
+				// idx 从 1 开始
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				return []sms.Service{svc0, svc1}
+			},
+		},
+		{
+			name: "失败之后换下一个",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("发送失败"))
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				return []sms.Service{svc0, svc1}
+			},
+		},
+		{
+			name: "全部失败",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("发送失败"))
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("发送失败"))
+				return []sms.Service{svc0, svc1}
+			},
+			wantErr: ErrAllFailed,
+		},
+		{
+			name: "调用方超时不再重试",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(context.DeadlineExceeded)
+				return []sms.Service{svc0, svc1}
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewFailOverSMSService(tc.mock(ctrl))
+			err := svc.Send(context.Background(), "1110", []string{"123456"}, "+8613800000000")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestTimeoutFailoverSMSService_Send(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mock      func(ctrl *gomock.Controller) []sms.Service
+		idx       int32
+		cnt       int32
+		threshold int32
+
+		wantErr error
+		wantIdx int32
+		wantCnt int32
+	}{
+		{
+			name: "没有超过阈值",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				return []sms.Service{svc0, smsmocks.NewMockService(ctrl)}
+			},
+			cnt:       2,
+			threshold: 3,
+			wantIdx:   0,
+			wantCnt:   0,
+		},
+		{
+			name: "超时计数",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(context.DeadlineExceeded)
+				return []sms.Service{svc0, smsmocks.NewMockService(ctrl)}
+			},
+			cnt:       1,
+			threshold: 3,
+			wantErr:   context.DeadlineExceeded,
+			wantIdx:   0,
+			wantCnt:   2,
+		},
+		{
+			name: "其它错误不计数",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("模板不存在"))
+				return []sms.Service{svc0, smsmocks.NewMockService(ctrl)}
+			},
+			cnt:       1,
+			threshold: 3,
+			wantErr:   errors.New("模板不存在"),
+			wantIdx:   0,
+			wantCnt:   1,
+		},
+		{
+			name: "超过阈值切换",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc1 := smsmocks.NewMockService(ctrl)
+				svc1.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				return []sms.Service{smsmocks.NewMockService(ctrl), svc1}
+			},
+			cnt:       3,
+			threshold: 3,
+			wantIdx:   1,
+			wantCnt:   0,
+		},
+		{
+			name: "熔断之后下一次切换",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(circuitbreaker.ErrOpen)
+				return []sms.Service{svc0, smsmocks.NewMockService(ctrl)}
+			},
+			threshold: 3,
+			wantErr:   circuitbreaker.ErrOpen,
+			wantIdx:   0,
+			wantCnt:   3,
+		},
+		{
+			name: "最后一个切回第一个",
+			mock: func(ctrl *gomock.Controller) []sms.Service {
+				svc0 := smsmocks.NewMockService(ctrl)
+				svc0.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(context.DeadlineExceeded)
+				return []sms.Service{svc0, smsmocks.NewMockService(ctrl)}
+			},
+			idx:       1,
+			cnt:       3,
+			threshold: 3,
+			wantErr:   context.DeadlineExceeded,
+			wantIdx:   0,
+			wantCnt:   1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewTimeoutFailoverSMSService(tc.mock(ctrl), tc.threshold)
+			svc.idx = tc.idx
+			svc.cnt = tc.cnt
+			err := svc.Send(context.Background(), "1110", []string{"123456"}, "+8613800000000")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantIdx, svc.idx)
+			assert.Equal(t, tc.wantCnt, svc.cnt)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"github.com/skcheng003/webook/pkg/circuitbreaker"
+	"sync/atomic"
+)
+
+// TimeoutFailoverSMSService 连续超时 threshold 次或者熔断了就认为当前服务商出问题了，
+// 切换到下一个。其它错误不切换，直接返回给调用方
+type TimeoutFailoverSMSService struct {
+	svcs []sms.Service
+	// 当前正在使用的服务商
+	idx int32
+	// 连续超时的次数
+	cnt       int32
+	threshold int32
+}
+
+func NewTimeoutFailoverSMSService(svcs []sms.Service, threshold int32) *TimeoutFailoverSMSService {
+	return &TimeoutFailoverSMSService{
+		svcs:      svcs,
+		threshold: threshold,
+	}
+}
+
+func (t *TimeoutFailoverSMSService) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	idx := atomic.LoadInt32(&t.idx)
+	cnt := atomic.LoadInt32(&t.cnt)
+	if cnt >= t.threshold {
+		newIdx := (idx + 1) % int32(len(t.svcs))
+		// 并发的时候只有一个 goroutine 能切换成功，其它的用切换后的
+		if atomic.CompareAndSwapInt32(&t.idx, idx, newIdx) {
+			atomic.StoreInt32(&t.cnt, 0)
+		}
+		idx = atomic.LoadInt32(&t.idx)
+	}
+	err := t.svcs[idx].Send(ctx, tplId, args, numbers...)
+	switch {
+	case err == nil:
+		// 连续超时才切换，成功一次就重新计数
+		atomic.StoreInt32(&t.cnt, 0)
+	case errors.Is(err, context.DeadlineExceeded):
+		atomic.AddInt32(&t.cnt, 1)
+	case errors.Is(err, circuitbreaker.ErrOpen):
+		// 已经熔断了，下一次直接切换
+		atomic.StoreInt32(&t.cnt, t.threshold)
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/service/sms/types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/service/sms/types.go -package=smsmocks -destination=internal/service/sms/mocks/sms.mock.gen.go
+//
+
+// Package smsmocks is a generated GoMock package.
+package smsmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	sms "github.com/skcheng003/webook/internal/service/sms"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockService) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, tplId, args}
+	for _, a := range numbers {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Send", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockServiceMockRecorder) Send(ctx, tplId, args any, numbers ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, tplId, args}, numbers...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockService)(nil).Send), varargs...)
+}
+
+// MockResultService is a mock of ResultService interface.
+type MockResultService struct {
+	ctrl     *gomock.Controller
+	recorder *MockResultServiceMockRecorder
+}
+
+// MockResultServiceMockRecorder is the mock recorder for MockResultService.
+type MockResultServiceMockRecorder struct {
+	mock *MockResultService
+}
+
+// NewMockResultService creates a new mock instance.
+func NewMockResultService(ctrl *gomock.Controller) *MockResultService {
+	mock := &MockResultService{ctrl: ctrl}
+	mock.recorder = &MockResultServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResultService) EXPECT() *MockResultServiceMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockResultService) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, tplId, args}
+	for _, a := range numbers {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Send", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockResultServiceMockRecorder) Send(ctx, tplId, args any, numbers ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, tplId, args}, numbers...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockResultService)(nil).Send), varargs...)
+}
+
+// SendWithResult mocks base method.
+func (m *MockResultService) SendWithResult(ctx context.Context, tplId string, args []string, numbers ...string) ([]sms.SendResult, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, tplId, args}
+	for _, a := range numbers {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SendWithResult", varargs...)
+	ret0, _ := ret[0].([]sms.SendResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendWithResult indicates an expected call of SendWithResult.
+func (mr *MockResultServiceMockRecorder) SendWithResult(ctx, tplId, args any, numbers ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, tplId, args}, numbers...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWithResult", reflect.TypeOf((*MockResultService)(nil).SendWithResult), varargs...)
+}
+
+// MockReceiptParser is a mock of ReceiptParser interface.
+type MockReceiptParser struct {
+	ctrl     *gomock.Controller
+	recorder *MockReceiptParserMockRecorder
+}
+
+// MockReceiptParserMockRecorder is the mock recorder for MockReceiptParser.
+type MockReceiptParserMockRecorder struct {
+	mock *MockReceiptParser
+}
+
+// NewMockReceiptParser creates a new mock instance.
+func NewMockReceiptParser(ctrl *gomock.Controller) *MockReceiptParser {
+	mock := &MockReceiptParser{ctrl: ctrl}
+	mock.recorder = &MockReceiptParserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReceiptParser) EXPECT() *MockReceiptParserMockRecorder {
+	return m.recorder
+}
+
+// ParseReceipts mocks base method.
+func (m *MockReceiptParser) ParseReceipts(body []byte) ([]sms.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseReceipts", body)
+	ret0, _ := ret[0].([]sms.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseReceipts indicates an expected call of ParseReceipts.
+func (mr *MockReceiptParserMockRecorder) ParseReceipts(body any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseReceipts", reflect.TypeOf((*MockReceiptParser)(nil).ParseReceipts), body)
+}
+
+// ReceiptAck mocks base method.
+func (m *MockReceiptParser) ReceiptAck() any {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiptAck")
+	ret0, _ := ret[0].(any)
+	return ret0
+}
+
+// ReceiptAck indicates an expected call of ReceiptAck.
+func (mr *MockReceiptParserMockRecorder) ReceiptAck() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiptAck", reflect.TypeOf((*MockReceiptParser)(nil).ReceiptAck))
+}
@@ -14,8 +14,8 @@ import (
 
 const key = "tencent_sms"
 
-// 暂时不对外
-var errLimited = errors.New("短信服务触发限流")
+// ErrLimited 异步发送的装饰器要根据这个判断是不是被限流了
+var ErrLimited = errors.New("短信服务触发限流")
 
 type RateLimitSMSService struct {
 	delegate sms.Service
@@ -39,7 +39,7 @@ func (s *RateLimitSMSService) Send(ctx context.Context, tplId string,
 		return fmt.Errorf("短信服务判断是否限流异常 %w", err)
 	}
 	if limited {
-		return errLimited
+		return ErrLimited
 	}
 	return s.delegate.Send(ctx, tplId, args, numbers...)
 }
@@ -0,0 +1,114 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"strings"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("短信模板不存在")
+	ErrInvalidArgs      = errors.New("短信模板参数个数不对")
+)
+
+// Template 服务商那边申请的模板
+type Template struct {
+	Biz      string
+	Provider string
+	Locale   i18n.Lang
+	// Id 服务商给的模板 id
+	Id string
+	// ParamCount 模板里面的参数个数，发送之前校验
+	ParamCount int
+}
+
+type templateKey struct {
+	biz      string
+	provider string
+	locale   i18n.Lang
+}
+
+// Registry 业务场景、服务商、语言三者确定一个模板。
+// 不同的服务商模板 id 不一样，故障切换之后也要用对应服务商的模板
+type Registry struct {
+	tpls map[templateKey]Template
+}
+
+func NewRegistry(tpls []Template) *Registry {
+	r := &Registry{
+		tpls: make(map[templateKey]Template, len(tpls)),
+	}
+	for _, tpl := range tpls {
+		r.tpls[templateKey{biz: tpl.Biz, provider: tpl.Provider, locale: tpl.Locale}] = tpl
+	}
+	return r
+}
+
+// Find 找不到对应语言的模板就用 i18n.Default 的
+func (r *Registry) Find(biz string, provider string, locale i18n.Lang) (Template, error) {
+	if tpl, ok := r.tpls[templateKey{biz: biz, provider: provider, locale: locale}]; ok {
+		return tpl, nil
+	}
+	if tpl, ok := r.tpls[templateKey{biz: biz, provider: provider, locale: i18n.Default}]; ok {
+		return tpl, nil
+	}
+	return Template{}, fmt.Errorf("%w, biz: %s, provider: %s, locale: %s",
+		ErrTemplateNotFound, biz, provider, locale)
+}
+
+// Check 确认每个业务场景在每个服务商那边都有 i18n.Default 的模板，
+// 这样不管故障切换到哪个服务商、用户是什么语言都能找到模板。缺了哪些都会列出来
+func (r *Registry) Check(bizs []string, providers []string) error {
+	var errs []error
+	for _, biz := range bizs {
+		for _, provider := range providers {
+			if _, ok := r.tpls[templateKey{biz: biz, provider: provider, locale: i18n.Default}]; !ok {
+				errs = append(errs, fmt.Errorf("%w, biz: %s, provider: %s, locale: %s",
+					ErrTemplateNotFound, biz, provider, i18n.Default))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Key 业务方调用 sms.Service 的时候传的 tplId，真正的模板 id 到了具体的服务商再确定
+func Key(biz string, locale i18n.Lang) string {
+	return biz + "#" + string(locale)
+}
+
+func parseKey(key string) (string, i18n.Lang) {
+	biz, locale, _ := strings.Cut(key, "#")
+	return biz, i18n.Lang(locale)
+}
+
+// Service 包在具体的服务商外面，把 Key 换成这个服务商的模板 id
+type Service struct {
+	provider string
+	svc      sms.Service
+	registry *Registry
+}
+
+func NewService(provider string, svc sms.Service, registry *Registry) *Service {
+	return &Service{
+		provider: provider,
+		svc:      svc,
+		registry: registry,
+	}
+}
+
+func (s *Service) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	biz, locale := parseKey(tplId)
+	tpl, err := s.registry.Find(biz, s.provider, locale)
+	if err != nil {
+		return err
+	}
+	if len(args) != tpl.ParamCount {
+		return fmt.Errorf("%w, 模板 %s 需要 %d 个参数，传了 %d 个",
+			ErrInvalidArgs, tpl.Id, tpl.ParamCount, len(args))
+	}
+	return s.svc.Send(ctx, tpl.Id, args, numbers...)
+}
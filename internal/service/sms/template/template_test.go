@@ -0,0 +1,146 @@
+package template
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/service/sms"
+	smsmocks "github.com/skcheng003/webook/internal/service/sms/mocks"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	registry := NewRegistry([]Template{
+		{Biz: "user/login", Provider: "tencent", Locale: i18n.ZhCN, Id: "1110", ParamCount: 1},
+		{Biz: "user/login", Provider: "tencent", Locale: i18n.EnUS, Id: "1111", ParamCount: 1},
+		{Biz: "user/login", Provider: "aliyun", Locale: i18n.ZhCN, Id: "SMS_1110", ParamCount: 1},
+		{Biz: "user/unlock", Provider: "tencent", Locale: i18n.ZhCN, Id: "1120", ParamCount: 1},
+	})
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) sms.Service
+		provider string
+		key      string
+		args     []string
+
+		wantErr error
+	}{
+		{
+			name: "按照语言选模板",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "1111", []string{"123456"}, "+8613800000000").Return(nil)
+				return svc
+			},
+			provider: "tencent",
+			key:      Key("user/login", i18n.EnUS),
+			args:     []string{"123456"},
+		},
+		{
+			name: "不同服务商的模板 id 不一样",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "SMS_1110", []string{"123456"}, "+8613800000000").Return(nil)
+				return svc
+			},
+			provider: "aliyun",
+			key:      Key("user/login", i18n.ZhCN),
+			args:     []string{"123456"},
+		},
+		{
+			name: "没有英文模板用中文的",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "1120", []string{"123456"}, "+8613800000000").Return(nil)
+				return svc
+			},
+			provider: "tencent",
+			key:      Key("user/unlock", i18n.EnUS),
+			args:     []string{"123456"},
+		},
+		{
+			name: "模板不存在",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				return smsmocks.NewMockService(ctrl)
+			},
+			provider: "aliyun",
+			key:      Key("user/unlock", i18n.ZhCN),
+			args:     []string{"123456"},
+			wantErr:  ErrTemplateNotFound,
+		},
+		{
+			name: "参数个数不对",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				return smsmocks.NewMockService(ctrl)
+			},
+			provider: "tencent",
+			key:      Key("user/login", i18n.ZhCN),
+			args:     []string{"123456", "5"},
+			wantErr:  ErrInvalidArgs,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewService(tc.provider, tc.mock(ctrl), registry)
+			err := svc.Send(context.Background(), tc.key, tc.args, "+8613800000000")
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestRegistry_Check(t *testing.T) {
+	registry := NewRegistry([]Template{
+		{Biz: "user/login", Provider: "tencent", Locale: i18n.ZhCN, Id: "1110", ParamCount: 1},
+		{Biz: "user/login", Provider: "aliyun", Locale: i18n.ZhCN, Id: "SMS_1110", ParamCount: 1},
+		{Biz: "user/unlock", Provider: "tencent", Locale: i18n.ZhCN, Id: "1120", ParamCount: 1},
+		{Biz: "user/unlock", Provider: "aliyun", Locale: i18n.EnUS, Id: "SMS_1121", ParamCount: 1},
+	})
+	testCases := []struct {
+		name      string
+		bizs      []string
+		providers []string
+
+		wantErr     bool
+		wantMissing []string
+	}{
+		{
+			name:      "每个服务商都有中文模板",
+			bizs:      []string{"user/login"},
+			providers: []string{"tencent", "aliyun"},
+		},
+		{
+			name:        "只有英文模板不算",
+			bizs:        []string{"user/login", "user/unlock"},
+			providers:   []string{"tencent", "aliyun"},
+			wantErr:     true,
+			wantMissing: []string{"biz: user/unlock, provider: aliyun"},
+		},
+		{
+			name:      "缺了的都列出来",
+			bizs:      []string{"user/login", "user/merge_phone"},
+			providers: []string{"tencent", "memory"},
+			wantErr:   true,
+			wantMissing: []string{
+				"biz: user/login, provider: memory",
+				"biz: user/merge_phone, provider: tencent",
+				"biz: user/merge_phone, provider: memory",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := registry.Check(tc.bizs, tc.providers)
+			if !tc.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrTemplateNotFound)
+			for _, m := range tc.wantMissing {
+				assert.Contains(t, err.Error(), m)
+			}
+		})
+	}
+}
@@ -2,18 +2,20 @@ package tencent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/ecodeclub/ekit/slice"
-	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"github.com/skcheng003/webook/internal/service/sms"
+	tencentsms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
 )
 
 type Service struct {
-	client   *sms.Client
+	client   *tencentsms.Client
 	appId    *string
 	signName *string
 }
 
-func NewService(c *sms.Client, appId string,
+func NewService(c *tencentsms.Client, appId string,
 	signName string) *Service {
 	return &Service{
 		client:   c,
@@ -24,7 +26,30 @@ func NewService(c *sms.Client, appId string,
 
 func (s *Service) Send(ctx context.Context, tplId string,
 	args []string, numbers ...string) error {
-	req := sms.NewSendSmsRequest()
+	results, err := s.SendWithResult(ctx, tplId, args, numbers...)
+	if err != nil {
+		return err
+	}
+	var rejected *sms.RejectedError
+	for _, res := range results {
+		if res.Success {
+			continue
+		}
+		if rejected == nil {
+			rejected = &sms.RejectedError{Reason: res.Reason}
+		}
+		rejected.Numbers = append(rejected.Numbers, res.Number)
+	}
+	if rejected != nil {
+		return rejected
+	}
+	return nil
+}
+
+// SendWithResult 每个号码都有自己的 SerialNo，回执里面的 sid 就是它
+func (s *Service) SendWithResult(ctx context.Context, tplId string,
+	args []string, numbers ...string) ([]sms.SendResult, error) {
+	req := tencentsms.NewSendSmsRequest()
 	req.PhoneNumberSet = toStringPtrSlice(numbers)
 	req.SmsSdkAppId = s.appId
 	req.SetContext(ctx)
@@ -33,15 +58,50 @@ func (s *Service) Send(ctx context.Context, tplId string,
 	req.SignName = s.signName
 	resp, err := s.client.SendSms(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	results := make([]sms.SendResult, 0, len(resp.Response.SendStatusSet))
 	for _, status := range resp.Response.SendStatusSet {
-		if status.Code == nil || *(status.Code) != "Ok" {
-			return fmt.Errorf("发送失败，code: %s, 原因：%s",
-				*status.Code, *status.Message)
+		res := sms.SendResult{
+			Number:   deref(status.PhoneNumber),
+			SerialNo: deref(status.SerialNo),
+			Success:  deref(status.Code) == "Ok",
+		}
+		if !res.Success {
+			res.Reason = fmt.Sprintf("code: %s, %s", deref(status.Code), deref(status.Message))
 		}
+		results = append(results, res)
 	}
-	return nil
+	return results, nil
+}
+
+// receipt 腾讯云短信下发状态回调的一条记录，回调是一个数组
+type receipt struct {
+	Sid          string `json:"sid"`
+	ReportStatus string `json:"report_status"`
+	ErrMsg       string `json:"errmsg"`
+	Description  string `json:"description"`
+}
+
+func (s *Service) ParseReceipts(body []byte) ([]sms.Receipt, error) {
+	var receipts []receipt
+	if err := json.Unmarshal(body, &receipts); err != nil {
+		return nil, err
+	}
+	return slice.Map[receipt, sms.Receipt](receipts, func(idx int, src receipt) sms.Receipt {
+		res := sms.Receipt{
+			SerialNo:  src.Sid,
+			Delivered: src.ReportStatus == "SUCCESS",
+		}
+		if !res.Delivered {
+			res.Reason = src.ErrMsg + " " + src.Description
+		}
+		return res
+	}), nil
+}
+
+func (s *Service) ReceiptAck() any {
+	return map[string]any{"result": 0, "errmsg": "OK"}
 }
 
 func toStringPtrSlice(src []string) []*string {
@@ -49,3 +109,10 @@ func toStringPtrSlice(src []string) []*string {
 		return &src
 	})
 }
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
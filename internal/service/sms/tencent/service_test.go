@@ -2,7 +2,9 @@ package tencent
 
 import (
 	"context"
+	smstypes "github.com/skcheng003/webook/internal/service/sms"
 	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"reflect"
 	"testing"
 )
 
@@ -38,3 +40,21 @@ func TestService_Send(t *testing.T) {
 		})
 	}
 }
+
+func TestService_ParseReceipts(t *testing.T) {
+	s := &Service{}
+	receipts, err := s.ParseReceipts([]byte(`[
+		{"mobile":"13800000000","report_status":"SUCCESS","errmsg":"DELIVRD","sid":"sid-1"},
+		{"mobile":"13800000001","report_status":"FAIL","errmsg":"MK:0001","description":"空号","sid":"sid-2"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []smstypes.Receipt{
+		{SerialNo: "sid-1", Delivered: true},
+		{SerialNo: "sid-2", Reason: "MK:0001 空号"},
+	}
+	if !reflect.DeepEqual(receipts, want) {
+		t.Errorf("ParseReceipts() = %v, want %v", receipts, want)
+	}
+}
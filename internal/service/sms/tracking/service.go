@@ -0,0 +1,107 @@
+package tracking
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Service 记录每个号码的发送结果，服务商支持 sms.ResultService 的话还会记下流水号，
+// 之后收到回执的时候按流水号更新状态。要放在最里面，这样 failover 换了服务商也能记对
+type Service struct {
+	svc      sms.Service
+	provider string
+	repo     repository.SMSDeliveryRepository
+}
+
+func NewService(svc sms.Service, provider string, repo repository.SMSDeliveryRepository) *Service {
+	return &Service{
+		svc:      svc,
+		provider: provider,
+		repo:     repo,
+	}
+}
+
+func (s *Service) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	res, err := s.send(ctx, tplId, args, numbers)
+	s.record(tplId, res)
+	if err != nil {
+		return err
+	}
+	var rejected *sms.RejectedError
+	for _, r := range res {
+		if r.Success {
+			continue
+		}
+		if rejected == nil {
+			// 原因只留第一个，每个号码的原因发送记录里面都有
+			rejected = &sms.RejectedError{Reason: r.Reason}
+		}
+		rejected.Numbers = append(rejected.Numbers, r.Number)
+	}
+	if rejected != nil {
+		return rejected
+	}
+	return nil
+}
+
+func (s *Service) send(ctx context.Context, tplId string,
+	args []string, numbers []string) ([]sms.SendResult, error) {
+	if rs, ok := s.svc.(sms.ResultService); ok {
+		res, err := rs.SendWithResult(ctx, tplId, args, numbers...)
+		if res != nil || err == nil {
+			return res, err
+		}
+		// 请求都没有成功，每个号码都记一条失败
+		return failAll(numbers, err), err
+	}
+	// 拿不到流水号，只能记下有没有受理
+	err := s.svc.Send(ctx, tplId, args, numbers...)
+	if err != nil {
+		return failAll(numbers, err), err
+	}
+	res := make([]sms.SendResult, 0, len(numbers))
+	for _, n := range numbers {
+		res = append(res, sms.SendResult{Number: n, Success: true})
+	}
+	return res, nil
+}
+
+// record 发送记录写失败不影响发短信，调用方取消了也要记下来，所以不用调用方的 ctx
+func (s *Service) record(tplId string, res []sms.SendResult) {
+	if len(res) == 0 {
+		return
+	}
+	ds := make([]domain.SMSDelivery, 0, len(res))
+	for _, r := range res {
+		status := domain.SMSDeliveryAccepted
+		if !r.Success {
+			status = domain.SMSDeliveryRejected
+		}
+		ds = append(ds, domain.SMSDelivery{
+			Provider: s.provider,
+			TplId:    tplId,
+			Phone:    r.Number,
+			Status:   status,
+			SerialNo: r.SerialNo,
+			Reason:   r.Reason,
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.repo.Create(ctx, ds); err != nil {
+		zap.L().Error("保存短信发送记录失败", zap.String("provider", s.provider), zap.Error(err))
+	}
+}
+
+func failAll(numbers []string, err error) []sms.SendResult {
+	res := make([]sms.SendResult, 0, len(numbers))
+	for _, n := range numbers {
+		res = append(res, sms.SendResult{Number: n, Reason: err.Error()})
+	}
+	return res
+}
@@ -0,0 +1,126 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"github.com/skcheng003/webook/internal/domain"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	"github.com/skcheng003/webook/internal/service/sms"
+	smsmocks "github.com/skcheng003/webook/internal/service/sms/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (sms.Service, *repomocks.MockSMSDeliveryRepository)
+		numbers []string
+
+		wantErr bool
+		// wantRejected 服务商没有受理的号码
+		wantRejected []string
+	}{
+		{
+			name: "记录流水号",
+			mock: func(ctrl *gomock.Controller) (sms.Service, *repomocks.MockSMSDeliveryRepository) {
+				svc := smsmocks.NewMockResultService(ctrl)
+				svc.EXPECT().SendWithResult(gomock.Any(), "1110", []string{"123456"},
+					"+8613800000000", "+8613800000001").
+					Return([]sms.SendResult{
+						{Number: "+8613800000000", SerialNo: "sid-1", Success: true},
+						{Number: "+8613800000001", SerialNo: "sid-2", Success: true},
+					}, nil)
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), []domain.SMSDelivery{
+					{Provider: "tencent", TplId: "1110", Phone: "+8613800000000",
+						Status: domain.SMSDeliveryAccepted, SerialNo: "sid-1"},
+					{Provider: "tencent", TplId: "1110", Phone: "+8613800000001",
+						Status: domain.SMSDeliveryAccepted, SerialNo: "sid-2"},
+				}).Return(nil)
+				return svc, repo
+			},
+			numbers: []string{"+8613800000000", "+8613800000001"},
+		},
+		{
+			name: "部分号码被拒绝",
+			mock: func(ctrl *gomock.Controller) (sms.Service, *repomocks.MockSMSDeliveryRepository) {
+				svc := smsmocks.NewMockResultService(ctrl)
+				svc.EXPECT().SendWithResult(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return([]sms.SendResult{
+						{Number: "+8613800000000", SerialNo: "sid-1", Success: true},
+						{Number: "+8613800000001", Reason: "号码格式错误"},
+					}, nil)
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), []domain.SMSDelivery{
+					{Provider: "tencent", TplId: "1110", Phone: "+8613800000000",
+						Status: domain.SMSDeliveryAccepted, SerialNo: "sid-1"},
+					{Provider: "tencent", TplId: "1110", Phone: "+8613800000001",
+						Status: domain.SMSDeliveryRejected, Reason: "号码格式错误"},
+				}).Return(nil)
+				return svc, repo
+			},
+			numbers:      []string{"+8613800000000", "+8613800000001"},
+			wantErr:      true,
+			wantRejected: []string{"+8613800000001"},
+		},
+		{
+			name: "请求失败每个号码都记失败",
+			mock: func(ctrl *gomock.Controller) (sms.Service, *repomocks.MockSMSDeliveryRepository) {
+				svc := smsmocks.NewMockResultService(ctrl)
+				svc.EXPECT().SendWithResult(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("超时"))
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), []domain.SMSDelivery{
+					{Provider: "tencent", TplId: "1110", Phone: "+8613800000000",
+						Status: domain.SMSDeliveryRejected, Reason: "超时"},
+				}).Return(nil)
+				return svc, repo
+			},
+			numbers: []string{"+8613800000000"},
+			wantErr: true,
+		},
+		{
+			name: "不支持流水号的服务商",
+			mock: func(ctrl *gomock.Controller) (sms.Service, *repomocks.MockSMSDeliveryRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "1110", []string{"123456"}, "+8613800000000").Return(nil)
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), []domain.SMSDelivery{
+					{Provider: "tencent", TplId: "1110", Phone: "+8613800000000",
+						Status: domain.SMSDeliveryAccepted},
+				}).Return(nil)
+				return svc, repo
+			},
+			numbers: []string{"+8613800000000"},
+		},
+		{
+			name: "记录失败不影响发送",
+			mock: func(ctrl *gomock.Controller) (sms.Service, *repomocks.MockSMSDeliveryRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errors.New("db 错误"))
+				return svc, repo
+			},
+			numbers: []string{"+8613800000000"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, repo := tc.mock(ctrl)
+			err := NewService(svc, "tencent", repo).
+				Send(context.Background(), "1110", []string{"123456"}, tc.numbers...)
+			assert.Equal(t, tc.wantErr, err != nil)
+			var rejected *sms.RejectedError
+			if errors.As(err, &rejected) {
+				assert.Equal(t, tc.wantRejected, rejected.Numbers)
+			} else {
+				assert.Empty(t, tc.wantRejected)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package sms
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/skcheng003/webook/internal/domain"
+	"strings"
+)
 
 // Service 发送短信的抽象
 // 目前你可以理解为，这是一个为了适配不同的短信供应商的抽象
@@ -8,3 +14,77 @@ type Service interface {
 	Send(ctx context.Context, tplId string,
 		args []string, numbers ...string) error
 }
+
+// SendResult 每个号码的发送结果，Success 表示服务商已经受理，是否送达要看回执
+type SendResult struct {
+	Number   string
+	SerialNo string
+	Success  bool
+	Reason   string
+}
+
+// ResultService 能返回流水号的服务商实现这个接口，发送记录里面有流水号才能和回执对上
+type ResultService interface {
+	Service
+	SendWithResult(ctx context.Context, tplId string,
+		args []string, numbers ...string) ([]SendResult, error)
+}
+
+// Receipt 服务商推送的送达回执
+type Receipt struct {
+	SerialNo string
+	// Delivered 是否送达用户手机
+	Delivered bool
+	Reason    string
+}
+
+// ReceiptParser 各个服务商回执的格式不一样，由各自的实现解析
+type ReceiptParser interface {
+	ParseReceipts(body []byte) ([]Receipt, error)
+	// ReceiptAck 处理成功之后返回给服务商的响应
+	ReceiptAck() any
+}
+
+// RejectedError 请求成功了，但是部分号码服务商没有受理，比如号码不对、被拉黑。
+// 服务商本身是正常的，上层可以只重试这些号码
+type RejectedError struct {
+	Numbers []string
+	Reason  string
+}
+
+func (e *RejectedError) Error() string {
+	masked := make([]string, 0, len(e.Numbers))
+	for _, n := range e.Numbers {
+		masked = append(masked, domain.MaskPhone(n))
+	}
+	return fmt.Sprintf("短信发送失败 %s: %s", strings.Join(masked, ","), e.Reason)
+}
+
+// ResponseError 服务商响应了，但是没有受理这次请求，比如签名不对、参数不对、欠费
+type ResponseError struct {
+	// StatusCode HTTP 状态码，有的服务商业务错误也是 200
+	StatusCode int
+	Code       string
+	Msg        string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("发送失败，HTTP 状态码 %d, code: %s, 原因：%s", e.StatusCode, e.Code, e.Msg)
+}
+
+// IsProviderFailure 是不是服务商自己出了问题：请求没发出去、超时、5xx 都算。
+// 号码被拒绝、4xx 这些是请求本身的问题，服务商是好的，熔断和切换的时候不能算进去
+func IsProviderFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rejected *RejectedError
+	if errors.As(err, &rejected) {
+		return false
+	}
+	var resp *ResponseError
+	if errors.As(err, &resp) {
+		return resp.StatusCode >= 500
+	}
+	return true
+}
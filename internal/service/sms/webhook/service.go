@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderTimestamp = "X-Webook-Timestamp"
+	HeaderSignature = "X-Webook-Signature"
+)
+
+type Config struct {
+	URL string
+	// Secret 不为空的时候对请求签名，接收方用来确认请求确实来自 webook
+	Secret string
+	// Headers 额外的请求头，比如对方要求的 Authorization
+	Headers map[string]string
+}
+
+// Service 通用的 HTTP 短信服务商，把短信 POST 给一个 webhook 地址，
+// 方便接入没有专门实现的服务商或者公司内部的短信网关
+type Service struct {
+	client *http.Client
+	cfg    Config
+}
+
+func NewService(client *http.Client, cfg Config) *Service {
+	return &Service{
+		client: client,
+		cfg:    cfg,
+	}
+}
+
+// Request webhook 收到的请求体
+type Request struct {
+	TplId   string   `json:"tplId"`
+	Args    []string `json:"args"`
+	Numbers []string `json:"numbers"`
+}
+
+// Response webhook 可以返回流水号，用来对上之后推送的回执，不返回也可以
+type Response struct {
+	SerialNo string `json:"serialNo"`
+}
+
+// Receipt webhook 推送回执的格式，推送的是数组
+type Receipt struct {
+	SerialNo  string `json:"serialNo"`
+	Delivered bool   `json:"delivered"`
+	Reason    string `json:"reason"`
+}
+
+func (s *Service) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	_, err := s.SendWithResult(ctx, tplId, args, numbers...)
+	return err
+}
+
+func (s *Service) SendWithResult(ctx context.Context, tplId string,
+	args []string, numbers ...string) ([]sms.SendResult, error) {
+	body, err := json.Marshal(Request{
+		TplId:   tplId,
+		Args:    args,
+		Numbers: numbers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if s.cfg.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, ts)
+		req.Header.Set(HeaderSignature, Sign(s.cfg.Secret, ts, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// 只读一部分，避免对方返回一个很大的页面
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		return nil, &sms.ResponseError{StatusCode: resp.StatusCode, Msg: string(data)}
+	}
+	var res Response
+	// 响应体不是 JSON 也算发送成功，只是没有流水号
+	_ = json.Unmarshal(data, &res)
+	results := make([]sms.SendResult, 0, len(numbers))
+	for _, n := range numbers {
+		results = append(results, sms.SendResult{Number: n, SerialNo: res.SerialNo, Success: true})
+	}
+	return results, nil
+}
+
+func (s *Service) ParseReceipts(body []byte) ([]sms.Receipt, error) {
+	var receipts []Receipt
+	if err := json.Unmarshal(body, &receipts); err != nil {
+		return nil, err
+	}
+	res := make([]sms.Receipt, 0, len(receipts))
+	for _, r := range receipts {
+		res = append(res, sms.Receipt{SerialNo: r.SerialNo, Delivered: r.Delivered, Reason: r.Reason})
+	}
+	return res, nil
+}
+
+func (s *Service) ReceiptAck() any {
+	return map[string]any{"ok": true}
+}
+
+// Sign HMAC-SHA256(secret, timestamp + "." + body) 的十六进制，带上时间戳防重放
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name    string
+		secret  string
+		handler func(t *testing.T) http.HandlerFunc
+
+		wantErr bool
+	}{
+		{
+			name:   "发送成功，带签名",
+			secret: "secret",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+					assert.Equal(t, Sign("secret", r.Header.Get(HeaderTimestamp), body),
+						r.Header.Get(HeaderSignature))
+					var req Request
+					assert.NoError(t, json.Unmarshal(body, &req))
+					assert.Equal(t, Request{
+						TplId:   "1110",
+						Args:    []string{"123456"},
+						Numbers: []string{"+8613800000000"},
+					}, req)
+					w.WriteHeader(http.StatusNoContent)
+				}
+			},
+		},
+		{
+			name: "不配置 secret 不签名",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					assert.Empty(t, r.Header.Get(HeaderSignature))
+				}
+			},
+		},
+		{
+			name: "对方返回错误",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "余额不足", http.StatusPaymentRequired)
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler(t))
+			defer server.Close()
+			svc := NewService(server.Client(), Config{
+				URL:     server.URL,
+				Secret:  tc.secret,
+				Headers: map[string]string{"Authorization": "Bearer token"},
+			})
+			err := svc.Send(context.Background(), "1110", []string{"123456"}, "+8613800000000")
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
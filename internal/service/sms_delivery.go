@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/internal/service/sms"
+)
+
+var ErrReceiptRejected = errors.New("回执 token 不对或者服务商不存在")
+
+type SMSDeliveryConfig struct {
+	// CallbackToken 配置在服务商回执地址里面，防止别人伪造回执
+	CallbackToken string
+	// Parsers 服务商名字到回执解析器，不支持回执的服务商没有
+	Parsers map[string]sms.ReceiptParser
+}
+
+type SMSDeliveryService interface {
+	// HandleReceipts 解析服务商推过来的回执并更新发送记录，返回需要响应给服务商的内容
+	HandleReceipts(ctx context.Context, provider string, token string, body []byte) (any, error)
+	// History 按手机号查发送记录，给客服用
+	History(ctx context.Context, phone string, offset int, limit int) ([]domain.SMSDelivery, error)
+}
+
+type smsDeliveryService struct {
+	repo repository.SMSDeliveryRepository
+	cfg  SMSDeliveryConfig
+}
+
+func NewSMSDeliveryService(repo repository.SMSDeliveryRepository, cfg SMSDeliveryConfig) SMSDeliveryService {
+	return &smsDeliveryService{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+func (svc *smsDeliveryService) HandleReceipts(ctx context.Context, provider string,
+	token string, body []byte) (any, error) {
+	parser, ok := svc.cfg.Parsers[provider]
+	if !ok || svc.cfg.CallbackToken == "" ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(svc.cfg.CallbackToken)) != 1 {
+		return nil, ErrReceiptRejected
+	}
+	receipts, err := parser.ParseReceipts(body)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range receipts {
+		if r.SerialNo == "" {
+			// 没有流水号对不上发送记录，不然会更新到所有没有流水号的记录
+			continue
+		}
+		status := domain.SMSDeliveryUndelivered
+		if r.Delivered {
+			status = domain.SMSDeliveryDelivered
+		}
+		err = svc.repo.UpdateStatus(ctx, provider, r.SerialNo, status, r.Reason)
+		if err != nil {
+			// 返回错误服务商会重推，更新是幂等的
+			return nil, err
+		}
+	}
+	return parser.ReceiptAck(), nil
+}
+
+func (svc *smsDeliveryService) History(ctx context.Context, phone string,
+	offset int, limit int) ([]domain.SMSDelivery, error) {
+	return svc.repo.FindByPhone(ctx, phone, offset, limit)
+}
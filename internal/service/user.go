@@ -3,33 +3,102 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/skcheng003/webook/internal/domain"
 	"github.com/skcheng003/webook/internal/repository"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 var ErrUserDuplicateEmail = repository.ErrUserDuplicate
+
+// ErrPhoneBound 和 ErrEmailBound 说明要绑定的手机号或者邮箱已经被别的账号用了
+var ErrPhoneBound = errors.New("手机号已经被其它账号绑定")
+var ErrEmailBound = errors.New("邮箱已经被其它账号绑定")
 var ErrInvalidUserOrPassword = errors.New("invalid user or password")
 var ErrUserNoFound = repository.ErrUserNoFound
+var (
+	ErrUserDisabled     = errors.New("账号已被禁用")
+	ErrInvalidRole      = errors.New("角色不存在")
+	ErrAccountLocked    = errors.New("账号已被临时锁定")
+	ErrAccountNotLocked = errors.New("账号没有被锁定")
+	ErrLoginTooFrequent = errors.New("登录失败次数太多")
+	ErrAccountNoPhone   = errors.New("账号没有绑定手机号")
+	ErrCodeInvalid      = errors.New("验证码错误")
+	ErrMFARequired      = errors.New("需要二次验证码")
+	ErrUserNotDisabled  = repository.ErrUserNotDisabled
+	ErrReauthRequired   = errors.New("更换手机号或者邮箱之前要重新认证")
+)
+
+const (
+	unlockBiz = "user/unlock"
+	rebindBiz = "user/rebind"
+)
+
+// LockoutConfig 登录失败的锁定策略
+type LockoutConfig struct {
+	// Threshold 窗口内同一个账号失败多少次就锁定账号
+	Threshold int64
+	// IPThreshold 窗口内同一个 IP 失败多少次就封禁 IP，主要是防撞库
+	IPThreshold int64
+	Window      time.Duration
+	// BaseLock 第一次锁定的时长，之后每次翻倍，最长 MaxLock
+	BaseLock time.Duration
+	MaxLock  time.Duration
+	IPLock   time.Duration
+}
 
 var _ UserService = (*userService)(nil)
 
 type UserService interface {
 	SignUp(ctx context.Context, u domain.User) error
-	Login(ctx context.Context, email string, password string) (domain.User, error)
+	// Login 开启了二次验证的账号密码正确的时候返回用户和 ErrMFARequired，要接着调用 LoginMFA
+	Login(ctx context.Context, email string, password string, ip string) (domain.User, error)
+	// LoginMFA 登录的第二步，二次验证码错误和密码错误一样计入失败次数，会触发锁定
+	LoginMFA(ctx context.Context, uid int64, code string, ip string) (domain.User, error)
+	// Reauthenticate 确认操作人能完整登录 uid 这个账号：设置了密码要校验密码，
+	// 开启了二次验证还要校验二次验证码，失败和登录一样计入失败次数
+	Reauthenticate(ctx context.Context, uid int64, cred domain.Credential) error
+	// SendUnlockCode 给被锁定账号绑定的手机发送解锁验证码
+	SendUnlockCode(ctx context.Context, email string) error
+	UnlockAccount(ctx context.Context, email string, code string) error
 	EditProfile(ctx context.Context, user domain.User) error
 	FindProfile(ctx context.Context, email string) (domain.User, error)
 	FindProfileJWT(ctx context.Context, uid int64) (domain.User, error)
 	FindOrCreate(ctx context.Context, phone string) (domain.User, error)
+	// Disable 管理员禁用账号，禁用之后不能再登录
+	Disable(ctx context.Context, uid int64) error
+	// Enable 只能解禁被禁用的账号，停用或者注销冷静期中的账号返回 ErrUserNotDisabled
+	Enable(ctx context.Context, uid int64) error
+	UpdateRoles(ctx context.Context, uid int64, roles []string) error
+	// SendRebindCode 给没有密码的账号当前的手机号发验证码，更换手机号或者邮箱的时候用来重新认证
+	SendRebindCode(ctx context.Context, uid int64) error
+	// BindPhone 绑定或者更换手机号，调用之前要先验证手机号属于当前用户。
+	// 更换的时候还要用 cred 重新认证，不然拿到 token 的人就能把账号的手机号换成自己的
+	BindPhone(ctx context.Context, uid int64, phone string, cred domain.Credential) error
+	// BindEmail 绑定或者更换邮箱，要求和 BindPhone 一样
+	BindEmail(ctx context.Context, uid int64, email string, cred domain.Credential) error
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo        repository.UserRepository
+	attemptRepo repository.LoginAttemptRepository
+	codeSvc     CodeService
+	mfaSvc      MFAService
+	emitter     SecurityEventEmitter
+	lockout     LockoutConfig
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
+func NewUserService(repo repository.UserRepository, attemptRepo repository.LoginAttemptRepository,
+	codeSvc CodeService, mfaSvc MFAService, emitter SecurityEventEmitter, lockout LockoutConfig) UserService {
 	return &userService{
-		repo: repo,
+		repo:        repo,
+		attemptRepo: attemptRepo,
+		codeSvc:     codeSvc,
+		mfaSvc:      mfaSvc,
+		emitter:     emitter,
+		lockout:     lockout,
 	}
 }
 
@@ -42,9 +111,21 @@ func (svc *userService) SignUp(ctx context.Context, u domain.User) error {
 	return svc.repo.CreateUser(ctx, u)
 }
 
-func (svc *userService) Login(ctx context.Context, email string, password string) (domain.User, error) {
+func (svc *userService) Login(ctx context.Context, email string, password string, ip string) (domain.User, error) {
+	ttl, err := svc.attemptRepo.IPLockTTL(ctx, ip)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if ttl > 0 {
+		return domain.User{}, ErrLoginTooFrequent
+	}
+
 	u, err := svc.repo.FindByEmail(ctx, email)
 	if errors.Is(err, ErrUserNoFound) {
+		// 账号不存在也要算 IP 的失败次数，不然撞库的时候统计不到
+		if err = svc.recordFailure(ctx, 0, ip); err != nil {
+			return domain.User{}, err
+		}
 		return domain.User{}, ErrUserNoFound
 	}
 
@@ -53,10 +134,224 @@ func (svc *userService) Login(ctx context.Context, email string, password string
 		return domain.User{}, err
 	}
 
+	// 锁定期间即使密码正确也不能登录，否则锁定就没有意义了
+	ttl, err = svc.attemptRepo.UserLockTTL(ctx, u.Id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	// 账号存在的时候，失败也带上 uid，方便记录审计日志
+	if ttl > 0 {
+		return domain.User{Id: u.Id}, ErrAccountLocked
+	}
+
 	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	if err != nil {
-		// TODO: add info logger here
-		return domain.User{}, ErrInvalidUserOrPassword
+		if err = svc.recordFailure(ctx, u.Id, ip); err != nil {
+			return domain.User{Id: u.Id}, err
+		}
+		return domain.User{Id: u.Id}, ErrInvalidUserOrPassword
+	}
+	mfaEnabled, err := svc.mfaSvc.IsEnabled(ctx, u.Id)
+	if err != nil {
+		return domain.User{Id: u.Id}, err
+	}
+	// 密码对了才告诉对方账号被禁用，避免泄露账号状态
+	if u.Status == domain.UserStatusDisabled {
+		return domain.User{Id: u.Id}, ErrUserDisabled
+	}
+	// 开启了二次验证的账号要等 LoginMFA 通过才清零失败次数、恢复停用的账号，
+	// 否则知道密码的人每次重新登录都能多猜几次二次验证码，还能悄悄恢复账号
+	if mfaEnabled {
+		return u, ErrMFARequired
+	}
+	svc.resetFailure(ctx, u.Id)
+	return svc.reactivate(ctx, u)
+}
+
+func (svc *userService) LoginMFA(ctx context.Context, uid int64, code string, ip string) (domain.User, error) {
+	ttl, err := svc.attemptRepo.IPLockTTL(ctx, ip)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if ttl > 0 {
+		return domain.User{}, ErrLoginTooFrequent
+	}
+	ttl, err = svc.attemptRepo.UserLockTTL(ctx, uid)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if ttl > 0 {
+		return domain.User{Id: uid}, ErrAccountLocked
+	}
+	ok, err := svc.mfaSvc.Verify(ctx, uid, code)
+	if err != nil {
+		return domain.User{Id: uid}, err
+	}
+	if !ok {
+		if err = svc.recordFailure(ctx, uid, ip); err != nil {
+			return domain.User{Id: uid}, err
+		}
+		return domain.User{Id: uid}, ErrMFAInvalidCode
+	}
+	svc.resetFailure(ctx, uid)
+	// 拿最新的用户信息，角色要放进 token 里面
+	u, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return domain.User{Id: uid}, err
+	}
+	if !u.Status.CanLogin() {
+		return domain.User{Id: uid}, ErrUserDisabled
+	}
+	return svc.reactivate(ctx, u)
+}
+
+func (svc *userService) Reauthenticate(ctx context.Context, uid int64, cred domain.Credential) error {
+	ttl, err := svc.attemptRepo.IPLockTTL(ctx, cred.IP)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		return ErrLoginTooFrequent
+	}
+	ttl, err = svc.attemptRepo.UserLockTTL(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		return ErrAccountLocked
+	}
+	u, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	// 短信登录自动注册的账号没有密码，手机验证码就是它的登录方式
+	if u.Password != "" {
+		err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(cred.Password))
+		if err != nil {
+			if err = svc.recordFailure(ctx, uid, cred.IP); err != nil {
+				return err
+			}
+			return ErrInvalidUserOrPassword
+		}
+	}
+	mfaEnabled, err := svc.mfaSvc.IsEnabled(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if mfaEnabled {
+		if cred.MFACode == "" {
+			return ErrMFARequired
+		}
+		ok, err := svc.mfaSvc.Verify(ctx, uid, cred.MFACode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err = svc.recordFailure(ctx, uid, cred.IP); err != nil {
+				return err
+			}
+			return ErrMFAInvalidCode
+		}
+	}
+	svc.resetFailure(ctx, uid)
+	return nil
+}
+
+func (svc *userService) resetFailure(ctx context.Context, uid int64) {
+	err := svc.attemptRepo.ResetFailure(ctx, uid)
+	if err != nil {
+		// 不影响登录，最多就是失败次数多算了几次
+		zap.L().Warn("重置登录失败次数失败", zap.Int64("uid", uid), zap.Error(err))
+	}
+}
+
+// reactivate 停用的账号重新登录就自动恢复
+func (svc *userService) reactivate(ctx context.Context, u domain.User) (domain.User, error) {
+	if u.Status != domain.UserStatusDeactivated {
+		return u, nil
+	}
+	err := svc.repo.UpdateStatus(ctx, u.Id, domain.UserStatusActive)
+	if err != nil {
+		return domain.User{Id: u.Id}, err
+	}
+	u.Status = domain.UserStatusActive
+	return u, nil
+}
+
+// recordFailure 累加失败次数，达到阈值的时候锁定账号或者 IP
+func (svc *userService) recordFailure(ctx context.Context, uid int64, ip string) error {
+	uidCnt, ipCnt, err := svc.attemptRepo.IncrFailure(ctx, uid, ip, svc.lockout.Window)
+	if err != nil {
+		return err
+	}
+	if ipCnt >= svc.lockout.IPThreshold {
+		err = svc.attemptRepo.LockIP(ctx, ip, svc.lockout.IPLock)
+		if err != nil {
+			return err
+		}
+		svc.emitter.Emit(ctx, domain.SecurityEvent{
+			Type:   domain.SecurityEventIPLocked,
+			Uid:    uid,
+			IP:     ip,
+			Detail: fmt.Sprintf("%d 次登录失败，封禁 %s", ipCnt, svc.lockout.IPLock),
+			Time:   time.Now(),
+		})
+		return ErrLoginTooFrequent
+	}
+	if uid != 0 && uidCnt >= svc.lockout.Threshold {
+		duration, err := svc.attemptRepo.LockUser(ctx, uid, svc.lockout.BaseLock, svc.lockout.MaxLock)
+		if err != nil {
+			return err
+		}
+		svc.emitter.Emit(ctx, domain.SecurityEvent{
+			Type:   domain.SecurityEventAccountLocked,
+			Uid:    uid,
+			IP:     ip,
+			Detail: fmt.Sprintf("%d 次密码或者二次验证码错误，锁定 %s", uidCnt, duration),
+			Time:   time.Now(),
+		})
+		return ErrAccountLocked
+	}
+	return nil
+}
+
+func (svc *userService) SendUnlockCode(ctx context.Context, email string) error {
+	u, err := svc.findLocked(ctx, email)
+	if err != nil {
+		return err
+	}
+	return svc.codeSvc.Send(ctx, unlockBiz, u.Phone)
+}
+
+func (svc *userService) UnlockAccount(ctx context.Context, email string, code string) error {
+	u, err := svc.findLocked(ctx, email)
+	if err != nil {
+		return err
+	}
+	ok, err := svc.codeSvc.Verify(ctx, unlockBiz, u.Phone, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCodeInvalid
+	}
+	return svc.attemptRepo.UnlockUser(ctx, u.Id)
+}
+
+func (svc *userService) findLocked(ctx context.Context, email string) (domain.User, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return domain.User{}, err
+	}
+	ttl, err := svc.attemptRepo.UserLockTTL(ctx, u.Id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if ttl <= 0 {
+		return domain.User{}, ErrAccountNotLocked
+	}
+	if u.Phone == "" {
+		return domain.User{}, ErrAccountNoPhone
 	}
 	return u, nil
 }
@@ -76,6 +371,12 @@ func (svc *userService) FindProfileJWT(ctx context.Context, uid int64) (domain.U
 
 func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.User, error) {
 	u, err := svc.repo.FindByPhone(ctx, phone)
+	if err == nil && u.Status == domain.UserStatusDisabled {
+		return domain.User{Id: u.Id}, ErrUserDisabled
+	}
+	if err == nil {
+		return svc.reactivate(ctx, u)
+	}
 	if !errors.Is(err, repository.ErrUserNoFound) {
 		// err == nil 和 err != ErrUserNotFound 都会进入这个分支
 		// 快路径
@@ -93,3 +394,91 @@ func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.
 	// 存在主从延迟问题
 	return svc.repo.FindByPhone(ctx, phone)
 }
+
+func (svc *userService) Disable(ctx context.Context, uid int64) error {
+	return svc.repo.UpdateStatus(ctx, uid, domain.UserStatusDisabled)
+}
+
+func (svc *userService) Enable(ctx context.Context, uid int64) error {
+	return svc.repo.Enable(ctx, uid)
+}
+
+func (svc *userService) UpdateRoles(ctx context.Context, uid int64, roles []string) error {
+	for _, role := range roles {
+		if !domain.IsValidRole(role) {
+			return fmt.Errorf("%w: %s", ErrInvalidRole, role)
+		}
+	}
+	_, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return svc.repo.UpdateRoles(ctx, uid, roles)
+}
+
+func (svc *userService) SendRebindCode(ctx context.Context, uid int64) error {
+	u, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Phone == "" {
+		return ErrAccountNoPhone
+	}
+	return svc.codeSvc.Send(ctx, rebindBiz, u.Phone)
+}
+
+func (svc *userService) BindPhone(ctx context.Context, uid int64, phone string, cred domain.Credential) error {
+	u, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Phone != "" && u.Phone != phone {
+		if err = svc.reauthenticateRebind(ctx, u, cred); err != nil {
+			return err
+		}
+	}
+	err = svc.repo.UpdatePhone(ctx, uid, phone)
+	if errors.Is(err, repository.ErrUserDuplicate) {
+		return ErrPhoneBound
+	}
+	return err
+}
+
+func (svc *userService) BindEmail(ctx context.Context, uid int64, email string, cred domain.Credential) error {
+	u, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Email != "" && u.Email != email {
+		if err = svc.reauthenticateRebind(ctx, u, cred); err != nil {
+			return err
+		}
+	}
+	err = svc.repo.UpdateEmail(ctx, uid, email)
+	if errors.Is(err, repository.ErrUserDuplicate) {
+		return ErrEmailBound
+	}
+	return err
+}
+
+// reauthenticateRebind 有密码的账号验证密码，没有密码的账号验证发到当前手机号的验证码，
+// 开启了二次验证的还要二次验证码，这部分和失败计数交给 Reauthenticate
+func (svc *userService) reauthenticateRebind(ctx context.Context, u domain.User, cred domain.Credential) error {
+	if u.Password != "" {
+		if cred.Password == "" {
+			return ErrReauthRequired
+		}
+		return svc.Reauthenticate(ctx, u.Id, cred)
+	}
+	if u.Phone == "" || cred.Code == "" {
+		return ErrReauthRequired
+	}
+	ok, err := svc.codeSvc.Verify(ctx, rebindBiz, u.Phone, cred.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCodeInvalid
+	}
+	return svc.Reauthenticate(ctx, u.Id, cred)
+}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	repomocks "github.com/skcheng003/webook/internal/repository/mocks"
+	svcmocks "github.com/skcheng003/webook/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+var testLockout = LockoutConfig{
+	Threshold:   5,
+	IPThreshold: 20,
+	Window:      time.Minute * 15,
+	BaseLock:    time.Minute * 15,
+	MaxLock:     time.Hour * 24,
+	IPLock:      time.Hour,
+}
+
+func TestUserService_Login(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hello#world123"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService)
+
+		wantErr error
+	}{
+		{
+			name: "没有开启二次验证，密码正确清零失败次数",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Password: string(hash)}, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().ResetFailure(gomock.Any(), int64(123)).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(123)).Return(false, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+		},
+		{
+			name: "开启了二次验证，密码正确也不清零失败次数",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Password: string(hash)}, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(123)).Return(true, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+			wantErr: ErrMFARequired,
+		},
+		{
+			name: "停用的账号没有开启二次验证，密码正确就恢复",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Password: string(hash), Status: domain.UserStatusDeactivated}, nil)
+				repo.EXPECT().UpdateStatus(gomock.Any(), int64(123), domain.UserStatusActive).Return(nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().ResetFailure(gomock.Any(), int64(123)).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(123)).Return(false, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+		},
+		{
+			name: "停用的账号开启了二次验证，只有密码不恢复",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Password: string(hash), Status: domain.UserStatusDeactivated}, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(123)).Return(true, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+			wantErr: ErrMFARequired,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, attemptRepo, mfaSvc := tc.mock(ctrl)
+			svc := NewUserService(repo, attemptRepo, nil, mfaSvc, NewLogSecurityEventEmitter(), testLockout)
+			_, err := svc.Login(context.Background(), "123@qq.com", "hello#world123", "10.0.0.1")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestUserService_LoginMFA(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService)
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "验证通过",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().ResetFailure(gomock.Any(), int64(123)).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(true, nil)
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Roles: []string{domain.RoleUser}}, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+			wantUser: domain.User{Id: 123, Roles: []string{domain.RoleUser}},
+		},
+		{
+			name: "验证通过之后恢复停用的账号",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().ResetFailure(gomock.Any(), int64(123)).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(true, nil)
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Status: domain.UserStatusDeactivated}, nil)
+				repo.EXPECT().UpdateStatus(gomock.Any(), int64(123), domain.UserStatusActive).Return(nil)
+				return repo, attemptRepo, mfaSvc
+			},
+			wantUser: domain.User{Id: 123, Status: domain.UserStatusActive},
+		},
+		{
+			name: "验证码错误计入失败次数",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().IncrFailure(gomock.Any(), int64(123), "10.0.0.1", testLockout.Window).
+					Return(int64(1), int64(1), nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(false, nil)
+				return repomocks.NewMockUserRepository(ctrl), attemptRepo, mfaSvc
+			},
+			wantUser: domain.User{Id: 123},
+			wantErr:  ErrMFAInvalidCode,
+		},
+		{
+			name: "验证码错误太多次锁定账号",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().IncrFailure(gomock.Any(), int64(123), "10.0.0.1", testLockout.Window).
+					Return(int64(5), int64(5), nil)
+				attemptRepo.EXPECT().LockUser(gomock.Any(), int64(123), testLockout.BaseLock, testLockout.MaxLock).
+					Return(testLockout.BaseLock, nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(false, nil)
+				return repomocks.NewMockUserRepository(ctrl), attemptRepo, mfaSvc
+			},
+			wantUser: domain.User{Id: 123},
+			wantErr:  ErrAccountLocked,
+		},
+		{
+			name: "账号已经锁定，不再校验验证码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Minute, nil)
+				return repomocks.NewMockUserRepository(ctrl), attemptRepo, svcmocks.NewMockMFAService(ctrl)
+			},
+			wantUser: domain.User{Id: 123},
+			wantErr:  ErrAccountLocked,
+		},
+		{
+			name: "账号已经被禁用",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().ResetFailure(gomock.Any(), int64(123)).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(true, nil)
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Status: domain.UserStatusDisabled}, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+			wantUser: domain.User{Id: 123},
+			wantErr:  ErrUserDisabled,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, attemptRepo, mfaSvc := tc.mock(ctrl)
+			svc := NewUserService(repo, attemptRepo, nil, mfaSvc, NewLogSecurityEventEmitter(), testLockout)
+			u, err := svc.LoginMFA(context.Background(), 123, "123456", "10.0.0.1")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func TestUserService_Reauthenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hello#world123"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService)
+		cred domain.Credential
+
+		wantErr error
+	}{
+		{
+			name: "密码和二次验证码都正确",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(456)).
+					Return(domain.User{Id: 456, Password: string(hash)}, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(456)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().ResetFailure(gomock.Any(), int64(456)).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(456)).Return(true, nil)
+				mfaSvc.EXPECT().Verify(gomock.Any(), int64(456), "123456").Return(true, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+			cred: domain.Credential{Password: "hello#world123", MFACode: "123456", IP: "10.0.0.1"},
+		},
+		{
+			name: "密码错误计入失败次数",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(456)).
+					Return(domain.User{Id: 456, Password: string(hash)}, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(456)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().IncrFailure(gomock.Any(), int64(456), "10.0.0.1", testLockout.Window).
+					Return(int64(1), int64(1), nil)
+				return repo, attemptRepo, svcmocks.NewMockMFAService(ctrl)
+			},
+			cred:    domain.Credential{Password: "wrong", IP: "10.0.0.1"},
+			wantErr: ErrInvalidUserOrPassword,
+		},
+		{
+			name: "开启了二次验证没有带验证码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(456)).
+					Return(domain.User{Id: 456, Password: string(hash)}, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(456)).Return(time.Duration(0), nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(456)).Return(true, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+			cred:    domain.Credential{Password: "hello#world123", IP: "10.0.0.1"},
+			wantErr: ErrMFARequired,
+		},
+		{
+			name: "短信注册的账号没有密码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(456)).
+					Return(domain.User{Id: 456, Phone: "+8613800138000"}, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(456)).Return(time.Duration(0), nil)
+				attemptRepo.EXPECT().ResetFailure(gomock.Any(), int64(456)).Return(nil)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(456)).Return(false, nil)
+				return repo, attemptRepo, mfaSvc
+			},
+			cred: domain.Credential{IP: "10.0.0.1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, attemptRepo, mfaSvc := tc.mock(ctrl)
+			svc := NewUserService(repo, attemptRepo, nil, mfaSvc, NewLogSecurityEventEmitter(), testLockout)
+			err := svc.Reauthenticate(context.Background(), 456, tc.cred)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestUserService_BindPhone(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hello#world123"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	// 重新认证通过的时候 Reauthenticate 要查的东西
+	reauthOK := func(repo *repomocks.MockUserRepository, attemptRepo *repomocks.MockLoginAttemptRepository,
+		mfaSvc *svcmocks.MockMFAService, u domain.User) {
+		repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(u, nil)
+		attemptRepo.EXPECT().IPLockTTL(gomock.Any(), "10.0.0.1").Return(time.Duration(0), nil)
+		attemptRepo.EXPECT().UserLockTTL(gomock.Any(), int64(123)).Return(time.Duration(0), nil)
+		attemptRepo.EXPECT().ResetFailure(gomock.Any(), int64(123)).Return(nil)
+		mfaSvc.EXPECT().IsEnabled(gomock.Any(), int64(123)).Return(false, nil)
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository,
+			CodeService, MFAService)
+		cred domain.Credential
+
+		wantErr error
+	}{
+		{
+			name: "第一次绑定不用重新认证",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository,
+				CodeService, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com", Password: string(hash)}, nil)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(123), "+8613900139000").Return(nil)
+				return repo, repomocks.NewMockLoginAttemptRepository(ctrl),
+					svcmocks.NewMockCodeService(ctrl), svcmocks.NewMockMFAService(ctrl)
+			},
+			cred: domain.Credential{IP: "10.0.0.1"},
+		},
+		{
+			name: "更换手机号没有带密码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository,
+				CodeService, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Phone: "+8613800138000", Password: string(hash)}, nil)
+				return repo, repomocks.NewMockLoginAttemptRepository(ctrl),
+					svcmocks.NewMockCodeService(ctrl), svcmocks.NewMockMFAService(ctrl)
+			},
+			cred:    domain.Credential{IP: "10.0.0.1"},
+			wantErr: ErrReauthRequired,
+		},
+		{
+			name: "更换手机号带了正确的密码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository,
+				CodeService, MFAService) {
+				u := domain.User{Id: 123, Phone: "+8613800138000", Password: string(hash)}
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(u, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				reauthOK(repo, attemptRepo, mfaSvc, u)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(123), "+8613900139000").Return(nil)
+				return repo, attemptRepo, svcmocks.NewMockCodeService(ctrl), mfaSvc
+			},
+			cred: domain.Credential{Password: "hello#world123", IP: "10.0.0.1"},
+		},
+		{
+			name: "没有密码的账号用当前手机号的验证码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository,
+				CodeService, MFAService) {
+				u := domain.User{Id: 123, Phone: "+8613800138000"}
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(u, nil)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "user/rebind", "+8613800138000", "111111").Return(true, nil)
+				attemptRepo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				mfaSvc := svcmocks.NewMockMFAService(ctrl)
+				reauthOK(repo, attemptRepo, mfaSvc, u)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(123), "+8613900139000").Return(nil)
+				return repo, attemptRepo, codeSvc, mfaSvc
+			},
+			cred: domain.Credential{Code: "111111", IP: "10.0.0.1"},
+		},
+		{
+			name: "没有密码的账号验证码错误",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository,
+				CodeService, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Phone: "+8613800138000"}, nil)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "user/rebind", "+8613800138000", "000000").Return(false, nil)
+				return repo, repomocks.NewMockLoginAttemptRepository(ctrl), codeSvc, svcmocks.NewMockMFAService(ctrl)
+			},
+			cred:    domain.Credential{Code: "000000", IP: "10.0.0.1"},
+			wantErr: ErrCodeInvalid,
+		},
+		{
+			name: "没有密码的账号只带了二次验证码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.LoginAttemptRepository,
+				CodeService, MFAService) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Phone: "+8613800138000"}, nil)
+				return repo, repomocks.NewMockLoginAttemptRepository(ctrl),
+					svcmocks.NewMockCodeService(ctrl), svcmocks.NewMockMFAService(ctrl)
+			},
+			cred:    domain.Credential{MFACode: "123456", IP: "10.0.0.1"},
+			wantErr: ErrReauthRequired,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, attemptRepo, codeSvc, mfaSvc := tc.mock(ctrl)
+			svc := NewUserService(repo, attemptRepo, codeSvc, mfaSvc, NewLogSecurityEventEmitter(), testLockout)
+			err := svc.BindPhone(context.Background(), 123, "+8613900139000", tc.cred)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
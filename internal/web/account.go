@@ -0,0 +1,64 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"net/http"
+)
+
+// AccountHandler 账号停用和注销
+type AccountHandler struct {
+	svc service.AccountService
+}
+
+func NewAccountHandler(svc service.AccountService) *AccountHandler {
+	return &AccountHandler{
+		svc: svc,
+	}
+}
+
+func (a *AccountHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users")
+	ginx.Handle(ug, http.MethodPost, "/deactivate", a.Deactivate,
+		openapi.Operation{Summary: "停用账号，重新登录就恢复"})
+	ginx.Handle(ug, http.MethodPost, "/delete", a.RequestDeletion,
+		openapi.Operation{Summary: "申请注销账号，冷静期之后删除"})
+	ginx.Handle(ug, http.MethodPost, "/delete/cancel", a.CancelDeletion,
+		openapi.Operation{Summary: "撤销注销申请"})
+}
+
+// Deactivate 停用之后所有设备都会退出，重新登录就恢复
+func (a *AccountHandler) Deactivate(ctx *gin.Context) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	err := a.svc.Deactivate(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	ctx.Header("X-Access-Token", "")
+	ctx.Header("X-Refresh-Token", "")
+	return ginx.OK(msgDeactivated), nil
+}
+
+// RequestDeletion 冷静期内重新登录，调用 CancelDeletion 就可以撤销
+func (a *AccountHandler) RequestDeletion(ctx *gin.Context) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	deleteAfter, err := a.svc.RequestDeletion(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	ctx.Header("X-Access-Token", "")
+	ctx.Header("X-Refresh-Token", "")
+	return ginx.OK(msgDeletionRequested).WithData(gin.H{"deleteAfter": deleteAfter.UnixMilli()}), nil
+}
+
+func (a *AccountHandler) CancelDeletion(ctx *gin.Context) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	err := a.svc.CancelDeletion(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgDeletionCanceled), nil
+}
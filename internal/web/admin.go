@@ -0,0 +1,240 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/internal/web/middleware"
+	"github.com/skcheng003/webook/pkg/circuitbreaker"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"github.com/skcheng003/webook/pkg/ratelimit"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// AdminHandler 管理后台接口，所有路由都要求对应的权限
+type AdminHandler struct {
+	userSvc    service.UserService
+	articleSvc service.ArticleService
+	limiter    ratelimit.Limiter
+	jwt.Handler
+}
+
+func NewAdminHandler(userSvc service.UserService, articleSvc service.ArticleService,
+	limiter ratelimit.Limiter, jwtHdl jwt.Handler) *AdminHandler {
+	return &AdminHandler{
+		userSvc:    userSvc,
+		articleSvc: articleSvc,
+		limiter:    limiter,
+		Handler:    jwtHdl,
+	}
+}
+
+func (a *AdminHandler) RegisterRoutes(server *gin.Engine) {
+	ag := server.Group("/admin")
+	ginx.Handle(a.require(ag, domain.PermUserRead), http.MethodGet, "/users/:id", a.UserDetail,
+		openapi.Operation{Summary: "查看用户", Response: AdminUserVO{}})
+	ginx.Handle(a.require(ag, domain.PermUserManage), http.MethodPost, "/users/:id/disable", a.DisableUser,
+		openapi.Operation{Summary: "禁用用户，所有设备都会退出"})
+	ginx.Handle(a.require(ag, domain.PermUserManage), http.MethodPost, "/users/:id/enable", a.EnableUser,
+		openapi.Operation{Summary: "解除禁用"})
+	ginx.HandleBody(a.require(ag, domain.PermRoleManage), http.MethodPost, "/users/:id/roles", a.UpdateRoles,
+		openapi.Operation{Summary: "修改用户的角色"})
+	ginx.Handle(a.require(ag, domain.PermArticleModerate), http.MethodPost, "/articles/:id/unpublish",
+		a.UnpublishArticle, openapi.Operation{Summary: "下架文章"})
+	ginx.HandleBody(a.require(ag, domain.PermRateLimitReset), http.MethodPost, "/ratelimit/reset",
+		a.ResetRateLimit, openapi.Operation{Summary: "清空某个限流器的记录"})
+	// 熔断器的状态，格式和 expvar 一样，不是统一的 Result
+	a.require(ag, domain.PermMetricsRead).GET("/debug/vars", gin.WrapH(circuitbreaker.ExpvarHandler()))
+}
+
+// require 返回一个要求 perm 权限的路由组，路径和 g 一样
+func (a *AdminHandler) require(g *gin.RouterGroup, perm domain.Permission) *gin.RouterGroup {
+	return g.Group("", middleware.NewPermissionMiddlewareBuilder(perm).Build())
+}
+
+type AdminUserVO struct {
+	Id       int64    `json:"id"`
+	Email    string   `json:"email"`
+	Phone    string   `json:"phone"`
+	Nickname string   `json:"nickname"`
+	Roles    []string `json:"roles"`
+	Disabled bool     `json:"disabled"`
+}
+
+func (a *AdminHandler) UserDetail(ctx *gin.Context) (ginx.Result, error) {
+	uid, err := a.pathId(ctx)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	u, err := a.userSvc.FindProfileJWT(ctx, uid)
+	if errors.Is(err, service.ErrUserNoFound) {
+		return ginx.Result{}, errUserNotFound
+	}
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.Result{
+		Data: AdminUserVO{
+			Id:       u.Id,
+			Email:    u.Email,
+			Phone:    u.Phone,
+			Nickname: u.Nickname,
+			Roles:    u.Roles,
+			Disabled: u.Status == domain.UserStatusDisabled,
+		},
+	}, nil
+}
+
+// DisableUser 禁用之后立刻踢掉所有设备，已经签发的 access token 也会因为 session 被吊销而失效
+func (a *AdminHandler) DisableUser(ctx *gin.Context) (ginx.Result, error) {
+	uid, err := a.pathId(ctx)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	err = a.userSvc.Disable(ctx, uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	err = a.RevokeAllSessions(ctx, uid)
+	if err != nil {
+		// 账号已经禁用了，剩下的 session 刷新 token 的时候也会被拒绝
+		zap.L().Warn("禁用账号时吊销 session 失败", zap.Int64("uid", uid), zap.Error(err))
+	}
+	a.audit(ctx, "disable_user", zap.Int64("target", uid))
+	return ginx.OK(ginx.MsgOK), nil
+}
+
+func (a *AdminHandler) EnableUser(ctx *gin.Context) (ginx.Result, error) {
+	uid, err := a.pathId(ctx)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	err = a.userSvc.Enable(ctx, uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	a.audit(ctx, "enable_user", zap.Int64("target", uid))
+	return ginx.OK(ginx.MsgOK), nil
+}
+
+type RolesReq struct {
+	Roles []string `json:"roles"`
+}
+
+// UpdateRoles 覆盖式更新，新角色在用户下一次刷新 token 之后生效
+func (a *AdminHandler) UpdateRoles(ctx *gin.Context, req RolesReq) (ginx.Result, error) {
+	uid, err := a.pathId(ctx)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	err = a.userSvc.UpdateRoles(ctx, uid, req.Roles)
+	if errors.Is(err, service.ErrUserNoFound) {
+		return ginx.Result{}, errUserNotFound
+	}
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	a.audit(ctx, "update_roles", zap.Int64("target", uid), zap.Strings("roles", req.Roles))
+	return ginx.OK(ginx.MsgOK), nil
+}
+
+func (a *AdminHandler) UnpublishArticle(ctx *gin.Context) (ginx.Result, error) {
+	id, err := a.pathId(ctx)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	err = a.articleSvc.Unpublish(ctx, id)
+	if errors.Is(err, service.ErrArticleNoFound) {
+		return ginx.Result{}, errArticleNotFound
+	}
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	a.audit(ctx, "unpublish_article", zap.Int64("article", id))
+	return ginx.OK(ginx.MsgOK), nil
+}
+
+// rateLimitTarget 可以在后台重置的限流器。redis key 由服务端拼出来，
+// 不能让调用方直接传 key，不然可以删掉任意的 key，比如已经退出的 session 的标记
+type rateLimitTarget struct {
+	// key 限流器只有一个 key 的时候用，这时候 subject 必须为空
+	key    string
+	prefix string
+	// subject 校验调用方传的 subject
+	subject func(s string) bool
+}
+
+var (
+	bizPattern       = regexp.MustCompile(`^[a-z0-9_\-]{1,64}$`)
+	rateLimitTargets = map[string]rateLimitTarget{
+		"sms":         {key: "tencent_sms"},
+		"sms_gateway": {prefix: "sms_gateway:", subject: bizPattern.MatchString},
+		"ip":          {prefix: "ip-limiter:", subject: isIP},
+		"grpc":        {prefix: "grpc-limiter:", subject: isIP},
+	}
+)
+
+func isIP(s string) bool {
+	return net.ParseIP(s) != nil
+}
+
+// rateLimitKey 限流器不存在或者 subject 不合法返回 false
+func rateLimitKey(limiter string, subject string) (string, bool) {
+	target, ok := rateLimitTargets[limiter]
+	if !ok {
+		return "", false
+	}
+	if target.key != "" {
+		if subject != "" {
+			return "", false
+		}
+		return target.key, true
+	}
+	if !target.subject(subject) {
+		return "", false
+	}
+	return target.prefix + subject, true
+}
+
+type RateLimitReq struct {
+	// Limiter sms / sms_gateway / ip / grpc
+	Limiter string `json:"limiter" binding:"required"`
+	// Subject sms_gateway 是业务方，ip 和 grpc 是 IP，sms 不用传
+	Subject string `json:"subject"`
+}
+
+// ResetRateLimit 被误伤的时候管理员手动放行
+func (a *AdminHandler) ResetRateLimit(ctx *gin.Context, req RateLimitReq) (ginx.Result, error) {
+	key, ok := rateLimitKey(req.Limiter, req.Subject)
+	if !ok {
+		return ginx.Result{}, ginx.ErrInvalidInput
+	}
+	err := a.limiter.Reset(ctx, key)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	a.audit(ctx, "reset_ratelimit", zap.String("limiter", req.Limiter), zap.String("subject", req.Subject))
+	return ginx.OK(ginx.MsgOK), nil
+}
+
+func (a *AdminHandler) pathId(ctx *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ginx.ErrInvalidInput
+	}
+	return id, nil
+}
+
+// audit 管理员的操作都记一条日志，方便事后追查
+func (a *AdminHandler) audit(ctx *gin.Context, action string, fields ...zap.Field) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	fields = append(fields, zap.String("action", action), zap.Int64("operator", uc.Uid))
+	zap.L().Info("admin_action", fields...)
+}
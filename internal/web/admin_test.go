@@ -0,0 +1,32 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRateLimitKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		limiter string
+		subject string
+
+		wantKey string
+		wantOK  bool
+	}{
+		{name: "短信", limiter: "sms", wantKey: "tencent_sms", wantOK: true},
+		{name: "短信不能带 subject", limiter: "sms", subject: "x"},
+		{name: "短信网关", limiter: "sms_gateway", subject: "marketing", wantKey: "sms_gateway:marketing", wantOK: true},
+		{name: "IP", limiter: "ip", subject: "10.0.0.1", wantKey: "ip-limiter:10.0.0.1", wantOK: true},
+		{name: "IP 不合法", limiter: "ip", subject: "10.0.0.1:*"},
+		{name: "不能拼出其它 key", limiter: "sms_gateway", subject: "x:user:ssid:abc"},
+		{name: "限流器不存在", limiter: "user:ssid", subject: "abc"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, ok := rateLimitKey(tc.limiter, tc.subject)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantKey, key)
+		})
+	}
+}
@@ -1,9 +1,15 @@
 package web
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/domain"
 	"github.com/skcheng003/webook/internal/service"
 	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"net/http"
+	"strconv"
 )
 
 type ArticleHandler struct {
@@ -18,19 +24,94 @@ func NewArticleHandler(svc service.ArticleService) *ArticleHandler {
 
 func (hdl *ArticleHandler) RegisterRoutes(server *gin.Engine) {
 	ug := server.Group("/articles")
-	ug.POST("/edit", hdl.Edit)
+	ginx.HandleBody(ug, http.MethodPost, "/edit", hdl.Edit,
+		openapi.Operation{Summary: "保存草稿，id 为 0 表示新建，返回文章 id", Response: int64(0)})
+	ginx.HandleBody(ug, http.MethodPost, "/publish", hdl.Publish,
+		openapi.Operation{Summary: "发表文章，返回文章 id", Response: int64(0)})
+	ginx.HandleBody(ug, http.MethodPost, "/withdraw", hdl.Withdraw,
+		openapi.Operation{Summary: "撤回文章，只有作者自己可以撤回"})
+	ginx.Handle(ug, http.MethodGet, "/pub/:id", hdl.PubDetail,
+		openapi.Operation{Summary: "查看已发表的文章", Response: ArticleVO{}})
 }
 
-func (hdl *ArticleHandler) Edit(ctx *gin.Context) {
-	type Req struct {
-		Id      int64
-		Title   string `json:"title"`
-		Content string `json:"content"`
+type ArticleReq struct {
+	Id      int64  `json:"id" binding:"min=0"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+func (req ArticleReq) toDomain(uid int64) domain.Article {
+	return domain.Article{
+		Id:      req.Id,
+		Title:   req.Title,
+		Content: req.Content,
+		Author: domain.Author{
+			Id: uid,
+		},
+	}
+}
+
+// Edit 保存草稿
+func (hdl *ArticleHandler) Edit(ctx *gin.Context, req ArticleReq) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	id, err := hdl.svc.Save(ctx, req.toDomain(uc.Uid))
+	if err != nil {
+		return ginx.Result{}, err
 	}
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		return
+	return ginx.Result{Data: id}, nil
+}
+
+func (hdl *ArticleHandler) Publish(ctx *gin.Context, req ArticleReq) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	id, err := hdl.svc.Publish(ctx, req.toDomain(uc.Uid))
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.Result{Data: id}, nil
+}
+
+type WithdrawReq struct {
+	Id int64 `json:"id" binding:"required"`
+}
+
+type ArticleVO struct {
+	Id       int64  `json:"id"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	AuthorId int64  `json:"authorId"`
+	Ctime    int64  `json:"ctime"`
+	Utime    int64  `json:"utime"`
+}
+
+func (hdl *ArticleHandler) Withdraw(ctx *gin.Context, req WithdrawReq) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	err := hdl.svc.Withdraw(ctx, uc.Uid, req.Id)
+	if err != nil {
+		return ginx.Result{}, err
 	}
-	uc := ctx.MustGet("user").(jwt.UserClaims)
+	return ginx.OK(ginx.MsgOK), nil
+}
 
+func (hdl *ArticleHandler) PubDetail(ctx *gin.Context) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{}, ginx.ErrInvalidInput
+	}
+	art, err := hdl.svc.GetPubById(ctx, id)
+	if errors.Is(err, service.ErrArticleNoFound) {
+		return ginx.Result{}, errArticleNotFound
+	}
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.Result{
+		Data: ArticleVO{
+			Id:       art.Id,
+			Title:    art.Title,
+			Content:  art.Content,
+			AuthorId: art.Author.Id,
+			Ctime:    art.CreateTime.UnixMilli(),
+			Utime:    art.UpdateTime.UnixMilli(),
+		},
+	}, nil
 }
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"encoding/base64"
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"net/http"
+)
+
+type CaptchaHandler struct {
+	svc service.CaptchaService
+}
+
+func NewCaptchaHandler(svc service.CaptchaService) *CaptchaHandler {
+	return &CaptchaHandler{
+		svc: svc,
+	}
+}
+
+func (h *CaptchaHandler) RegisterRoutes(server *gin.Engine) {
+	ginx.Handle(server.Group("/captcha"), http.MethodGet, "", h.Generate, openapi.Operation{
+		Summary:  "获取图形验证码，接口返回 428 之后带上 X-Captcha-Id 和 X-Captcha-Answer 重试",
+		Public:   true,
+		Response: CaptchaVO{},
+	})
+}
+
+type CaptchaVO struct {
+	Id string `json:"id"`
+	// Image data URL，可以直接放到 img 的 src 里面
+	Image string `json:"image"`
+}
+
+func (h *CaptchaHandler) Generate(ctx *gin.Context) (ginx.Result, error) {
+	id, img, err := h.svc.Generate(ctx)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.Result{Data: CaptchaVO{
+		Id:    id,
+		Image: "data:image/png;base64," + base64.StdEncoding.EncodeToString(img),
+	}}, nil
+}
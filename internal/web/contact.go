@@ -0,0 +1,184 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"net/http"
+)
+
+const (
+	bindPhoneBiz  = "user/bind_phone"
+	bindEmailBiz  = "user/bind_email"
+	mergePhoneBiz = "user/merge_phone"
+	mergeEmailBiz = "user/merge_email"
+)
+
+// ContactHandler 绑定和更换手机号、邮箱。
+// 邮箱注册的用户可以补绑手机号，短信登录的用户可以补绑邮箱。
+// 如果手机号已经单独注册过账号，可以通过验证码证明是本人，把两个账号合并
+type ContactHandler struct {
+	svc          service.UserService
+	accountSvc   service.AccountService
+	smsCodeSvc   service.CodeService
+	emailCodeSvc service.EmailCodeService
+}
+
+func NewContactHandler(svc service.UserService, accountSvc service.AccountService,
+	smsCodeSvc service.CodeService, emailCodeSvc service.EmailCodeService) *ContactHandler {
+	return &ContactHandler{
+		svc:          svc,
+		accountSvc:   accountSvc,
+		smsCodeSvc:   smsCodeSvc,
+		emailCodeSvc: emailCodeSvc,
+	}
+}
+
+func (c *ContactHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users")
+	ginx.HandleBody(ug, http.MethodPost, "/phone/code/send", c.SendPhoneCode,
+		openapi.Operation{Summary: "发送绑定手机号的验证码"})
+	ginx.HandleBody(ug, http.MethodPost, "/phone/bind", c.BindPhone,
+		openapi.Operation{Summary: "绑定或者更换手机号"})
+	ginx.Handle(ug, http.MethodPost, "/rebind/code/send", c.SendRebindCode,
+		openapi.Operation{Summary: "没有密码的账号更换手机号或者邮箱之前，给当前手机号发验证码"})
+	ginx.HandleBody(ug, http.MethodPost, "/email/code/send", c.SendEmailCode,
+		openapi.Operation{Summary: "发送绑定邮箱的验证码"})
+	ginx.HandleBody(ug, http.MethodPost, "/email/bind", c.BindEmail,
+		openapi.Operation{Summary: "绑定或者更换邮箱"})
+	ginx.HandleBody(ug, http.MethodPost, "/merge/code/send", c.SendMergeCode,
+		openapi.Operation{Summary: "发送合并账号的验证码，phone 和 email 二选一"})
+	ginx.HandleBody(ug, http.MethodPost, "/merge", c.Merge,
+		openapi.Operation{Summary: "把另一个账号合并到当前账号"})
+}
+
+// PhoneReq 发验证码的时候不用传 code。更换已经绑定的手机号还要重新认证：
+// 有密码的账号带上 password，没有密码的带上 currentCode，开启了二次验证还要带上 mfaCode
+type PhoneReq struct {
+	Phone       string `json:"phone" binding:"required,phone" errcode:"400104"`
+	Code        string `json:"code"`
+	Password    string `json:"password"`
+	CurrentCode string `json:"currentCode"`
+	MFACode     string `json:"mfaCode"`
+}
+
+// EmailReq 更换已经绑定的邮箱和 PhoneReq 一样要重新认证
+type EmailReq struct {
+	Email       string `json:"email" binding:"required,email" errcode:"400101"`
+	Code        string `json:"code"`
+	Password    string `json:"password"`
+	CurrentCode string `json:"currentCode"`
+	MFACode     string `json:"mfaCode"`
+}
+
+func (c *ContactHandler) SendPhoneCode(ctx *gin.Context, req PhoneReq) (ginx.Result, error) {
+	return c.send(ctx, c.smsCodeSvc, bindPhoneBiz, req.Phone)
+}
+
+func (c *ContactHandler) BindPhone(ctx *gin.Context, req PhoneReq) (ginx.Result, error) {
+	if err := c.verify(ctx, c.smsCodeSvc, bindPhoneBiz, req.Phone, req.Code); err != nil {
+		return ginx.Result{}, err
+	}
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	cred := domain.Credential{Password: req.Password, MFACode: req.MFACode, Code: req.CurrentCode, IP: ctx.ClientIP()}
+	err := c.svc.BindPhone(ctx, uc.Uid, req.Phone, cred)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgBound), nil
+}
+
+func (c *ContactHandler) SendRebindCode(ctx *gin.Context) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	err := c.svc.SendRebindCode(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgCodeSent), nil
+}
+
+func (c *ContactHandler) SendEmailCode(ctx *gin.Context, req EmailReq) (ginx.Result, error) {
+	return c.send(ctx, c.emailCodeSvc, bindEmailBiz, req.Email)
+}
+
+func (c *ContactHandler) BindEmail(ctx *gin.Context, req EmailReq) (ginx.Result, error) {
+	if err := c.verify(ctx, c.emailCodeSvc, bindEmailBiz, req.Email, req.Code); err != nil {
+		return ginx.Result{}, err
+	}
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	cred := domain.Credential{Password: req.Password, MFACode: req.MFACode, Code: req.CurrentCode, IP: ctx.ClientIP()}
+	err := c.svc.BindEmail(ctx, uc.Uid, req.Email, cred)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgBound), nil
+}
+
+// MergeReq phone 和 email 二选一，表示要合并进来的那个账号。
+// 那个账号设置了密码就要带上密码，开启了二次验证还要带上二次验证码
+type MergeReq struct {
+	Phone    string `json:"phone" binding:"required_without=Email,omitempty,phone" errcode:"400104"`
+	Email    string `json:"email" binding:"required_without=Phone,omitempty,email" errcode:"400101"`
+	Code     string `json:"code"`
+	Password string `json:"password"`
+	MFACode  string `json:"mfaCode"`
+}
+
+func (c *ContactHandler) SendMergeCode(ctx *gin.Context, req MergeReq) (ginx.Result, error) {
+	if req.Phone != "" {
+		return c.send(ctx, c.smsCodeSvc, mergePhoneBiz, req.Phone)
+	}
+	return c.send(ctx, c.emailCodeSvc, mergeEmailBiz, req.Email)
+}
+
+// Merge 当前登录的账号已经证明了所有权，这里再验证另一个账号的手机号或者邮箱，
+// 并且用密码和二次验证码重新认证另一个账号，都通过才合并，合并之后保留当前账号
+func (c *ContactHandler) Merge(ctx *gin.Context, req MergeReq) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	cred := domain.Credential{Password: req.Password, MFACode: req.MFACode, IP: ctx.ClientIP()}
+	var err error
+	switch {
+	case req.Phone != "":
+		if err = c.verify(ctx, c.smsCodeSvc, mergePhoneBiz, req.Phone, req.Code); err != nil {
+			return ginx.Result{}, err
+		}
+		err = c.accountSvc.MergeByPhone(ctx, uc.Uid, req.Phone, cred)
+	default:
+		if err = c.verify(ctx, c.emailCodeSvc, mergeEmailBiz, req.Email, req.Code); err != nil {
+			return ginx.Result{}, err
+		}
+		err = c.accountSvc.MergeByEmail(ctx, uc.Uid, req.Email, cred)
+	}
+	if errors.Is(err, service.ErrUserNoFound) {
+		return ginx.Result{}, errUserNotFound
+	}
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgMerged), nil
+}
+
+func (c *ContactHandler) send(ctx *gin.Context, codeSvc service.CodeService,
+	biz string, target string) (ginx.Result, error) {
+	err := codeSvc.Send(ctx, biz, target)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgCodeSent), nil
+}
+
+func (c *ContactHandler) verify(ctx *gin.Context, codeSvc service.CodeService,
+	biz string, target string, code string) error {
+	ok, err := codeSvc.Verify(ctx, biz, target, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errInvalidCode
+	}
+	return nil
+}
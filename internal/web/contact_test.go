@@ -0,0 +1,90 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/service"
+	svcmocks "github.com/skcheng003/webook/internal/service/mocks"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContactHandler_SendMergeCode(t *testing.T) {
+	testCases := []struct {
+		name string
+		// mock 返回短信和邮件的验证码服务
+		mock    func(ctrl *gomock.Controller) (service.CodeService, service.EmailCodeService)
+		reqBody string
+
+		expectCode int
+		expectBody ginx.Result
+	}{
+		{
+			name: "手机号",
+			mock: func(ctrl *gomock.Controller) (service.CodeService, service.EmailCodeService) {
+				smsCodeSvc := svcmocks.NewMockCodeService(ctrl)
+				smsCodeSvc.EXPECT().Send(gomock.Any(), "user/merge_phone", "+8613800138000").Return(nil)
+				return smsCodeSvc, svcmocks.NewMockCodeService(ctrl)
+			},
+			reqBody:    `{"phone": "+8613800138000"}`,
+			expectCode: http.StatusOK,
+			expectBody: ginx.Result{Msg: "发送成功"},
+		},
+		{
+			name: "邮箱",
+			mock: func(ctrl *gomock.Controller) (service.CodeService, service.EmailCodeService) {
+				emailCodeSvc := svcmocks.NewMockCodeService(ctrl)
+				emailCodeSvc.EXPECT().Send(gomock.Any(), "user/merge_email", "a@qq.com").Return(nil)
+				return svcmocks.NewMockCodeService(ctrl), emailCodeSvc
+			},
+			reqBody:    `{"email": "a@qq.com"}`,
+			expectCode: http.StatusOK,
+			expectBody: ginx.Result{Msg: "发送成功"},
+		},
+		{
+			name: "邮箱格式不对",
+			mock: func(ctrl *gomock.Controller) (service.CodeService, service.EmailCodeService) {
+				return svcmocks.NewMockCodeService(ctrl), svcmocks.NewMockCodeService(ctrl)
+			},
+			reqBody:    `{"email": "a@"}`,
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400101, Msg: "邮箱格式错误"},
+		},
+		{
+			name: "手机号和邮箱都没有",
+			mock: func(ctrl *gomock.Controller) (service.CodeService, service.EmailCodeService) {
+				return svcmocks.NewMockCodeService(ctrl), svcmocks.NewMockCodeService(ctrl)
+			},
+			reqBody:    `{}`,
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400104, Msg: "手机号格式错误，需要带国家码，比如 +8613800138000"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			smsCodeSvc, emailCodeSvc := tc.mock(ctrl)
+			server := gin.Default()
+			NewContactHandler(nil, nil, smsCodeSvc, emailCodeSvc).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/users/merge/code/send",
+				bytes.NewBuffer([]byte(tc.reqBody)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.expectCode, resp.Code)
+			var res ginx.Result
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+			assert.Equal(t, tc.expectBody, res)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/internal/service/sms/auth"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"net/http"
+)
+
+// 错误码前三位是 HTTP 状态码，后三位：1xx 用户，2xx 文章，3xx 二次验证，4xx 导出，5xx 短信网关，6xx 图形验证码
+var (
+	errInvalidEmail      = ginx.NewError(400101, http.StatusBadRequest, "邮箱格式错误")
+	errPasswordMismatch  = ginx.NewError(400102, http.StatusBadRequest, "两次输入密码不一致")
+	errInvalidPassword   = ginx.NewError(400103, http.StatusBadRequest, "密码必须包含数字、特殊字符，并且长度不能小于8位")
+	errInvalidPhone      = ginx.NewError(400104, http.StatusBadRequest, "手机号格式错误，需要带国家码，比如 +8613800138000")
+	errInvalidCode       = ginx.NewError(400105, http.StatusBadRequest, "验证码有误")
+	errNicknameTooLong   = ginx.NewError(400106, http.StatusBadRequest, "昵称太长")
+	errBioTooLong        = ginx.NewError(400107, http.StatusBadRequest, "个人简介太长")
+	errInvalidBirthday   = ginx.NewError(400108, http.StatusBadRequest, "生日格式错误")
+	errInvalidRole       = ginx.NewError(400109, http.StatusBadRequest, "角色不存在")
+	errMergeSelf         = ginx.NewError(400110, http.StatusBadRequest, "不能和自己合并")
+	errInvalidLocale     = ginx.NewError(400111, http.StatusBadRequest, "不支持的语言")
+	errPhoneBlocked      = ginx.NewError(400112, http.StatusBadRequest, "这个号段不能接收验证码")
+	errInvalidCredential = ginx.NewError(401101, http.StatusUnauthorized, "用户名或密码错误")
+	errUserDisabled      = ginx.NewError(403101, http.StatusForbidden, "账号已被禁用")
+	errAccountLocked     = ginx.NewError(403102, http.StatusForbidden, "密码错误次数太多，账号已被临时锁定，请稍后再试或者使用短信验证码解锁")
+	errUserNotFound      = ginx.NewError(404101, http.StatusNotFound, "用户不存在")
+	errSessionNotFound   = ginx.NewError(404102, http.StatusNotFound, "设备不存在")
+	errEmailDuplicate    = ginx.NewError(409101, http.StatusConflict, "邮箱地址冲突")
+	errPhoneBound        = ginx.NewError(409102, http.StatusConflict, "手机号已经被其它账号绑定")
+	errEmailBound        = ginx.NewError(409103, http.StatusConflict, "邮箱已经被其它账号绑定")
+	errAccountNotLocked  = ginx.NewError(409104, http.StatusConflict, "账号没有被锁定")
+	errAccountNoPhone    = ginx.NewError(409105, http.StatusConflict, "账号没有绑定手机号，请等待锁定结束")
+	errAccountNotActive  = ginx.NewError(409106, http.StatusConflict, "账号当前状态不允许这个操作")
+	errNotPendingDelete  = ginx.NewError(409107, http.StatusConflict, "账号没有在注销冷静期内")
+	errUserNotMergeable  = ginx.NewError(409108, http.StatusConflict, "账号当前状态不能合并")
+	errUserNotDisabled   = ginx.NewError(409109, http.StatusConflict, "账号没有被禁用")
+	errReauthRequired    = ginx.NewError(428101, http.StatusPreconditionRequired, "更换之前请先输入密码，没有密码的账号输入当前手机号收到的验证码")
+	errCodeSendTooMany   = ginx.NewError(429101, http.StatusTooManyRequests, "发送太频繁，请稍后再试")
+	errLoginTooFrequent  = ginx.NewError(429102, http.StatusTooManyRequests, "登录失败次数太多，请稍后再试")
+	errCodeQuotaExceeded = ginx.NewError(429103, http.StatusTooManyRequests, "今天发送验证码的次数太多，请明天再试")
+	errCodeVerifyTooMany = ginx.NewError(429104, http.StatusTooManyRequests, "验证码错误次数太多，请重新获取")
+
+	errArticleNotFound  = ginx.NewError(404201, http.StatusNotFound, "文章不存在")
+	errArticleNotOwned  = ginx.NewError(403201, http.StatusForbidden, "文章不存在或者不属于你")
+	errArticleModerated = ginx.NewError(403202, http.StatusForbidden, "文章已经被管理员下架，不能修改")
+
+	errMFAInvalidCode    = ginx.NewError(400301, http.StatusBadRequest, "二次验证码错误")
+	errMFANotEnrolled    = ginx.NewError(409301, http.StatusConflict, "没有绑定身份验证器")
+	errMFAAlreadyEnabled = ginx.NewError(409302, http.StatusConflict, "已经开启二次验证")
+	errMFARequired       = ginx.NewError(428301, http.StatusPreconditionRequired, "请输入二次验证码")
+
+	errExportNotFound   = ginx.NewError(404401, http.StatusNotFound, "没有导出记录")
+	errExportInProgress = ginx.NewError(409401, http.StatusConflict, "上一次导出还没有完成")
+
+	errGatewayInvalidToken  = ginx.NewError(401501, http.StatusUnauthorized, "短信网关 token 无效")
+	errGatewayTemplate      = ginx.NewError(400501, http.StatusBadRequest, "模板不存在或者参数个数不对")
+	errGatewayQuotaExceeded = ginx.NewError(429501, http.StatusTooManyRequests, "短信额度已经用完，请稍后再试")
+	errGatewayInvalidTTL    = ginx.NewError(400502, http.StatusBadRequest, "有效期必须大于 0 并且不超过 90 天")
+	errGatewayReservedTpl   = ginx.NewError(403501, http.StatusForbidden, "不能使用内部的短信模板")
+
+	errCaptchaInvalid  = ginx.NewError(400601, http.StatusBadRequest, "图形验证码错误或者已经过期")
+	errCaptchaRequired = ginx.NewError(428601, http.StatusPreconditionRequired, "请求太频繁，请先输入图形验证码")
+)
+
+func init() {
+	// 注意 ErrUserNoFound 和 ErrArticleNoFound 底层是同一个错误，
+	// 这里只注册成通用的不存在，handler 里面需要更准确的提示就自己转换
+	ginx.Register(service.ErrUserNoFound, ginx.ErrNotFound)
+	ginx.Register(service.ErrUserDuplicateEmail, errEmailDuplicate)
+	ginx.Register(service.ErrInvalidUserOrPassword, errInvalidCredential)
+	ginx.Register(service.ErrUserDisabled, errUserDisabled)
+	ginx.Register(service.ErrInvalidRole, errInvalidRole)
+	ginx.Register(service.ErrAccountLocked, errAccountLocked)
+	ginx.Register(service.ErrAccountNotLocked, errAccountNotLocked)
+	ginx.Register(service.ErrLoginTooFrequent, errLoginTooFrequent)
+	ginx.Register(service.ErrAccountNoPhone, errAccountNoPhone)
+	ginx.Register(service.ErrCodeInvalid, errInvalidCode)
+	ginx.Register(service.ErrCodeSendTooMany, errCodeSendTooMany)
+	ginx.Register(service.ErrCodeQuotaExceeded, errCodeQuotaExceeded)
+	ginx.Register(service.ErrCodeVerifyTooManyTimes, errCodeVerifyTooMany)
+	ginx.Register(service.ErrPhoneBlocked, errPhoneBlocked)
+	ginx.Register(service.ErrPhoneBound, errPhoneBound)
+	ginx.Register(service.ErrEmailBound, errEmailBound)
+	ginx.Register(service.ErrAccountNotActive, errAccountNotActive)
+	ginx.Register(service.ErrNotPendingDeletion, errNotPendingDelete)
+	ginx.Register(service.ErrMergeSelf, errMergeSelf)
+	ginx.Register(service.ErrUserNotMergeable, errUserNotMergeable)
+	ginx.Register(service.ErrUserNotDisabled, errUserNotDisabled)
+	ginx.Register(service.ErrReauthRequired, errReauthRequired)
+	ginx.Register(service.ErrArticleNotOwned, errArticleNotOwned)
+	ginx.Register(service.ErrArticleModerated, errArticleModerated)
+	ginx.Register(service.ErrMFANotEnrolled, errMFANotEnrolled)
+	ginx.Register(service.ErrMFAAlreadyEnabled, errMFAAlreadyEnabled)
+	ginx.Register(service.ErrMFAInvalidCode, errMFAInvalidCode)
+	ginx.Register(service.ErrMFARequired, errMFARequired)
+	ginx.Register(service.ErrExportInProgress, errExportInProgress)
+	ginx.Register(service.ErrExportNotFound, errExportNotFound)
+	ginx.Register(service.ErrExportLinkInvalid, ginx.ErrNotFound)
+	ginx.Register(auth.ErrInvalidToken, errGatewayInvalidToken)
+	ginx.Register(auth.ErrQuotaExceeded, errGatewayQuotaExceeded)
+	ginx.Register(auth.ErrInvalidTTL, errGatewayInvalidTTL)
+	ginx.Register(auth.ErrReservedTemplate, errGatewayReservedTpl)
+	ginx.Register(service.ErrCaptchaInvalid, errCaptchaInvalid)
+	ginx.Register(service.ErrCaptchaRequired, errCaptchaRequired)
+}
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"net/http"
+	"strconv"
+)
+
+// ExportHandler 个人数据导出
+type ExportHandler struct {
+	svc service.ExportService
+}
+
+func NewExportHandler(svc service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		svc: svc,
+	}
+}
+
+func (e *ExportHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users")
+	ginx.Handle(ug, http.MethodPost, "/export", e.Request,
+		openapi.Operation{Summary: "申请导出个人数据，生成好了会通知"})
+	ginx.Handle(ug, http.MethodGet, "/export", e.Status,
+		openapi.Operation{Summary: "最近一次导出的状态和下载链接", Response: ExportVO{}})
+	// 下载链接自带签名，不需要登录；返回的是文件，所以不走 ginx.Wrap
+	ug.GET("/export/download", e.Download)
+}
+
+func (e *ExportHandler) Request(ctx *gin.Context) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	task, err := e.svc.Request(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgExportStarted).WithData(gin.H{"id": task.Id}), nil
+}
+
+type ExportVO struct {
+	Id       string `json:"id"`
+	Status   string `json:"status"`
+	Link     string `json:"link,omitempty"`
+	ExpireAt int64  `json:"expireAt,omitempty"`
+}
+
+func (e *ExportHandler) Status(ctx *gin.Context) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	task, link, err := e.svc.Status(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	vo := ExportVO{
+		Id:     task.Id,
+		Status: string(task.Status),
+		Link:   link,
+	}
+	if link != "" {
+		vo.ExpireAt = task.ExpireAt.UnixMilli()
+	}
+	return ginx.Result{Data: vo}, nil
+}
+
+func (e *ExportHandler) Download(ctx *gin.Context) {
+	uid, err := strconv.ParseInt(ctx.Query("uid"), 10, 64)
+	if err != nil {
+		ginx.Abort(ctx, ginx.ErrNotFound)
+		return
+	}
+	expires, err := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	if err != nil {
+		ginx.Abort(ctx, ginx.ErrNotFound)
+		return
+	}
+	path, err := e.svc.Open(ctx, uid, ctx.Query("task"), expires, ctx.Query("sig"))
+	if err != nil {
+		ginx.Abort(ctx, err)
+		return
+	}
+	ctx.FileAttachment(path, "webook-export.zip")
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/pkg/ginx"
 )
 
 func RegisterRoutes() *gin.Engine {
@@ -12,21 +13,21 @@ func RegisterRoutes() *gin.Engine {
 
 func registerUsersRoutes(server *gin.Engine) {
 	u := &UserHandler{}
-	server.POST("/users/signup", u.SignUp)
+	server.POST("/users/signup", ginx.WrapBody(u.SignUp))
 	// 这是 REST 风格
 	// server.PUT("/user", func(context *gin.Context) {
 	//
 	// })
 
-	server.POST("/users/login", u.Login)
+	server.POST("/users/login", ginx.WrapBody(u.Login))
 
-	server.POST("/users/edit", u.Edit)
+	server.POST("/users/edit", ginx.WrapBody(u.Edit))
 	// REST 风格
 	// server.POST("/users/:id", func(context *gin.Context) {
 	//
 	// })
 
-	server.GET("/users/profile", u.Profile)
+	server.GET("/users/profile", ginx.WrapBody(u.Profile))
 	// REST 风格
 	// server.GET("/users/:id", func(context *gin.Context) {
 	//
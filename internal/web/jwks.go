@@ -0,0 +1,30 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"net/http"
+)
+
+// JWKSHandler 公开 access token 的验签公钥，其它服务用它来校验 webook 签发的 token
+type JWKSHandler struct {
+	keys *jwt.KeySet
+}
+
+func NewJWKSHandler(keys jwt.Keys) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys.Access,
+	}
+}
+
+func (h *JWKSHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+func (h *JWKSHandler) JWKS(ctx *gin.Context) {
+	// 轮换密钥的时候新公钥要先发布出去，缓存时间不宜太长
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{
+		"keys": h.keys.JWKS(),
+	})
+}
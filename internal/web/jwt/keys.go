@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+)
+
+var (
+	ErrUnknownKid        = errors.New("未知的 kid")
+	ErrUnsupportedMethod = errors.New("不支持的签名算法")
+)
+
+// KeyConfig 密钥配置，HS512 用 Secret，RS256 和 EdDSA 用 PEM 文件。
+// 轮换掉的旧密钥只需要配置公钥，继续用来验证还没过期的 token
+type KeyConfig struct {
+	Kid            string
+	Alg            string
+	Secret         string
+	PrivateKeyFile string
+	PublicKeyFile  string
+}
+
+// KeySetConfig Active 是当前用来签名的密钥
+type KeySetConfig struct {
+	Active string
+	Keys   []KeyConfig
+}
+
+// Key 一把签名密钥，signKey 为空说明只能用来验签
+type Key struct {
+	Kid       string
+	Method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+// KeySet 用 active 签名，验签的时候按照 token 头部的 kid 找密钥，
+// 所以轮换期间新旧密钥签发的 token 都是有效的
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// Keys 不同用途的 token 用不同的密钥，避免 refresh token 被当成 access token 用
+type Keys struct {
+	Access  *KeySet
+	Refresh *KeySet
+	MFA     *KeySet
+}
+
+func NewKeySet(cfg KeySetConfig) (*KeySet, error) {
+	ks := &KeySet{
+		keys: make(map[string]*Key, len(cfg.Keys)),
+	}
+	for _, kc := range cfg.Keys {
+		k, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("加载密钥 %s 失败 %w", kc.Kid, err)
+		}
+		ks.keys[k.Kid] = k
+	}
+	active, ok := ks.keys[cfg.Active]
+	if !ok || active.signKey == nil {
+		return nil, fmt.Errorf("签名密钥 %s 不存在或者缺少私钥", cfg.Active)
+	}
+	ks.active = active
+	return ks, nil
+}
+
+// Sign 用当前密钥签名，并且在头部带上 kid
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.Kid
+	return token.SignedString(ks.active.signKey)
+}
+
+// Parse 校验签名和过期时间
+func (ks *KeySet) Parse(signedToken string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(signedToken, claims, ks.keyfunc)
+	if err != nil {
+		return err
+	}
+	if token == nil || !token.Valid {
+		return jwt.ErrTokenInvalidClaims
+	}
+	return nil
+}
+
+func (ks *KeySet) keyfunc(token *jwt.Token) (any, error) {
+	// 上线 kid 之前签发的 token 没有 kid，用当前密钥校验
+	k := ks.active
+	if kid, ok := token.Header["kid"].(string); ok {
+		k, ok = ks.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKid
+		}
+	}
+	// 必须校验算法，防止用公钥当 HMAC 密钥伪造 token
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, ErrUnsupportedMethod
+	}
+	return k.verifyKey, nil
+}
+
+// JWK RFC 7517 里面的公钥格式
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS 只公开非对称密钥，HMAC 密钥是不能外泄的
+func (ks *KeySet) JWKS() []JWK {
+	res := make([]JWK, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			res = append(res, JWK{
+				Kty: "RSA",
+				Kid: k.Kid,
+				Use: "sig",
+				Alg: k.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			res = append(res, JWK{
+				Kty: "OKP",
+				Kid: k.Kid,
+				Use: "sig",
+				Alg: k.Method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return res
+}
+
+func loadKey(cfg KeyConfig) (*Key, error) {
+	k := &Key{Kid: cfg.Kid}
+	switch cfg.Alg {
+	case "HS512":
+		if cfg.Secret == "" {
+			return nil, errors.New("缺少 secret")
+		}
+		k.Method = jwt.SigningMethodHS512
+		k.signKey = []byte(cfg.Secret)
+		k.verifyKey = k.signKey
+		return k, nil
+	case "RS256":
+		k.Method = jwt.SigningMethodRS256
+		return k, loadAsymmetricKey(k, cfg,
+			func(data []byte) (crypto.Signer, error) {
+				return jwt.ParseRSAPrivateKeyFromPEM(data)
+			},
+			func(data []byte) (any, error) {
+				return jwt.ParseRSAPublicKeyFromPEM(data)
+			})
+	case "EdDSA":
+		k.Method = jwt.SigningMethodEdDSA
+		return k, loadAsymmetricKey(k, cfg,
+			func(data []byte) (crypto.Signer, error) {
+				pk, err := jwt.ParseEdPrivateKeyFromPEM(data)
+				if err != nil {
+					return nil, err
+				}
+				return pk.(crypto.Signer), nil
+			},
+			func(data []byte) (any, error) {
+				return jwt.ParseEdPublicKeyFromPEM(data)
+			})
+	default:
+		return nil, ErrUnsupportedMethod
+	}
+}
+
+// loadAsymmetricKey 有私钥的时候公钥直接从私钥里面推导出来
+func loadAsymmetricKey(k *Key, cfg KeyConfig,
+	parsePrivate func([]byte) (crypto.Signer, error),
+	parsePublic func([]byte) (any, error)) error {
+	if cfg.PrivateKeyFile != "" {
+		data, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+		signer, err := parsePrivate(data)
+		if err != nil {
+			return err
+		}
+		k.signKey = signer
+		k.verifyKey = signer.Public()
+		return nil
+	}
+	if cfg.PublicKeyFile == "" {
+		return errors.New("缺少密钥文件")
+	}
+	data, err := os.ReadFile(cfg.PublicKeyFile)
+	if err != nil {
+		return err
+	}
+	k.verifyKey, err = parsePublic(data)
+	return err
+}
@@ -0,0 +1,103 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeySet_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	rsaFile := writePrivateKey(t, dir, "rs.pem", mustRSAKey(t))
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edFile := writePrivateKey(t, dir, "ed.pem", edKey)
+
+	keys := []KeyConfig{
+		{Kid: "hs-1", Alg: "HS512", Secret: "old-secret"},
+		{Kid: "rs-1", Alg: "RS256", PrivateKeyFile: rsaFile},
+		{Kid: "ed-1", Alg: "EdDSA", PrivateKeyFile: edFile},
+	}
+	old, err := NewKeySet(KeySetConfig{Active: "hs-1", Keys: keys})
+	require.NoError(t, err)
+	oldToken, err := old.Sign(newClaims())
+	require.NoError(t, err)
+
+	for _, active := range []string{"rs-1", "ed-1"} {
+		t.Run(active, func(t *testing.T) {
+			ks, err := NewKeySet(KeySetConfig{Active: active, Keys: keys})
+			require.NoError(t, err)
+			token, err := ks.Sign(newClaims())
+			require.NoError(t, err)
+
+			claims := &UserClaims{}
+			require.NoError(t, ks.Parse(token, claims))
+			assert.Equal(t, int64(123), claims.Uid)
+			// 轮换期间旧密钥签发的 token 依旧有效
+			require.NoError(t, ks.Parse(oldToken, &UserClaims{}))
+		})
+	}
+
+	ks, err := NewKeySet(KeySetConfig{Active: "rs-1", Keys: keys})
+	require.NoError(t, err)
+	jwks := ks.JWKS()
+	// HMAC 密钥不能公开
+	assert.Len(t, jwks, 2)
+	for _, k := range jwks {
+		assert.NotEqual(t, "hs-1", k.Kid)
+	}
+}
+
+func TestKeySet_Reject(t *testing.T) {
+	ks, err := NewKeySet(KeySetConfig{Active: "hs-1", Keys: []KeyConfig{
+		{Kid: "hs-1", Alg: "HS512", Secret: "secret"},
+	}})
+	require.NoError(t, err)
+
+	// 未知 kid
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, newClaims())
+	token.Header["kid"] = "hs-2"
+	signed, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, ks.Parse(signed, &UserClaims{}), ErrUnknownKid)
+
+	// 算法和密钥不匹配
+	token = jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims())
+	token.Header["kid"] = "hs-1"
+	signed, err = token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, ks.Parse(signed, &UserClaims{}), ErrUnsupportedMethod)
+}
+
+func newClaims() UserClaims {
+	return UserClaims{
+		Uid: 123,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func writePrivateKey(t *testing.T, dir string, name string, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+	return path
+}
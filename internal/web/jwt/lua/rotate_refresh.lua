@@ -0,0 +1,24 @@
+-- 当前有效的 refresh token id
+local key = KEYS[1]
+-- 已经被轮换掉的 refresh token id
+local usedKey = KEYS[2]
+local presented = ARGV[1]
+local next = ARGV[2]
+local ttl = tonumber(ARGV[3])
+
+local current = redis.call("get", key)
+if current == false then
+    -- session 已经过期或者被吊销
+    return -2
+end
+if current == presented then
+    redis.call("set", key, next, "EX", ttl)
+    redis.call("sadd", usedKey, presented)
+    redis.call("expire", usedKey, ttl)
+    return 0
+end
+if redis.call("sismember", usedKey, presented) == 1 then
+    -- 旧的 refresh token 被重放了
+    return -1
+end
+return -2
@@ -1,35 +1,53 @@
 package jwt
 
 import (
+	"context"
+	_ "embed"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
-	"net/http"
+	"github.com/skcheng003/webook/internal/domain"
 	"strings"
 	"time"
 )
 
-var AccessTokenKey = []byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0")
-var RefreshTokenKey = []byte("95osj3fUD7fo0mlYdDbncXz4VD2igvfx")
+var (
+	//go:embed lua/rotate_refresh.lua
+	luaRotateRefresh string
+
+	ErrRefreshTokenReused  = errors.New("refresh token 被重复使用")
+	ErrRefreshTokenInvalid = errors.New("refresh token 已经失效")
+	ErrMFATokenExhausted   = errors.New("mfa token 已经用过或者尝试次数太多")
+)
+
+// mfaTokenMaxAttempts 一个 MFA token 最多可以提交几次验证码
+const mfaTokenMaxAttempts = 5
+
+// refreshTokenExpiration refresh token 本身的有效期，Redis 里面的轮换记录、设备列表
+// 和退出标记都用同一个值，两边对不上的话，token 过期了记录还在，或者记录没了 token 还能用
+const refreshTokenExpiration = time.Hour * 72
 
 type RedisJWTHandler struct {
 	redisCmd     redis.Cmdable
+	keys         Keys
 	rtExpiration time.Duration
 }
 
-func NewRedisJWTHandler(cmd redis.Cmdable) Handler {
+func NewRedisJWTHandler(cmd redis.Cmdable, keys Keys) Handler {
 	return RedisJWTHandler{
 		redisCmd:     cmd,
-		rtExpiration: time.Hour * 24 * 7,
+		keys:         keys,
+		rtExpiration: refreshTokenExpiration,
 	}
 }
 
-func (h RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64) error {
+func (h RedisJWTHandler) SetLoginToken(ctx *gin.Context, u domain.User) error {
+	uid := u.Id
 	ssid := uuid.New().String()
-	err := h.SetAccessToken(ctx, uid, ssid)
+	err := h.SetAccessToken(ctx, u, ssid)
 	if err != nil {
 		return err
 	}
@@ -37,55 +55,149 @@ func (h RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64) error {
 	if err != nil {
 		return err
 	}
-	return nil
+	now := time.Now()
+	return h.saveSession(ctx, uid, Session{
+		Ssid:        ssid,
+		UserAgent:   ctx.Request.UserAgent(),
+		IP:          ctx.ClientIP(),
+		LoginTime:   now,
+		LastRefresh: now,
+	})
 }
 
-func (h RedisJWTHandler) SetAccessToken(ctx *gin.Context, uid int64, ssid string) error {
+func (h RedisJWTHandler) SetAccessToken(ctx *gin.Context, u domain.User, ssid string) error {
 	// 用 JWT 设置登陆态, 生成一个 JWT token
 	claims := UserClaims{
-		Uid:       uid,
+		Uid:       u.Id,
 		Ssid:      ssid,
 		UserAgent: ctx.Request.UserAgent(),
+		Roles:     u.Roles,
+		Locale:    u.Locale,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 30)),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	signedToken, err := token.SignedString(AccessTokenKey)
+	signedToken, err := h.keys.Access.Sign(claims)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, Result{
-			Code: 5,
-			Msg:  "系统错误，生成 access-token 失败",
-		})
 		return err
 	}
 	ctx.Header("X-Access-Token", signedToken)
 	return nil
 }
 
+// SetRefreshToken 开启一个新的 refresh token 链，只有最新的那个 token 能用来刷新
 func (h RedisJWTHandler) SetRefreshToken(ctx *gin.Context, uid int64, ssid string) error {
+	jti := uuid.New().String()
+	err := h.redisCmd.Set(ctx, h.refreshKey(ssid), jti, h.rtExpiration).Err()
+	if err != nil {
+		return err
+	}
+	return h.signRefreshToken(ctx, uid, ssid, jti)
+}
+
+// RotateRefreshToken 每次刷新都换一个新的 refresh token。
+// 如果拿来刷新的是已经被换掉的旧 token，说明 token 很可能被盗了，
+// 直接吊销整个 session，攻击者和用户都需要重新登录
+func (h RedisJWTHandler) RotateRefreshToken(ctx *gin.Context, rc *RefreshClaims) error {
+	jti := uuid.New().String()
+	res, err := h.redisCmd.Eval(ctx, luaRotateRefresh,
+		[]string{h.refreshKey(rc.Ssid), h.usedRefreshKey(rc.Ssid)},
+		rc.ID, jti, int64(h.rtExpiration.Seconds())).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 0:
+		return h.signRefreshToken(ctx, rc.Uid, rc.Ssid, jti)
+	case -1:
+		err = h.RevokeSession(ctx, rc.Uid, rc.Ssid)
+		if err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	default:
+		return ErrRefreshTokenInvalid
+	}
+}
+
+func (h RedisJWTHandler) signRefreshToken(ctx *gin.Context, uid int64, ssid string, jti string) error {
 	claims := &RefreshClaims{
 		Uid:  uid,
 		Ssid: ssid,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.rtExpiration)),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	signedToken, err := token.SignedString(RefreshTokenKey)
+	signedToken, err := h.keys.Refresh.Sign(claims)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, Result{
-			Code: 5,
-			Msg:  "系统错误，生成 refresh-token失败",
-		})
+		return err
 	}
 	ctx.Header("X-Refresh-Token", signedToken)
 	return nil
 }
 
-func (h RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) error {
-	logout, err := h.redisCmd.Exists(ctx,
-		fmt.Sprintf("users:Ssid:%s", ssid)).Result()
+// SetMFAToken 有效期很短，只能用来完成二次验证，不能访问其它接口
+func (h RedisJWTHandler) SetMFAToken(ctx *gin.Context, uid int64) error {
+	claims := MFAClaims{
+		Uid:       uid,
+		UserAgent: ctx.Request.UserAgent(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			// 用 jti 在 Redis 里面记录尝试次数
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 5)),
+		},
+	}
+	signedToken, err := h.keys.MFA.Sign(claims)
+	if err != nil {
+		return err
+	}
+	ctx.Header("X-MFA-Token", signedToken)
+	return nil
+}
+
+// UseMFAToken 每次提交验证码之前调用，超过次数或者已经验证通过的 token 返回 ErrMFATokenExhausted
+func (h RedisJWTHandler) UseMFAToken(ctx context.Context, mc *MFAClaims) error {
+	if mc.ID == "" || mc.ExpiresAt == nil {
+		return ErrMFATokenExhausted
+	}
+	key := h.mfaKey(mc.ID)
+	pipe := h.redisCmd.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	// 和 token 同时过期
+	pipe.ExpireAt(ctx, key, mc.ExpiresAt.Time)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if incr.Val() > mfaTokenMaxAttempts {
+		return ErrMFATokenExhausted
+	}
+	return nil
+}
+
+// ConsumeMFAToken 验证通过之后作废 token，不能再拿去换登录态
+func (h RedisJWTHandler) ConsumeMFAToken(ctx context.Context, mc *MFAClaims) error {
+	return h.redisCmd.Set(ctx, h.mfaKey(mc.ID), mfaTokenMaxAttempts, redis.KeepTTL).Err()
+}
+
+func (h RedisJWTHandler) ParseAccessToken(signedToken string) (*UserClaims, error) {
+	claims := &UserClaims{}
+	return claims, h.keys.Access.Parse(signedToken, claims)
+}
+
+func (h RedisJWTHandler) ParseRefreshToken(signedToken string) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+	return claims, h.keys.Refresh.Parse(signedToken, claims)
+}
+
+func (h RedisJWTHandler) ParseMFAToken(signedToken string) (*MFAClaims, error) {
+	claims := &MFAClaims{}
+	return claims, h.keys.MFA.Parse(signedToken, claims)
+}
+
+func (h RedisJWTHandler) CheckSession(ctx context.Context, ssid string) error {
+	logout, err := h.redisCmd.Exists(ctx, h.key(ssid)).Result()
 	if err != nil {
 		return err
 	}
@@ -98,14 +210,26 @@ func (h RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) error {
 func (h RedisJWTHandler) ClearSession(ctx *gin.Context) error {
 	ctx.Header("X-Access-Token", "")
 	ctx.Header("X-Refresh-Token", "")
-	uc := ctx.MustGet("userClaims").(UserClaims)
-	return h.redisCmd.Set(ctx, h.key(uc.Ssid), "", h.rtExpiration).Err()
+	uc := ctx.MustGet("userClaims").(*UserClaims)
+	return h.RevokeSession(ctx, uc.Uid, uc.Ssid)
 }
 
 func (h RedisJWTHandler) key(ssid string) string {
 	return fmt.Sprintf("user:ssid:%s", ssid)
 }
 
+func (h RedisJWTHandler) refreshKey(ssid string) string {
+	return fmt.Sprintf("user:refresh:%s", ssid)
+}
+
+func (h RedisJWTHandler) usedRefreshKey(ssid string) string {
+	return fmt.Sprintf("user:refresh:used:%s", ssid)
+}
+
+func (h RedisJWTHandler) mfaKey(jti string) string {
+	return fmt.Sprintf("user:mfa:%s", jti)
+}
+
 func (h RedisJWTHandler) ExtractToken(ctx *gin.Context) string {
 	token := ctx.GetHeader("Authorization")
 	segs := strings.Split(token, " ")
@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"time"
+)
+
+// 每个用户的所有设备放在一个 hash 里面，field 是 ssid，value 是 Session 的 JSON
+
+func (h RedisJWTHandler) TouchSession(ctx *gin.Context, uid int64, ssid string) error {
+	now := time.Now()
+	sess, err := h.getSession(ctx, uid, ssid)
+	if errors.Is(err, redis.Nil) {
+		// 上线设备管理之前登录的 session，没有登录时间
+		sess = Session{
+			Ssid:      ssid,
+			UserAgent: ctx.Request.UserAgent(),
+			IP:        ctx.ClientIP(),
+		}
+	} else if err != nil {
+		return err
+	}
+	sess.IP = ctx.ClientIP()
+	sess.LastRefresh = now
+	return h.saveSession(ctx, uid, sess)
+}
+
+func (h RedisJWTHandler) ListSessions(ctx context.Context, uid int64) ([]Session, error) {
+	vals, err := h.redisCmd.HGetAll(ctx, h.sessionsKey(uid)).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Session, 0, len(vals))
+	for _, val := range vals {
+		var sess Session
+		if err = json.Unmarshal([]byte(val), &sess); err != nil {
+			return nil, err
+		}
+		// 超过 refresh token 有效期没有刷新过的设备，实际上已经掉线了
+		if time.Since(sess.LastRefresh) > h.rtExpiration {
+			continue
+		}
+		res = append(res, sess)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LoginTime.After(res[j].LoginTime)
+	})
+	return res, nil
+}
+
+// RevokeSession 把 ssid 标记为已退出，CheckSession 会拒绝这个 ssid 的所有 token
+func (h RedisJWTHandler) RevokeSession(ctx context.Context, uid int64, ssid string) error {
+	pipe := h.redisCmd.TxPipeline()
+	pipe.Set(ctx, h.key(ssid), "", h.rtExpiration)
+	pipe.HDel(ctx, h.sessionsKey(uid), ssid)
+	pipe.Del(ctx, h.refreshKey(ssid), h.usedRefreshKey(ssid))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (h RedisJWTHandler) RevokeAllSessions(ctx context.Context, uid int64) error {
+	ssids, err := h.redisCmd.HKeys(ctx, h.sessionsKey(uid)).Result()
+	if err != nil {
+		return err
+	}
+	for _, ssid := range ssids {
+		if err = h.RevokeSession(ctx, uid, ssid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h RedisJWTHandler) getSession(ctx context.Context, uid int64, ssid string) (Session, error) {
+	var sess Session
+	val, err := h.redisCmd.HGet(ctx, h.sessionsKey(uid), ssid).Bytes()
+	if err != nil {
+		return sess, err
+	}
+	err = json.Unmarshal(val, &sess)
+	return sess, err
+}
+
+func (h RedisJWTHandler) saveSession(ctx context.Context, uid int64, sess Session) error {
+	val, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	key := h.sessionsKey(uid)
+	pipe := h.redisCmd.TxPipeline()
+	pipe.HSet(ctx, key, sess.Ssid, val)
+	pipe.Expire(ctx, key, h.rtExpiration)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (h RedisJWTHandler) sessionsKey(uid int64) string {
+	return fmt.Sprintf("user:sessions:%d", uid)
+}
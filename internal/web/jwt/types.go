@@ -1,17 +1,35 @@
 package jwt
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/skcheng003/webook/internal/domain"
+	"time"
 )
 
 type Handler interface {
-	SetLoginToken(ctx *gin.Context, uid int64) error
-	SetAccessToken(ctx *gin.Context, uid int64, ssid string) error
+	SetLoginToken(ctx *gin.Context, u domain.User) error
+	SetAccessToken(ctx *gin.Context, u domain.User, ssid string) error
 	SetRefreshToken(ctx *gin.Context, uid int64, ssid string) error
-	CheckSession(ctx *gin.Context, ssid string) error
+	RotateRefreshToken(ctx *gin.Context, rc *RefreshClaims) error
+	SetMFAToken(ctx *gin.Context, uid int64) error
+	// UseMFAToken 和 ConsumeMFAToken 限制一个 MFA token 的尝试次数，并且只能成功一次
+	UseMFAToken(ctx context.Context, mc *MFAClaims) error
+	ConsumeMFAToken(ctx context.Context, mc *MFAClaims) error
+	ParseAccessToken(signedToken string) (*UserClaims, error)
+	ParseRefreshToken(signedToken string) (*RefreshClaims, error)
+	ParseMFAToken(signedToken string) (*MFAClaims, error)
+	CheckSession(ctx context.Context, ssid string) error
 	ClearSession(ctx *gin.Context) error
 	ExtractToken(ctx *gin.Context) string
+	// TouchSession 刷新 token 时更新设备的最近活跃时间
+	TouchSession(ctx *gin.Context, uid int64, ssid string) error
+	ListSessions(ctx context.Context, uid int64) ([]Session, error)
+	// RevokeSession 远程退出某台设备
+	RevokeSession(ctx context.Context, uid int64, ssid string) error
+	// RevokeAllSessions 退出所有设备，比如账号被禁用的时候
+	RevokeAllSessions(ctx context.Context, uid int64) error
 }
 
 type UserClaims struct {
@@ -19,6 +37,10 @@ type UserClaims struct {
 	Ssid string
 	jwt.RegisteredClaims
 	UserAgent string
+	// Roles 角色变更要等到下一次刷新 token 才生效
+	Roles []string
+	// Locale 和 Roles 一样，修改之后下一次刷新 token 才生效
+	Locale string
 }
 type RefreshClaims struct {
 	Uid  int64
@@ -26,9 +48,18 @@ type RefreshClaims struct {
 	jwt.RegisteredClaims
 }
 
-// Result TODO: 在web包中重复了
-type Result struct {
-	Code int    `json:"code"`
-	Msg  string `json:"msg"`
-	Data any    `json:"data"`
+// MFAClaims 密码校验通过但还没完成二次验证时下发的临时 token
+type MFAClaims struct {
+	Uid int64
+	jwt.RegisteredClaims
+	UserAgent string
+}
+
+// Session 一次登录对应一个 ssid，也就是一台设备
+type Session struct {
+	Ssid        string    `json:"ssid"`
+	UserAgent   string    `json:"userAgent"`
+	IP          string    `json:"ip"`
+	LoginTime   time.Time `json:"loginTime"`
+	LastRefresh time.Time `json:"lastRefresh"`
 }
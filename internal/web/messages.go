@@ -0,0 +1,108 @@
+package web
+
+import (
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/i18n"
+)
+
+// 成功提示，编号规则和错误码一样
+var (
+	msgSignUp            = ginx.NewMessage(200101, "注册成功")
+	msgLogin             = ginx.NewMessage(200102, "登录成功")
+	msgCodeSent          = ginx.NewMessage(200103, "发送成功")
+	msgUnlocked          = ginx.NewMessage(200104, "解锁成功")
+	msgUpdated           = ginx.NewMessage(200105, "更新成功")
+	msgLogout            = ginx.NewMessage(200106, "退出登录成功")
+	msgRefreshed         = ginx.NewMessage(200107, "刷新成功")
+	msgSessionRevoked    = ginx.NewMessage(200108, "已退出该设备")
+	msgBound             = ginx.NewMessage(200109, "绑定成功")
+	msgMerged            = ginx.NewMessage(200110, "合并成功")
+	msgDeactivated       = ginx.NewMessage(200111, "账号已停用，重新登录即可恢复")
+	msgDeletionRequested = ginx.NewMessage(200112, "已申请注销")
+	msgDeletionCanceled  = ginx.NewMessage(200113, "已撤销注销申请")
+
+	msgMFARequired = ginx.NewMessage(200301, "请输入二次验证码")
+	msgMFAEnabled  = ginx.NewMessage(200302, "开启二次验证成功，请妥善保存恢复码")
+	msgMFADisabled = ginx.NewMessage(200303, "关闭二次验证成功")
+
+	msgExportStarted = ginx.NewMessage(200401, "正在生成，完成之后会通知你")
+
+	msgGatewayTokenRevoked = ginx.NewMessage(200501, "已停用")
+)
+
+// 中文就是写在代码里的文案，这里只需要注册其它语言
+func init() {
+	i18n.Register(i18n.EnUS, map[int]string{
+		msgSignUp.Id:              "Signed up successfully",
+		msgLogin.Id:               "Logged in successfully",
+		msgCodeSent.Id:            "Code sent",
+		msgUnlocked.Id:            "Account unlocked",
+		msgUpdated.Id:             "Updated successfully",
+		msgLogout.Id:              "Logged out successfully",
+		msgRefreshed.Id:           "Token refreshed",
+		msgSessionRevoked.Id:      "Device logged out",
+		msgBound.Id:               "Bound successfully",
+		msgMerged.Id:              "Accounts merged",
+		msgDeactivated.Id:         "Account deactivated, log in again to restore it",
+		msgDeletionRequested.Id:   "Account deletion requested",
+		msgDeletionCanceled.Id:    "Account deletion canceled",
+		msgMFARequired.Id:         "Please enter your two-factor code",
+		msgMFAEnabled.Id:          "Two-factor authentication enabled, please keep your recovery codes safe",
+		msgMFADisabled.Id:         "Two-factor authentication disabled",
+		msgExportStarted.Id:       "Your export is being generated, we will notify you when it is ready",
+		msgGatewayTokenRevoked.Id: "Token revoked",
+
+		errInvalidEmail.Code:      "Invalid email address",
+		errPasswordMismatch.Code:  "The two passwords do not match",
+		errInvalidPassword.Code:   "Password must be at least 8 characters and contain digits and special characters",
+		errInvalidPhone.Code:      "Invalid phone number, include the country code, e.g. +8613800138000",
+		errInvalidCode.Code:       "Invalid verification code",
+		errNicknameTooLong.Code:   "Nickname is too long",
+		errBioTooLong.Code:        "Bio is too long",
+		errInvalidBirthday.Code:   "Invalid birthday",
+		errInvalidRole.Code:       "Role does not exist",
+		errMergeSelf.Code:         "Cannot merge an account with itself",
+		errInvalidLocale.Code:     "Unsupported language",
+		errPhoneBlocked.Code:      "This number range cannot receive verification codes",
+		errInvalidCredential.Code: "Incorrect username or password",
+		errUserDisabled.Code:      "Account has been disabled",
+		errAccountLocked.Code:     "Too many failed attempts, the account is temporarily locked. Try again later or unlock it with an SMS code",
+		errUserNotFound.Code:      "User not found",
+		errSessionNotFound.Code:   "Device not found",
+		errEmailDuplicate.Code:    "Email address already in use",
+		errPhoneBound.Code:        "Phone number is already bound to another account",
+		errEmailBound.Code:        "Email address is already bound to another account",
+		errAccountNotLocked.Code:  "Account is not locked",
+		errAccountNoPhone.Code:    "No phone number bound, please wait for the lock to expire",
+		errAccountNotActive.Code:  "This operation is not allowed in the current account state",
+		errNotPendingDelete.Code:  "Account is not pending deletion",
+		errUserNotMergeable.Code:  "Account cannot be merged in its current state",
+		errUserNotDisabled.Code:   "Account is not disabled",
+		errReauthRequired.Code:    "Enter your password, or the code sent to your current phone if you have no password, before replacing it",
+		errCodeSendTooMany.Code:   "Sending too frequently, please try again later",
+		errLoginTooFrequent.Code:  "Too many failed logins, please try again later",
+		errCodeQuotaExceeded.Code: "Too many verification codes today, please try again tomorrow",
+		errCodeVerifyTooMany.Code: "Too many wrong attempts, please request a new code",
+
+		errArticleNotFound.Code:  "Article not found",
+		errArticleNotOwned.Code:  "Article not found or not owned by you",
+		errArticleModerated.Code: "Article was taken down by a moderator and cannot be changed",
+
+		errMFAInvalidCode.Code:    "Invalid two-factor code",
+		errMFANotEnrolled.Code:    "No authenticator is enrolled",
+		errMFAAlreadyEnabled.Code: "Two-factor authentication is already enabled",
+		errMFARequired.Code:       "Please enter the two-factor code",
+
+		errExportNotFound.Code:   "No export found",
+		errExportInProgress.Code: "The previous export has not finished yet",
+
+		errGatewayInvalidToken.Code:  "Invalid SMS gateway token",
+		errGatewayTemplate.Code:      "Template not found or wrong number of arguments",
+		errGatewayQuotaExceeded.Code: "SMS quota exceeded, please try again later",
+		errGatewayInvalidTTL.Code:    "TTL must be greater than 0 and at most 90 days",
+		errGatewayReservedTpl.Code:   "Internal SMS templates cannot be used",
+
+		errCaptchaInvalid.Code:  "Invalid or expired captcha",
+		errCaptchaRequired.Code: "Too many requests, please enter the captcha first",
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+// AccessLogMiddlewareBuilder 和 gin 默认的访问日志格式一样，
+// 只是地址里面带凭证的接口，前缀后面的部分不写进日志
+type AccessLogMiddlewareBuilder struct {
+	prefixes []string
+}
+
+func NewAccessLogMiddlewareBuilder() *AccessLogMiddlewareBuilder {
+	return &AccessLogMiddlewareBuilder{}
+}
+
+func (b *AccessLogMiddlewareBuilder) RedactPrefix(prefix ...string) *AccessLogMiddlewareBuilder {
+	b.prefixes = append(b.prefixes, prefix...)
+	return b
+}
+
+func (b *AccessLogMiddlewareBuilder) Build() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		for _, prefix := range b.prefixes {
+			if strings.HasPrefix(param.Path, prefix) {
+				param.Path = prefix + "***"
+				break
+			}
+		}
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			param.Path,
+			param.ErrorMessage,
+		)
+	})
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLogMiddlewareBuilder(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+
+		wantLog    string
+		notWantLog string
+	}{
+		{
+			name:       "回执地址里面的 token 打码",
+			path:       "/sms/receipts/tencent/r7Wc2PqL9xN4tZ8k",
+			wantLog:    `"/sms/receipts/***"`,
+			notWantLog: "r7Wc2PqL9xN4tZ8k",
+		},
+		{
+			name:       "导出下载地址的签名打码",
+			path:       "/users/export/download?uid=1&task=9c1f&sig=Qm4xZ7vT2kLp",
+			wantLog:    `"/users/export/download***"`,
+			notWantLog: "Qm4xZ7vT2kLp",
+		},
+		{
+			name:    "其它地址原样记录",
+			path:    "/users/profile?uid=1",
+			wantLog: `"/users/profile?uid=1"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			old := gin.DefaultWriter
+			gin.DefaultWriter = &buf
+			defer func() { gin.DefaultWriter = old }()
+
+			server := gin.New()
+			server.Use(NewAccessLogMiddlewareBuilder().RedactPrefix("/sms/receipts/", "/users/export/download").Build())
+			server.Any("/*path", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			server.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Contains(t, buf.String(), tc.wantLog)
+			if tc.notWantLog != "" {
+				assert.NotContains(t, buf.String(), tc.notWantLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"strings"
+)
+
+// CaptchaMiddlewareBuilder 同一个 IP 请求 Path 里面的接口太频繁之后，
+// 要在 X-Captcha-Id 和 X-Captcha-Answer 里面带上 GET /captcha 拿到的图形验证码。
+// 每个路径单独计数，其它敏感接口加一个 Path 就行
+type CaptchaMiddlewareBuilder struct {
+	svc   service.CaptchaService
+	paths map[string]struct{}
+}
+
+func NewCaptchaMiddlewareBuilder(svc service.CaptchaService) *CaptchaMiddlewareBuilder {
+	return &CaptchaMiddlewareBuilder{
+		svc:   svc,
+		paths: make(map[string]struct{}),
+	}
+}
+
+func (c *CaptchaMiddlewareBuilder) Path(path ...string) *CaptchaMiddlewareBuilder {
+	for _, p := range path {
+		c.paths[p] = struct{}{}
+	}
+	return c
+}
+
+func (c *CaptchaMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		path := ctx.Request.URL.Path
+		if _, ok := c.paths[path]; !ok {
+			return
+		}
+		err := c.svc.Check(ctx, path, ctx.ClientIP(),
+			ctx.GetHeader("X-Captcha-Id"), strings.TrimSpace(ctx.GetHeader("X-Captcha-Answer")))
+		if err != nil {
+			ginx.Abort(ctx, err)
+		}
+	}
+}
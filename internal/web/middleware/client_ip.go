@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/service"
+)
+
+// ClientIPMiddlewareBuilder 把客户端 IP 放进 context，service 层按 IP 限制的时候用
+type ClientIPMiddlewareBuilder struct {
+}
+
+func NewClientIPMiddlewareBuilder() *ClientIPMiddlewareBuilder {
+	return &ClientIPMiddlewareBuilder{}
+}
+
+func (c *ClientIPMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(service.ClientIPContextKey, ctx.ClientIP())
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPMiddlewareBuilder(t *testing.T) {
+	testCases := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		xff            string
+
+		wantIP string
+	}{
+		{
+			name:       "没有配置代理，伪造的 X-Forwarded-For 不生效",
+			remoteAddr: "203.0.113.7:52000",
+			xff:        "1.2.3.4",
+			wantIP:     "203.0.113.7",
+		},
+		{
+			name:           "不是从代理过来的，伪造的 X-Forwarded-For 不生效",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "203.0.113.7:52000",
+			xff:            "1.2.3.4",
+			wantIP:         "203.0.113.7",
+		},
+		{
+			name:           "从代理过来的，用 X-Forwarded-For",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:52000",
+			xff:            "1.2.3.4",
+			wantIP:         "1.2.3.4",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			require.NoError(t, server.SetTrustedProxies(tc.trustedProxies))
+			server.Use(NewClientIPMiddlewareBuilder().Build())
+			var ip string
+			server.GET("/test", func(ctx *gin.Context) {
+				ip, _ = ctx.Value(service.ClientIPContextKey).(string)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Forwarded-For", tc.xff)
+			server.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.wantIP, ip)
+		})
+	}
+}
@@ -18,8 +18,8 @@ func (c *CorsMiddlewareBuilder) Build() gin.HandlerFunc {
 	return gincors.New(gincors.Config{
 		// AllowOrigins: []string{"https://localhost:3000"},
 		// AllowMethods: []string{"POST", "GET"},
-		AllowHeaders:  []string{"Content-Type", "Authorization"},
-		ExposeHeaders: []string{"X-Access-Token", "X-Refresh-Token"},
+		AllowHeaders:  []string{"Content-Type", "Authorization", "X-Captcha-Id", "X-Captcha-Answer"},
+		ExposeHeaders: []string{"X-Access-Token", "X-Refresh-Token", "X-MFA-Token"},
 		// 是否允许cookie
 		AllowCredentials: true,
 		AllowOriginFunc: func(origin string) bool {
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/pkg/i18n"
+)
+
+// LocaleMiddlewareBuilder 根据 Accept-Language 决定返回的语言，
+// 要放在 LoginJWTMiddlewareBuilder 前面，登录用户自己选的语言会在那里覆盖掉
+type LocaleMiddlewareBuilder struct {
+}
+
+func NewLocaleMiddlewareBuilder() *LocaleMiddlewareBuilder {
+	return &LocaleMiddlewareBuilder{}
+}
+
+func (l *LocaleMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(i18n.ContextKey, i18n.FromAcceptLanguage(ctx.GetHeader("Accept-Language")))
+	}
+}
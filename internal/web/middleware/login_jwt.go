@@ -3,14 +3,16 @@ package middleware
 import (
 	"encoding/gob"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	jwt2 "github.com/skcheng003/webook/internal/web/jwt"
-	"net/http"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"strings"
 	"time"
 )
 
 type LoginJWTMiddlewareBuilder struct {
-	paths []string
+	paths    []string
+	prefixes []string
 	jwt2.Handler
 }
 
@@ -25,6 +27,12 @@ func (l *LoginJWTMiddlewareBuilder) IgnorePath(path ...string) *LoginJWTMiddlewa
 	return l
 }
 
+// IgnorePrefix 地址里面带参数的接口按前缀跳过
+func (l *LoginJWTMiddlewareBuilder) IgnorePrefix(prefix ...string) *LoginJWTMiddlewareBuilder {
+	l.prefixes = append(l.prefixes, prefix...)
+	return l
+}
+
 func (l *LoginJWTMiddlewareBuilder) Build() gin.HandlerFunc {
 	// 用 Go 的方式编码解码
 	gob.Register(time.Now())
@@ -35,31 +43,34 @@ func (l *LoginJWTMiddlewareBuilder) Build() gin.HandlerFunc {
 				return
 			}
 		}
+		for _, prefix := range l.prefixes {
+			if strings.HasPrefix(ctx.Request.URL.Path, prefix) {
+				return
+			}
+		}
 
-		signedToken := l.ExtractToken(ctx)
-		claims := &jwt2.UserClaims{}
-		token, err := jwt.ParseWithClaims(signedToken, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0"), nil
-		})
-		if err != nil {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+		// 会验证签名和过期时间
+		claims, err := l.ParseAccessToken(l.ExtractToken(ctx))
+		if err != nil || claims.Uid == 0 {
+			ginx.Abort(ctx, ginx.ErrUnauthorized)
 			return
 		}
-		// token.Valid 会验证过期时间
-		if token == nil || !token.Valid || claims.Uid == 0 {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-		}
 		//  验证发送客户端
 		if claims.UserAgent != ctx.Request.UserAgent() {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+			ginx.Abort(ctx, ginx.ErrUnauthorized)
+			return
 		}
 		// 查询当前 session 是否已经退出
 		err = l.CheckSession(ctx, claims.Ssid)
 		if err != nil {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+			ginx.Abort(ctx, ginx.ErrUnauthorized)
 			return
 		}
 
+		// 用户自己选过语言就覆盖 Accept-Language
+		if lang, ok := i18n.Parse(claims.Locale); ok {
+			ctx.Set(i18n.ContextKey, lang)
+		}
 		// 把解析后的 claim 放在 context 里面，方便其他路由函数获取
 		ctx.Set("userClaims", claims)
 	}
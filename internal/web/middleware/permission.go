@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/domain"
+	jwt2 "github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/pkg/ginx"
+)
+
+// PermissionMiddlewareBuilder 校验当前用户是否拥有某个权限，
+// 必须放在 LoginJWTMiddlewareBuilder 之后，依赖它放进 context 的 userClaims
+type PermissionMiddlewareBuilder struct {
+	perm domain.Permission
+}
+
+func NewPermissionMiddlewareBuilder(perm domain.Permission) *PermissionMiddlewareBuilder {
+	return &PermissionMiddlewareBuilder{
+		perm: perm,
+	}
+}
+
+func (p *PermissionMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		val, ok := ctx.Get("userClaims")
+		if !ok {
+			ginx.Abort(ctx, ginx.ErrUnauthorized)
+			return
+		}
+		claims, ok := val.(*jwt2.UserClaims)
+		if !ok {
+			ginx.Abort(ctx, ginx.ErrUnauthorized)
+			return
+		}
+		if !domain.HasPermission(claims.Roles, p.perm) {
+			ginx.Abort(ctx, ginx.ErrForbidden)
+			return
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/internal/web/middleware"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+// SMSDeliveryHandler 服务商的送达回执和管理员查询发送记录
+type SMSDeliveryHandler struct {
+	svc service.SMSDeliveryService
+}
+
+func NewSMSDeliveryHandler(svc service.SMSDeliveryService) *SMSDeliveryHandler {
+	return &SMSDeliveryHandler{
+		svc: svc,
+	}
+}
+
+func (h *SMSDeliveryHandler) RegisterRoutes(server *gin.Engine) {
+	// 回执地址配置成 /sms/receipts/tencent/xxx。服务商的回调加不了请求头，只能把 token 放在路径里面，
+	// 放在 query 里面会被网关和访问日志原样记下来，路径的这一段访问日志会打码
+	server.POST("/sms/receipts/:provider/:token", h.Receipts)
+	// 发送记录里面是用户的手机号，只给管短信的管理员看
+	ag := server.Group("/admin/sms", middleware.NewPermissionMiddlewareBuilder(domain.PermSMSManage).Build())
+	ginx.HandleBody(ag, http.MethodGet, "/deliveries", h.History, openapi.Operation{
+		Summary:  "按手机号查询发送记录",
+		Response: []SMSDeliveryVO{},
+	})
+}
+
+// Receipts 各个服务商要求的响应格式不一样，所以不走 ginx 的统一响应
+func (h *SMSDeliveryHandler) Receipts(ctx *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, 1<<20))
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	provider := ctx.Param("provider")
+	ack, err := h.svc.HandleReceipts(ctx, provider, ctx.Param("token"), body)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, ack)
+	case service.ErrReceiptRejected:
+		ctx.AbortWithStatus(http.StatusNotFound)
+	default:
+		zap.L().Error("处理短信回执失败", zap.String("provider", provider), zap.Error(err))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+type SMSDeliveryReq struct {
+	// Phone 放在 query 里面要把 + 转义成 %2B
+	Phone string `form:"phone" binding:"required,phone" errcode:"400104"`
+	PageReq
+}
+
+type SMSDeliveryVO struct {
+	Provider string `json:"provider"`
+	TplId    string `json:"tplId"`
+	Phone    string `json:"phone"`
+	Status   string `json:"status"`
+	SerialNo string `json:"serialNo"`
+	Reason   string `json:"reason"`
+	Ctime    int64  `json:"ctime"`
+	Utime    int64  `json:"utime"`
+}
+
+// History 客服按手机号查短信有没有发出去，返回的手机号是打码的
+func (h *SMSDeliveryHandler) History(ctx *gin.Context, req SMSDeliveryReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	ds, err := h.svc.History(ctx, req.Phone, req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	zap.L().Info("admin_action", zap.String("action", "query_sms_deliveries"),
+		zap.String("phone", domain.MaskPhone(req.Phone)), zap.Int64("operator", uc.Uid))
+	res := make([]SMSDeliveryVO, 0, len(ds))
+	for _, d := range ds {
+		res = append(res, SMSDeliveryVO{
+			Provider: d.Provider,
+			TplId:    d.TplId,
+			Phone:    d.Phone,
+			Status:   string(d.Status),
+			SerialNo: d.SerialNo,
+			Reason:   d.Reason,
+			Ctime:    d.Ctime.UnixMilli(),
+			Utime:    d.Utime.UnixMilli(),
+		})
+	}
+	return ginx.Result{Data: res}, nil
+}
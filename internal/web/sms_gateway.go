@@ -0,0 +1,114 @@
+package web
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/service/sms/auth"
+	"github.com/skcheng003/webook/internal/service/sms/template"
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/internal/web/middleware"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SMSGatewayHandler 其它业务方通过 webook 的短信服务商发短信，
+// 用的是管理员签发的网关 token，不是用户登录的 token
+type SMSGatewayHandler struct {
+	svc *auth.Service
+}
+
+func NewSMSGatewayHandler(svc *auth.Service) *SMSGatewayHandler {
+	return &SMSGatewayHandler{
+		svc: svc,
+	}
+}
+
+func (h *SMSGatewayHandler) RegisterRoutes(server *gin.Engine) {
+	sg := server.Group("/sms")
+	ginx.HandleBody(sg, http.MethodPost, "/send", h.Send, openapi.Operation{
+		Summary: "业务方发送短信，Authorization 里面放网关 token",
+		Public:  true,
+	})
+	ag := server.Group("/admin/sms", middleware.NewPermissionMiddlewareBuilder(domain.PermSMSManage).Build())
+	ginx.HandleBody(ag, http.MethodPost, "/tokens", h.IssueToken, openapi.Operation{
+		Summary:  "给业务方签发网关 token",
+		Response: GatewayTokenVO{},
+	})
+	ginx.Handle(ag, http.MethodDelete, "/tokens/:jti", h.RevokeToken, openapi.Operation{
+		Summary: "提前停用网关 token",
+	})
+}
+
+type GatewaySendReq struct {
+	Args    []string `json:"args"`
+	Numbers []string `json:"numbers" binding:"required,max=100,dive,phone"`
+	// Locale 选择模板的语言，不传就是中文
+	Locale string `json:"locale" binding:"omitempty,locale" errcode:"400111"`
+}
+
+func (h *SMSGatewayHandler) Send(ctx *gin.Context, req GatewaySendReq) (ginx.Result, error) {
+	token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return ginx.Result{}, errGatewayInvalidToken
+	}
+	c := ctx.Request.Context()
+	if lang, ok := i18n.Parse(req.Locale); ok {
+		c = i18n.WithLang(c, lang)
+	}
+	err := h.svc.Send(c, token, req.Args, req.Numbers...)
+	// 模板的错误只有在网关这里是调用方的问题，其它地方是配置错了，所以不全局注册
+	if errors.Is(err, template.ErrTemplateNotFound) || errors.Is(err, template.ErrInvalidArgs) {
+		return ginx.Result{}, errGatewayTemplate
+	}
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgCodeSent), nil
+}
+
+type GatewayTokenReq struct {
+	Biz string `json:"biz" binding:"required"`
+	Tpl string `json:"tpl" binding:"required"`
+	// TTL 有效期，比如 720h，最长 auth.MaxTokenTTL
+	TTL string `json:"ttl" binding:"required"`
+}
+
+type GatewayTokenVO struct {
+	Token string `json:"token"`
+	// Jti 停用 token 的时候要用
+	Jti string `json:"jti"`
+}
+
+func (h *SMSGatewayHandler) IssueToken(ctx *gin.Context, req GatewayTokenReq) (ginx.Result, error) {
+	ttl, err := time.ParseDuration(req.TTL)
+	if err != nil {
+		return ginx.Result{}, errGatewayInvalidTTL
+	}
+	token, jti, err := h.svc.IssueToken(req.Biz, req.Tpl, ttl)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	zap.L().Info("admin_action", zap.String("action", "issue_sms_token"),
+		zap.String("biz", req.Biz), zap.String("tpl", req.Tpl), zap.String("jti", jti),
+		zap.Duration("ttl", ttl), zap.Int64("operator", uc.Uid))
+	return ginx.Result{Data: GatewayTokenVO{Token: token, Jti: jti}}, nil
+}
+
+func (h *SMSGatewayHandler) RevokeToken(ctx *gin.Context) (ginx.Result, error) {
+	jti := ctx.Param("jti")
+	err := h.svc.Revoke(ctx, jti)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	uc := ctx.MustGet("userClaims").(*jwt.UserClaims)
+	zap.L().Info("admin_action", zap.String("action", "revoke_sms_token"),
+		zap.String("jti", jti), zap.Int64("operator", uc.Uid))
+	return ginx.OK(msgGatewayTokenRevoked), nil
+}
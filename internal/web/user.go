@@ -2,138 +2,116 @@ package web
 
 import (
 	"errors"
-	"fmt"
-	regexp "github.com/dlclark/regexp2"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
 	"github.com/skcheng003/webook/internal/domain"
 	"github.com/skcheng003/webook/internal/service"
 	jwt2 "github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/pkg/ginx"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"go.uber.org/zap"
 	"net/http"
 )
 
-const (
-	nicknameSize = 16
-	bioSize      = 256
-)
+// phoneRegexPattern E.164 格式，比如 +8613800138000
+const phoneRegexPattern = `^\+[1-9]\d{1,14}$`
 
-var ErrUserDuplicateEmail = service.ErrUserDuplicateEmail
 var ErrUserNoFound = service.ErrUserNoFound
-var ErrInvalidUserOrPassword = service.ErrInvalidUserOrPassword
 
 // UserHandler 定义和用户有关的路由
 type UserHandler struct {
-	svc              service.UserService
-	codeSvc          service.CodeService
-	emailRegexExp    *regexp.Regexp
-	passwordRegexExp *regexp.Regexp
-	birthRegexExp    *regexp.Regexp
+	svc         service.UserService
+	codeSvc     service.CodeService
+	mfaSvc      service.MFAService
+	loginLogSvc service.LoginLogService
 	jwt2.Handler
-	cmd redis.Cmdable
 }
 
-func NewUserHandler(userSvc service.UserService, codeSvc service.CodeService, jwtHdl jwt2.Handler) *UserHandler {
-	const (
-		emailRegexPattern    = "^\\w+([-+.]\\w+)*@\\w+([-.]\\w+)*\\.\\w+([-.]\\w+)*$"
-		passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,72}$`
-		birthdayRegexPattern = `\d{4}-\d{2}-\d{2}`
-	)
-
+func NewUserHandler(userSvc service.UserService, codeSvc service.CodeService,
+	mfaSvc service.MFAService, loginLogSvc service.LoginLogService, jwtHdl jwt2.Handler) *UserHandler {
 	return &UserHandler{
-		svc:              userSvc,
-		codeSvc:          codeSvc,
-		emailRegexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
-		passwordRegexExp: regexp.MustCompile(passwordRegexPattern, regexp.None),
-		birthRegexExp:    regexp.MustCompile(birthdayRegexPattern, regexp.None),
-		Handler:          jwtHdl,
+		svc:         userSvc,
+		codeSvc:     codeSvc,
+		mfaSvc:      mfaSvc,
+		loginLogSvc: loginLogSvc,
+		Handler:     jwtHdl,
 	}
 }
 
 func (u *UserHandler) RegisterRoutes(server *gin.Engine) {
 	ug := server.Group("/users")
-	ug.POST("/signup", u.SignUp)
-	ug.POST("/login", u.LoginJWT)
-	ug.POST("/edit", u.Edit)
-	ug.GET("/profile", u.ProfileJWT)
-	ug.POST("/login_sms/code/send", u.SendLoginSMSCode)
-	ug.POST("/login_sms", u.VerifyLoginSMSCode)
-	ug.POST("/refresh_token", u.RefreshToken)
+	ginx.HandleBody(ug, http.MethodPost, "/signup", u.SignUp,
+		openapi.Operation{Summary: "邮箱注册", Public: true})
+	ginx.HandleBody(ug, http.MethodPost, "/login", u.LoginJWT,
+		openapi.Operation{Summary: "邮箱密码登录，开启了二次验证的账号会返回 X-MFA-Token", Public: true, Response: LoginVO{}})
+	ginx.HandleBody(ug, http.MethodPost, "/edit", u.Edit,
+		openapi.Operation{Summary: "编辑个人资料"})
+	ginx.Handle(ug, http.MethodGet, "/profile", u.ProfileJWT,
+		openapi.Operation{Summary: "查看个人资料", Response: ProfileVO{}})
+	ginx.HandleBody(ug, http.MethodPost, "/login_sms/code/send", u.SendLoginSMSCode,
+		openapi.Operation{Summary: "发送短信登录验证码", Public: true})
+	ginx.HandleBody(ug, http.MethodPost, "/login_sms", u.VerifyLoginSMSCode,
+		openapi.Operation{Summary: "短信验证码登录，没有注册过会自动注册", Public: true})
+	ginx.Handle(ug, http.MethodPost, "/refresh_token", u.RefreshToken,
+		openapi.Operation{Summary: "用 refresh token 换新的 access token", Public: true})
+	ginx.HandleBody(ug, http.MethodPost, "/login_mfa", u.LoginMFA,
+		openapi.Operation{Summary: "登录第二步，Authorization 带 X-MFA-Token", Public: true})
+	ginx.HandleBody(ug, http.MethodPost, "/login/unlock/code/send", u.SendUnlockCode,
+		openapi.Operation{Summary: "发送解锁账号的短信验证码", Public: true})
+	ginx.HandleBody(ug, http.MethodPost, "/login/unlock", u.UnlockAccount,
+		openapi.Operation{Summary: "用短信验证码解锁账号", Public: true})
+	ginx.Handle(ug, http.MethodPost, "/mfa/totp/enroll", u.EnrollTOTP,
+		openapi.Operation{Summary: "生成身份验证器密钥", Response: TOTPVO{}})
+	ginx.HandleBody(ug, http.MethodPost, "/mfa/totp/enable", u.EnableTOTP,
+		openapi.Operation{Summary: "开启二次验证", Response: RecoveryCodesVO{}})
+	ginx.HandleBody(ug, http.MethodPost, "/mfa/totp/disable", u.DisableTOTP,
+		openapi.Operation{Summary: "关闭二次验证"})
+	ginx.Handle(ug, http.MethodPost, "/logout", u.LogoutJWT,
+		openapi.Operation{Summary: "退出登录"})
+	ginx.Handle(ug, http.MethodGet, "/sessions", u.Sessions,
+		openapi.Operation{Summary: "登录中的设备", Response: []SessionVO{}})
+	ginx.HandleBody(ug, http.MethodPost, "/sessions/revoke", u.LogoutSession,
+		openapi.Operation{Summary: "远程退出某台设备"})
+	ginx.HandleBody(ug, http.MethodGet, "/security/logins", u.LoginHistory,
+		openapi.Operation{Summary: "登录记录", Response: []LoginLogVO{}})
 }
 
-func (u *UserHandler) SignUp(ctx *gin.Context) {
-	type SignUpReq struct {
-		Email           string `json:"email" binding:"required"`
-		Password        string `json:"password" binding:"required"`
-		ConfirmPassword string `json:"confirmPassword" binding:"required"`
-	}
-	var req SignUpReq
-	// Bind 根据 Content-Type 解析数据到 req 里面，如果解析错误，返回 400
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
-
-	// 邮箱校验
-	isEmail, err := u.emailRegexExp.MatchString(req.Email)
-	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
-	}
-	if !isEmail {
-		ctx.String(http.StatusOK, "邮箱格式错误")
-		return
-	}
-
-	// 密码校验
-	if req.Password != req.ConfirmPassword {
-		ctx.String(http.StatusOK, "两次输入密码不一致")
-		return
-	}
-	isPassword, err := u.passwordRegexExp.MatchString(req.Password)
-	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
-	}
-	if !isPassword {
-		ctx.String(http.StatusOK, "密码必须包含数字、特殊字符，并且长度不能小于8位")
-		return
-	}
+// SignUpReq 校验不通过的时候返回 errcode 对应的错误
+type SignUpReq struct {
+	Email           string `json:"email" binding:"required,email" errcode:"400101"`
+	Password        string `json:"password" binding:"required,password" errcode:"400103"`
+	ConfirmPassword string `json:"confirmPassword" binding:"required,eqfield=Password" errcode:"400102"`
+}
 
+func (u *UserHandler) SignUp(ctx *gin.Context, req SignUpReq) (ginx.Result, error) {
 	// 调用 service 进行注册
-	err = u.svc.SignUp(ctx, domain.User{
+	err := u.svc.SignUp(ctx, domain.User{
 		Email:    req.Email,
 		Password: req.Password,
 	})
-
-	if errors.Is(err, ErrUserDuplicateEmail) {
-		ctx.String(http.StatusOK, "邮箱地址冲突")
-		return
-	}
 	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
+		return ginx.Result{}, err
 	}
-	ctx.String(http.StatusOK, "注册成功")
+	return ginx.OK(msgSignUp), nil
 }
 
-func (u *UserHandler) Login(ctx *gin.Context) {
-	type LoginReq struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-	var req LoginReq
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
-	user, err := u.svc.Login(ctx, req.Email, req.Password)
-	if errors.Is(err, ErrUserNoFound) || errors.Is(err, ErrInvalidUserOrPassword) {
-		ctx.String(http.StatusOK, "用户名或密码错误")
-		return
+type LoginReq struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginVO struct {
+	MFARequired bool `json:"mfaRequired"`
+}
+
+func (u *UserHandler) Login(ctx *gin.Context, req LoginReq) (ginx.Result, error) {
+	user, err := u.svc.Login(ctx, req.Email, req.Password, ctx.ClientIP())
+	if errors.Is(err, ErrUserNoFound) {
+		return ginx.Result{}, errInvalidCredential
 	}
 	if err != nil {
-		ctx.String(http.StatusOK, "系统错误")
-		return
+		return ginx.Result{}, err
 	}
 	sess := sessions.Default(ctx)
 	// 在session中放值
@@ -144,282 +122,428 @@ func (u *UserHandler) Login(ctx *gin.Context) {
 		MaxAge: 30 * 60,
 	})
 	_ = sess.Save()
-	ctx.String(http.StatusOK, "登录成功")
-	return
+	return ginx.OK(msgLogin), nil
 }
 
-func (u *UserHandler) LoginJWT(ctx *gin.Context) {
-	// TODO: Log out function
-	type LoginReq struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+func (u *UserHandler) LoginJWT(ctx *gin.Context, req LoginReq) (ginx.Result, error) {
+	user, err := u.svc.Login(ctx, req.Email, req.Password, ctx.ClientIP())
+	if errors.Is(err, service.ErrMFARequired) {
+		// 开启了二次验证，先只给一个临时 token，验证通过之后才给真正的登录态
+		err = u.SetMFAToken(ctx, user.Id)
+		if err != nil {
+			return ginx.Result{}, err
+		}
+		u.recordLogin(ctx, user.Id, domain.LoginMethodPassword, domain.LoginOutcomeMFARequired)
+		return ginx.OK(msgMFARequired).WithData(LoginVO{MFARequired: true}), nil
 	}
-
-	var req LoginReq
-	if err := ctx.Bind(&req); err != nil {
-		return
+	if err != nil {
+		outcome := domain.LoginOutcomeFailure
+		if errors.Is(err, service.ErrAccountLocked) || errors.Is(err, service.ErrLoginTooFrequent) {
+			outcome = domain.LoginOutcomeLocked
+		}
+		u.recordLogin(ctx, user.Id, domain.LoginMethodPassword, outcome)
 	}
-
-	user, err := u.svc.Login(ctx, req.Email, req.Password)
-	if errors.Is(err, ErrUserNoFound) || errors.Is(err, ErrInvalidUserOrPassword) {
-		ctx.String(http.StatusOK, "用户名或密码错误")
-		return
+	// 账号不存在和密码错误给一样的提示，避免被用来探测账号
+	if errors.Is(err, ErrUserNoFound) {
+		return ginx.Result{}, errInvalidCredential
 	}
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		return
+		return ginx.Result{}, err
 	}
-	err = u.SetLoginToken(ctx, user.Id)
+	err = u.SetLoginToken(ctx, user)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, Result{
-			Msg: "设置登录token失败",
-		})
-		return
+		return ginx.Result{}, err
 	}
-	ctx.JSON(http.StatusOK, Result{
-		Msg: "登录成功",
-	})
-	return
+	u.recordLogin(ctx, user.Id, domain.LoginMethodPassword, domain.LoginOutcomeSuccess)
+	return ginx.OK(msgLogin), nil
 }
 
-func (u *UserHandler) Edit(ctx *gin.Context) {
-	type EditReq struct {
-		Nickname string `json:"nickname"`
-		Birth    string `json:"birth"`
-		Bio      string `json:"bio"`
+type CodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// LoginMFA 登录的第二步，Authorization 里面带的是 LoginJWT 下发的 X-MFA-Token
+func (u *UserHandler) LoginMFA(ctx *gin.Context, req CodeReq) (ginx.Result, error) {
+	mc, err := u.ParseMFAToken(u.ExtractToken(ctx))
+	if err != nil || mc.UserAgent != ctx.Request.UserAgent() {
+		return ginx.Result{}, ginx.ErrUnauthorized
 	}
-	signedToken := u.ExtractToken(ctx)
-	var claims jwt2.UserClaims
-	token, err := jwt.ParseWithClaims(signedToken, &claims, func(token *jwt.Token) (interface{}, error) {
-		return jwt2.AccessTokenKey, nil
-	})
-	if err != nil || token == nil || !token.Valid {
-		ctx.JSON(http.StatusUnauthorized, Result{
-			Code: 5,
-			Msg:  "验证token失败",
-		})
-		return
+	// 同一个 token 只能试几次，成功一次之后就作废
+	err = u.UseMFAToken(ctx, mc)
+	if errors.Is(err, jwt2.ErrMFATokenExhausted) {
+		return ginx.Result{}, ginx.ErrUnauthorized
 	}
-	var req EditReq
-	if err := ctx.Bind(&req); err != nil {
-		return
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	user, err := u.svc.LoginMFA(ctx, mc.Uid, req.Code, ctx.ClientIP())
+	switch {
+	case errors.Is(err, service.ErrMFAInvalidCode):
+		u.recordLogin(ctx, mc.Uid, domain.LoginMethodMFA, domain.LoginOutcomeFailure)
+		return ginx.Result{}, err
+	case errors.Is(err, service.ErrAccountLocked), errors.Is(err, service.ErrLoginTooFrequent):
+		u.recordLogin(ctx, mc.Uid, domain.LoginMethodMFA, domain.LoginOutcomeLocked)
+		return ginx.Result{}, err
+	case errors.Is(err, service.ErrUserDisabled):
+		return ginx.Result{}, ginx.ErrUnauthorized
+	case err != nil:
+		return ginx.Result{}, err
+	}
+	err = u.ConsumeMFAToken(ctx, mc)
+	if err != nil {
+		return ginx.Result{}, err
 	}
-
-	if len(req.Nickname) > nicknameSize {
-		ctx.String(http.StatusOK, "nickname too long")
-		return
+	err = u.SetLoginToken(ctx, user)
+	if err != nil {
+		return ginx.Result{}, err
 	}
+	u.recordLogin(ctx, mc.Uid, domain.LoginMethodMFA, domain.LoginOutcomeSuccess)
+	return ginx.OK(msgLogin), nil
+}
+
+type UnlockReq struct {
+	Email string `json:"email" binding:"required,email" errcode:"400101"`
+	Code  string `json:"code"`
+}
+
+// SendUnlockCode 账号被锁定之后，可以通过绑定的手机号解锁。
+// 账号不存在、没有被锁定、没有绑定手机或者发送太频繁，都和发送成功返回一样的结果，
+// 避免被用来探测账号是否存在、是否被锁定
+func (u *UserHandler) SendUnlockCode(ctx *gin.Context, req UnlockReq) (ginx.Result, error) {
+	err := u.svc.SendUnlockCode(ctx, req.Email)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrUserNoFound),
+		errors.Is(err, service.ErrAccountNotLocked),
+		errors.Is(err, service.ErrAccountNoPhone),
+		errors.Is(err, service.ErrCodeSendTooMany):
+		zap.L().Info("没有发送解锁验证码", zap.Error(err))
+	default:
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgCodeSent), nil
+}
 
-	if len(req.Bio) > bioSize {
-		ctx.String(http.StatusOK, "bio too long")
-		return
+// UnlockAccount 同样不区分账号的状态，解锁不了统一返回验证码错误
+func (u *UserHandler) UnlockAccount(ctx *gin.Context, req UnlockReq) (ginx.Result, error) {
+	err := u.svc.UnlockAccount(ctx, req.Email, req.Code)
+	if errors.Is(err, ErrUserNoFound) ||
+		errors.Is(err, service.ErrAccountNotLocked) ||
+		errors.Is(err, service.ErrAccountNoPhone) {
+		return ginx.Result{}, errInvalidCode
 	}
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgUnlocked), nil
+}
+
+// EnrollTOTP 生成新的身份验证器密钥，前端用 uri 渲染二维码
+func (u *UserHandler) EnrollTOTP(ctx *gin.Context) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt2.UserClaims)
+	secret, uri, err := u.mfaSvc.Enroll(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.Result{
+		Data: TOTPVO{
+			Secret: secret,
+			URI:    uri,
+		},
+	}, nil
+}
+
+type TOTPVO struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
 
-	isBirth, err := u.birthRegexExp.MatchString(req.Birth)
+type RecoveryCodesVO struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
 
+// EnableTOTP 用身份验证器上的验证码确认绑定，恢复码只在这里返回一次
+func (u *UserHandler) EnableTOTP(ctx *gin.Context, req CodeReq) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt2.UserClaims)
+	codes, err := u.mfaSvc.Enable(ctx, uc.Uid, req.Code)
 	if err != nil {
-		ctx.String(http.StatusOK, "system error")
-		return
+		return ginx.Result{}, err
 	}
+	return ginx.OK(msgMFAEnabled).WithData(RecoveryCodesVO{RecoveryCodes: codes}), nil
+}
 
-	if !isBirth {
-		ctx.String(http.StatusOK, "birthday format wrong")
-		return
+// DisableTOTP 关闭二次验证同样需要验证码或者恢复码
+func (u *UserHandler) DisableTOTP(ctx *gin.Context, req CodeReq) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt2.UserClaims)
+	err := u.mfaSvc.Disable(ctx, uc.Uid, req.Code)
+	if err != nil {
+		return ginx.Result{}, err
 	}
+	return ginx.OK(msgMFADisabled), nil
+}
 
-	err = u.svc.EditProfile(ctx, domain.User{
+type EditReq struct {
+	Nickname string `json:"nickname" binding:"max=16" errcode:"400106"`
+	Birth    string `json:"birth" binding:"datetime=2006-01-02" errcode:"400108"`
+	Bio      string `json:"bio" binding:"max=256" errcode:"400107"`
+	// Locale 不传就不修改
+	Locale string `json:"locale" binding:"omitempty,locale" errcode:"400111"`
+}
+
+func (u *UserHandler) Edit(ctx *gin.Context, req EditReq) (ginx.Result, error) {
+	claims := ctx.MustGet("userClaims").(*jwt2.UserClaims)
+	// 已经校验过了，这里只是统一成 zh-CN 这种写法
+	locale, _ := i18n.Parse(req.Locale)
+
+	err := u.svc.EditProfile(ctx, domain.User{
 		Id:       claims.Uid,
 		Nickname: req.Nickname,
 		Birth:    req.Birth,
 		Bio:      req.Bio,
+		Locale:   string(locale),
 	})
-
 	if errors.Is(err, ErrUserNoFound) {
-		ctx.String(http.StatusOK, "system error, user no found")
-		return
+		return ginx.Result{}, errUserNotFound
 	}
-
-	ctx.String(http.StatusOK, "Update profile successful!")
-	return
-}
-
-func (u *UserHandler) Profile(ctx *gin.Context) {
-
-	type ProfileReq struct {
-		Email string `form:"email"`
+	if err != nil {
+		return ginx.Result{}, err
 	}
+	return ginx.OK(msgUpdated), nil
+}
 
-	var req ProfileReq
+type ProfileVO struct {
+	Nickname string `json:"nickname"`
+	Birth    string `json:"birth"`
+	Bio      string `json:"bio"`
+	Locale   string `json:"locale"`
+}
 
-	if err := ctx.Bind(&req); err != nil {
-		ctx.String(http.StatusOK, "system error")
-		return
-	}
+type ProfileReq struct {
+	Email string `form:"email"`
+}
 
+func (u *UserHandler) Profile(ctx *gin.Context, req ProfileReq) (ginx.Result, error) {
 	user, err := u.svc.FindProfile(ctx, req.Email)
-
 	if errors.Is(err, ErrUserNoFound) {
-		ctx.String(http.StatusOK, "profile not exist")
-		return
+		return ginx.Result{}, errUserNotFound
 	}
-
 	if err != nil {
-		ctx.String(http.StatusOK, "system error")
-		return
+		return ginx.Result{}, err
 	}
-
-	ctx.String(http.StatusOK, "nickname: %s, birthday: %s, bio: %s", user.Nickname, user.Birth, user.Bio)
-	return
+	return ginx.Result{
+		Data: ProfileVO{Nickname: user.Nickname, Birth: user.Birth, Bio: user.Bio, Locale: user.Locale},
+	}, nil
 }
 
-func (u *UserHandler) ProfileJWT(ctx *gin.Context) {
-	signedToken := u.ExtractToken(ctx)
-	var claims jwt2.UserClaims
-	token, err := jwt.ParseWithClaims(signedToken, &claims, func(token *jwt.Token) (interface{}, error) {
-		return jwt2.AccessTokenKey, nil
-	})
-	if err != nil || token == nil || !token.Valid {
-		ctx.JSON(http.StatusUnauthorized, Result{
-			Code: 5,
-			Msg:  "验证token失败",
-		})
-		return
-	}
+func (u *UserHandler) ProfileJWT(ctx *gin.Context) (ginx.Result, error) {
+	claims := ctx.MustGet("userClaims").(*jwt2.UserClaims)
 	user, err := u.svc.FindProfileJWT(ctx, claims.Uid)
-
 	if errors.Is(err, ErrUserNoFound) {
-		ctx.String(http.StatusOK, "profile not exist")
-		return
+		return ginx.Result{}, errUserNotFound
 	}
-
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		return
+		return ginx.Result{}, err
 	}
+	return ginx.Result{
+		Data: ProfileVO{Nickname: user.Nickname, Birth: user.Birth, Bio: user.Bio, Locale: user.Locale},
+	}, nil
+}
 
-	ctx.String(http.StatusOK, "nickname: %s, birthday: %s, bio: %s", user.Nickname, user.Birth, user.Bio)
-	return
+type SMSCodeReq struct {
+	Phone string `json:"phone" binding:"required,phone" errcode:"400104"`
+	Code  string `json:"code"`
 }
 
-func (u *UserHandler) SendLoginSMSCode(ctx *gin.Context) {
-	type Req struct {
-		Phone string `json:"phone"`
-	}
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
-	if req.Phone == "" {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 4,
-			Msg:  "输入有误",
-		})
-		return
-	}
-	// TODO: 对手机号进行校验，使用正则表达式
+func (u *UserHandler) SendLoginSMSCode(ctx *gin.Context, req SMSCodeReq) (ginx.Result, error) {
 	const biz = "user/login"
 	err := u.codeSvc.Send(ctx, biz, req.Phone)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		return
+		return ginx.Result{}, err
 	}
-	ctx.JSON(http.StatusOK, Result{
-		Msg: "发送成功",
-	})
+	return ginx.OK(msgCodeSent), nil
 }
 
-func (u *UserHandler) VerifyLoginSMSCode(ctx *gin.Context) {
-	type Req struct {
-		Phone string `json:"phone"`
-		Code  string `json:"code"`
-	}
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
+func (u *UserHandler) VerifyLoginSMSCode(ctx *gin.Context, req SMSCodeReq) (ginx.Result, error) {
 	const biz = "user/login"
 	ok, err := u.codeSvc.Verify(ctx, biz, req.Phone, req.Code)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		return
+		return ginx.Result{}, err
 	}
 	if !ok {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 4,
-			Msg:  "验证码有误",
-		})
+		u.recordLogin(ctx, 0, domain.LoginMethodSMS, domain.LoginOutcomeFailure)
+		return ginx.Result{}, errInvalidCode
 	}
 
 	user, err := u.svc.FindOrCreate(ctx, req.Phone)
+	if errors.Is(err, service.ErrUserDisabled) {
+		u.recordLogin(ctx, user.Id, domain.LoginMethodSMS, domain.LoginOutcomeFailure)
+	}
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		return
+		return ginx.Result{}, err
 	}
 
-	err = u.SetLoginToken(ctx, user.Id)
+	err = u.SetLoginToken(ctx, user)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		return
+		return ginx.Result{}, err
 	}
-	ctx.JSON(http.StatusOK, Result{
-		Code: 4,
-		Msg:  "校验验证码通过",
-	})
+	u.recordLogin(ctx, user.Id, domain.LoginMethodSMS, domain.LoginOutcomeSuccess)
+	return ginx.OK(msgLogin), nil
 }
 
-func (u *UserHandler) LogoutJWT(ctx *gin.Context) {
+func (u *UserHandler) LogoutJWT(ctx *gin.Context) (ginx.Result, error) {
 	err := u.ClearSession(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "退出登录失败",
-		})
-		return
+		return ginx.Result{}, err
 	}
-	ctx.JSON(http.StatusOK, Result{
-		Msg: "退出登录成功",
-	})
+	return ginx.OK(msgLogout), nil
 }
 
-func (u *UserHandler) RefreshToken(ctx *gin.Context) {
-	refreshToken := u.ExtractToken(ctx)
-	var rc jwt2.RefreshClaims
-	token, err := jwt.ParseWithClaims(refreshToken, &rc, func(token *jwt.Token) (interface{}, error) {
-		return jwt2.RefreshTokenKey, nil
-	})
-	if err != nil || token == nil || !token.Valid {
-		ctx.AbortWithStatus(http.StatusUnauthorized)
-		return
+func (u *UserHandler) RefreshToken(ctx *gin.Context) (ginx.Result, error) {
+	rc, err := u.ParseRefreshToken(u.ExtractToken(ctx))
+	if err != nil {
+		return ginx.Result{}, ginx.ErrUnauthorized
+	}
+	err = u.CheckSession(ctx, rc.Ssid)
+	if err != nil {
+		return ginx.Result{}, ginx.ErrUnauthorized
 	}
-	err = u.cmd.Exists(ctx, fmt.Sprintf("users:ssid:%s", rc.Ssid)).Err()
+	// refresh token 也一起轮换
+	err = u.RotateRefreshToken(ctx, rc)
+	if errors.Is(err, jwt2.ErrRefreshTokenReused) {
+		zap.L().Warn("refresh token 被重放，吊销 session",
+			zap.Int64("uid", rc.Uid), zap.String("ssid", rc.Ssid))
+	}
+	if err != nil {
+		u.recordLogin(ctx, rc.Uid, domain.LoginMethodRefresh, domain.LoginOutcomeFailure)
+		return ginx.Result{}, ginx.ErrUnauthorized
+	}
+	// 每次刷新都重新加载用户，禁用和角色变更在这里生效
+	user, err := u.svc.FindProfileJWT(ctx, rc.Uid)
 	if err != nil {
-		ctx.AbortWithStatus(http.StatusUnauthorized)
-		return
+		return ginx.Result{}, ginx.ErrUnauthorized
+	}
+	if !user.Status.CanLogin() {
+		_ = u.RevokeSession(ctx, rc.Uid, rc.Ssid)
+		return ginx.Result{}, ginx.ErrUnauthorized
 	}
 	// 刷新 AccessToken
-	err = u.SetAccessToken(ctx, rc.Uid, rc.Ssid)
+	err = u.SetAccessToken(ctx, user, rc.Ssid)
 	if err != nil {
-		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return ginx.Result{}, err
 	}
-	ctx.JSON(http.StatusOK, Result{
-		Msg: "刷新成功",
+	err = u.TouchSession(ctx, rc.Uid, rc.Ssid)
+	if err != nil {
+		// 只影响设备列表里面的活跃时间，不影响刷新
+		zap.L().Warn("更新设备活跃时间失败", zap.Int64("uid", rc.Uid), zap.Error(err))
+	}
+	u.recordLogin(ctx, rc.Uid, domain.LoginMethodRefresh, domain.LoginOutcomeSuccess)
+	return ginx.OK(msgRefreshed), nil
+}
+
+type SessionVO struct {
+	jwt2.Session
+	Current bool `json:"current"`
+}
+
+// Sessions 列出当前账号所有登录中的设备
+func (u *UserHandler) Sessions(ctx *gin.Context) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt2.UserClaims)
+	sessions, err := u.ListSessions(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	res := make([]SessionVO, 0, len(sessions))
+	for _, sess := range sessions {
+		res = append(res, SessionVO{
+			Session: sess,
+			Current: sess.Ssid == uc.Ssid,
+		})
+	}
+	return ginx.Result{Data: res}, nil
+}
+
+type SessionReq struct {
+	Ssid string `json:"ssid" binding:"required"`
+}
+
+// LogoutSession 远程退出某台设备
+func (u *UserHandler) LogoutSession(ctx *gin.Context, req SessionReq) (ginx.Result, error) {
+	uc := ctx.MustGet("userClaims").(*jwt2.UserClaims)
+	// 先确认这个 ssid 属于当前用户，避免把别人的设备踢下线
+	sessions, err := u.ListSessions(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	found := false
+	for _, sess := range sessions {
+		if sess.Ssid == req.Ssid {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ginx.Result{}, errSessionNotFound
+	}
+	err = u.RevokeSession(ctx, uc.Uid, req.Ssid)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	return ginx.OK(msgSessionRevoked), nil
+}
+
+type PageReq struct {
+	Offset int `form:"offset" binding:"min=0"`
+	// Limit 不传或者超过 100 就用默认的 20
+	Limit int `form:"limit"`
+}
+
+type LoginLogVO struct {
+	IP         string `json:"ip"`
+	UserAgent  string `json:"userAgent"`
+	Method     string `json:"method"`
+	Outcome    string `json:"outcome"`
+	Country    string `json:"country"`
+	Suspicious bool   `json:"suspicious"`
+	Ctime      int64  `json:"ctime"`
+}
+
+// LoginHistory 查看自己账号的登录记录
+func (u *UserHandler) LoginHistory(ctx *gin.Context, req PageReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	uc := ctx.MustGet("userClaims").(*jwt2.UserClaims)
+	logs, err := u.loginLogSvc.List(ctx, uc.Uid, req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{}, err
+	}
+	res := make([]LoginLogVO, 0, len(logs))
+	for _, l := range logs {
+		res = append(res, LoginLogVO{
+			IP:         l.IP,
+			UserAgent:  l.UserAgent,
+			Method:     string(l.Method),
+			Outcome:    string(l.Outcome),
+			Country:    l.Country,
+			Suspicious: l.Suspicious,
+			Ctime:      l.Ctime.UnixMilli(),
+		})
+	}
+	return ginx.Result{Data: res}, nil
+}
+
+// recordLogin 审计日志写失败不能影响登录本身
+func (u *UserHandler) recordLogin(ctx *gin.Context, uid int64,
+	method domain.LoginMethod, outcome domain.LoginOutcome) {
+	err := u.loginLogSvc.Record(ctx, domain.LoginLog{
+		Uid:       uid,
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Method:    method,
+		Outcome:   outcome,
 	})
+	if err != nil {
+		zap.L().Error("记录登录日志失败", zap.Int64("uid", uid),
+			zap.String("method", string(method)), zap.Error(err))
+	}
 }
@@ -3,11 +3,13 @@ package web
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/skcheng003/webook/internal/domain"
 	"github.com/skcheng003/webook/internal/service"
 	svcmocks "github.com/skcheng003/webook/internal/service/mocks"
+	"github.com/skcheng003/webook/pkg/ginx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -34,7 +36,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 		mock       func(ctrl *gomock.Controller) (service.UserService, service.CodeService)
 		reqBody    string
 		expectCode int
-		expectBody string
+		expectBody ginx.Result
 	}{
 		{
 			name: "注册成功",
@@ -54,7 +56,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 }
 `,
 			expectCode: http.StatusOK,
-			expectBody: "注册成功",
+			expectBody: ginx.Result{Msg: "注册成功"},
 		},
 		{
 			name: "参数不对，Bind失败",
@@ -69,6 +71,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 }
 `,
 			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400000, Msg: "输入有误"},
 		},
 		{
 			name: "邮箱格式错误",
@@ -83,8 +86,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 	"confirmPassword": "hello#world123"
 }
 `,
-			expectCode: http.StatusOK,
-			expectBody: "邮箱格式错误",
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400101, Msg: "邮箱格式错误"},
 		},
 		{
 			name: "两次输入密码不一致",
@@ -99,8 +102,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 	"confirmPassword": "hello#world124"
 }
 `,
-			expectCode: http.StatusOK,
-			expectBody: "两次输入密码不一致",
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400102, Msg: "两次输入密码不一致"},
 		},
 		{
 			name: "密码必须包含数字、特殊字符，并且长度不能小于8位",
@@ -115,8 +118,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 	"confirmPassword": "hello"
 }
 `,
-			expectCode: http.StatusOK,
-			expectBody: "密码必须包含数字、特殊字符，并且长度不能小于8位",
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400103, Msg: "密码必须包含数字、特殊字符，并且长度不能小于8位"},
 		},
 		{
 			name: "邮箱地址冲突",
@@ -135,8 +138,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 	"confirmPassword": "hello#world123"
 }
 `,
-			expectCode: http.StatusOK,
-			expectBody: "邮箱地址冲突",
+			expectCode: http.StatusConflict,
+			expectBody: ginx.Result{Code: 409101, Msg: "邮箱地址冲突"},
 		},
 		{
 			name: "系统错误",
@@ -155,8 +158,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 	"confirmPassword": "hello#world123"
 }
 `,
-			expectCode: http.StatusOK,
-			expectBody: "系统错误",
+			expectCode: http.StatusInternalServerError,
+			expectBody: ginx.Result{Code: 500000, Msg: "系统错误"},
 		},
 	}
 
@@ -167,7 +170,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			defer ctrl.Finish()
 			// 注册路由
 			userSvc, codeSvc := tc.mock(ctrl)
-			h := NewUserHandler(userSvc, codeSvc, nil)
+			h := NewUserHandler(userSvc, codeSvc, nil, nil, nil)
 			h.RegisterRoutes(server)
 			// 构造请求
 			req, err := http.NewRequest(http.MethodPost, "/users/signup",
@@ -182,7 +185,10 @@ func TestUserHandler_SignUp(t *testing.T) {
 			server.ServeHTTP(resp, req)
 
 			assert.Equal(t, tc.expectCode, resp.Code)
-			assert.Equal(t, tc.expectBody, resp.Body.String())
+			var res ginx.Result
+			err = json.Unmarshal(resp.Body.Bytes(), &res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectBody, res)
 		})
 	}
 }
@@ -199,3 +205,240 @@ func TestMock(t *testing.T) {
 	})
 	t.Log(err)
 }
+
+func TestUserHandler_SendLoginSMSCode(t *testing.T) {
+	testCases := []struct {
+		name       string
+		mock       func(ctrl *gomock.Controller) (service.UserService, service.CodeService)
+		reqBody    string
+		lang       string
+		expectCode int
+		expectBody ginx.Result
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Send(gomock.Any(), "user/login", "+8613800138000").Return(nil)
+				return nil, codeSvc
+			},
+			reqBody:    `{"phone": "+8613800138000"}`,
+			expectCode: http.StatusOK,
+			expectBody: ginx.Result{Msg: "发送成功"},
+		},
+		{
+			name: "没有国家码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				return nil, svcmocks.NewMockCodeService(ctrl)
+			},
+			reqBody:    `{"phone": "13800138000"}`,
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400104, Msg: "手机号格式错误，需要带国家码，比如 +8613800138000"},
+		},
+		{
+			name: "号码太长",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				return nil, svcmocks.NewMockCodeService(ctrl)
+			},
+			reqBody:    `{"phone": "+8613800138000123"}`,
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400104, Msg: "手机号格式错误，需要带国家码，比如 +8613800138000"},
+		},
+		{
+			name: "英文提示",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				return nil, svcmocks.NewMockCodeService(ctrl)
+			},
+			reqBody:    `{"phone": "13800138000"}`,
+			lang:       "en-US,en;q=0.9",
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400104, Msg: "Invalid phone number, include the country code, e.g. +8613800138000"},
+		},
+		{
+			name: "手机号为空",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				return nil, svcmocks.NewMockCodeService(ctrl)
+			},
+			reqBody:    `{"phone": ""}`,
+			expectCode: http.StatusBadRequest,
+			expectBody: ginx.Result{Code: 400000, Msg: "输入有误"},
+		},
+		{
+			name: "发送失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Send(gomock.Any(), "user/login", "+12025550123").
+					Return(errors.New("短信服务不可用"))
+				return nil, codeSvc
+			},
+			reqBody:    `{"phone": "+12025550123"}`,
+			expectCode: http.StatusInternalServerError,
+			expectBody: ginx.Result{Code: 500000, Msg: "系统错误"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.Default()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, codeSvc := tc.mock(ctrl)
+			h := NewUserHandler(userSvc, codeSvc, nil, nil, nil)
+			h.RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/users/login_sms/code/send",
+				bytes.NewBuffer([]byte(tc.reqBody)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", tc.lang)
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.expectCode, resp.Code)
+			var res ginx.Result
+			err = json.Unmarshal(resp.Body.Bytes(), &res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectBody, res)
+		})
+	}
+}
+
+func TestUserHandler_VerifyLoginSMSCode(t *testing.T) {
+	testCases := []struct {
+		name       string
+		mock       func(ctrl *gomock.Controller) service.CodeService
+		reqBody    string
+		expectCode int
+		expectBody ginx.Result
+	}{
+		{
+			name: "验证次数用完",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "user/login", "+8613800138000", "123456").
+					Return(false, service.ErrCodeVerifyTooManyTimes)
+				return codeSvc
+			},
+			reqBody:    `{"phone": "+8613800138000", "code": "123456"}`,
+			expectCode: http.StatusTooManyRequests,
+			expectBody: ginx.Result{Code: 429104, Msg: "验证码错误次数太多，请重新获取"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.Default()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			h := NewUserHandler(nil, tc.mock(ctrl), nil, nil, nil)
+			h.RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/users/login_sms",
+				bytes.NewBuffer([]byte(tc.reqBody)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.expectCode, resp.Code)
+			var res ginx.Result
+			err = json.Unmarshal(resp.Body.Bytes(), &res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectBody, res)
+		})
+	}
+}
+
+func TestUserHandler_SendUnlockCode(t *testing.T) {
+	sent := ginx.Result{Msg: "发送成功"}
+	testCases := []struct {
+		name       string
+		mock       func(ctrl *gomock.Controller) service.UserService
+		expectCode int
+		expectBody ginx.Result
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().SendUnlockCode(gomock.Any(), "123@qq.com").Return(nil)
+				return userSvc
+			},
+			expectCode: http.StatusOK,
+			expectBody: sent,
+		},
+		{
+			name: "账号不存在",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().SendUnlockCode(gomock.Any(), "123@qq.com").Return(service.ErrUserNoFound)
+				return userSvc
+			},
+			expectCode: http.StatusOK,
+			expectBody: sent,
+		},
+		{
+			name: "账号没有被锁定",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().SendUnlockCode(gomock.Any(), "123@qq.com").Return(service.ErrAccountNotLocked)
+				return userSvc
+			},
+			expectCode: http.StatusOK,
+			expectBody: sent,
+		},
+		{
+			name: "没有绑定手机",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().SendUnlockCode(gomock.Any(), "123@qq.com").Return(service.ErrAccountNoPhone)
+				return userSvc
+			},
+			expectCode: http.StatusOK,
+			expectBody: sent,
+		},
+		{
+			name: "发送太频繁",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().SendUnlockCode(gomock.Any(), "123@qq.com").Return(service.ErrCodeSendTooMany)
+				return userSvc
+			},
+			expectCode: http.StatusOK,
+			expectBody: sent,
+		},
+		{
+			name: "系统错误",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().SendUnlockCode(gomock.Any(), "123@qq.com").Return(errors.New("db 错误"))
+				return userSvc
+			},
+			expectCode: http.StatusInternalServerError,
+			expectBody: ginx.Result{Code: 500000, Msg: "系统错误"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.Default()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			h := NewUserHandler(tc.mock(ctrl), nil, nil, nil, nil)
+			h.RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/users/login/unlock/code/send",
+				bytes.NewBuffer([]byte(`{"email": "123@qq.com"}`)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.expectCode, resp.Code)
+			var res ginx.Result
+			err = json.Unmarshal(resp.Body.Bytes(), &res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectBody, res)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	regexp "github.com/dlclark/regexp2"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"github.com/skcheng003/webook/pkg/openapi"
+)
+
+const (
+	// passwordRegexPattern 标准库的 regexp 不支持零宽断言，所以用 regexp2
+	passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,72}$`
+)
+
+var (
+	passwordRegexExp = regexp.MustCompile(passwordRegexPattern, regexp.None)
+	phoneTagRegexExp = regexp.MustCompile(phoneRegexPattern, regexp.None)
+)
+
+// 自定义的校验规则，请求结构体里面直接写在 binding 标签上，
+// 同时告诉 OpenAPI 文档怎么描述这些规则
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	_ = v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		ok, err := passwordRegexExp.MatchString(fl.Field().String())
+		return err == nil && ok
+	})
+	_ = v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		ok, err := phoneTagRegexExp.MatchString(fl.Field().String())
+		return err == nil && ok
+	})
+	_ = v.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		_, ok := i18n.Parse(fl.Field().String())
+		return ok
+	})
+
+	openapi.RegisterTag("password", func(s *openapi.Schema, param string) {
+		s.Format = "password"
+		s.Pattern = passwordRegexPattern
+		s.Description = "至少 8 位，必须包含字母、数字和特殊字符"
+	})
+	openapi.RegisterTag("phone", func(s *openapi.Schema, param string) {
+		s.Pattern = phoneRegexPattern
+		s.Description = "E.164 格式，比如 +8613800138000"
+	})
+	openapi.RegisterTag("locale", func(s *openapi.Schema, param string) {
+		s.Enum = []string{string(i18n.ZhCN), string(i18n.EnUS)}
+	})
+}
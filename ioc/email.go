@@ -0,0 +1,10 @@
+package ioc
+
+import (
+	"github.com/skcheng003/webook/internal/service/email"
+	"github.com/skcheng003/webook/internal/service/email/memory"
+)
+
+func InitEmailService() email.Service {
+	return memory.NewService()
+}
@@ -0,0 +1,30 @@
+package ioc
+
+import (
+	grpc2 "github.com/skcheng003/webook/internal/grpc"
+	jwt2 "github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/skcheng003/webook/pkg/grpcx/interceptors/logging"
+	grpcratelimit "github.com/skcheng003/webook/pkg/grpcx/interceptors/ratelimit"
+	"github.com/skcheng003/webook/pkg/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func InitGRPCServer(userSrv *grpc2.UserServiceServer, artSrv *grpc2.ArticleServiceServer,
+	jwtHdl jwt2.Handler, limiter ratelimit.Limiter) *grpc.Server {
+	auth := grpc2.NewAuthInterceptorBuilder(jwtHdl).IgnoreMethod(userSrv.PublicMethods()...)
+	for method, perm := range userSrv.Permissions() {
+		auth.RequirePermission(method, perm)
+	}
+	for method, perm := range artSrv.Permissions() {
+		auth.RequirePermission(method, perm)
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		logging.NewBuilder(zap.L()).Build(),
+		grpcratelimit.NewBuilder(limiter).Prefix("grpc-limiter").Build(),
+		auth.Build(),
+	))
+	userSrv.Register(server)
+	artSrv.Register(server)
+	return server
+}
@@ -0,0 +1,27 @@
+package ioc
+
+import (
+	"github.com/skcheng003/webook/internal/web/jwt"
+	"github.com/spf13/viper"
+)
+
+func InitJWTKeys() jwt.Keys {
+	return jwt.Keys{
+		Access:  initKeySet("jwt.access"),
+		Refresh: initKeySet("jwt.refresh"),
+		MFA:     initKeySet("jwt.mfa"),
+	}
+}
+
+func initKeySet(key string) *jwt.KeySet {
+	var cfg jwt.KeySetConfig
+	err := viper.UnmarshalKey(key, &cfg)
+	if err != nil {
+		panic(err)
+	}
+	ks, err := jwt.NewKeySet(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return ks
+}
@@ -0,0 +1,12 @@
+package ioc
+
+import (
+	"github.com/redis/go-redis/v9"
+	"github.com/skcheng003/webook/pkg/ratelimit"
+	"time"
+)
+
+func InitLimiter(cmd redis.Cmdable) ratelimit.Limiter {
+	// 每秒最多 100 个请求
+	return ratelimit.NewRedisSlidingWindowLimiter(cmd, 100, time.Second)
+}
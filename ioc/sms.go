@@ -1,10 +1,269 @@
 package ioc
 
 import (
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/internal/repository/dao"
+	"github.com/skcheng003/webook/internal/service"
 	"github.com/skcheng003/webook/internal/service/sms"
+	"github.com/skcheng003/webook/internal/service/sms/aliyun"
+	"github.com/skcheng003/webook/internal/service/sms/async"
+	"github.com/skcheng003/webook/internal/service/sms/auth"
+	smscb "github.com/skcheng003/webook/internal/service/sms/circuitbreaker"
+	"github.com/skcheng003/webook/internal/service/sms/failover"
 	"github.com/skcheng003/webook/internal/service/sms/memory"
+	smsratelimit "github.com/skcheng003/webook/internal/service/sms/ratelimit"
+	"github.com/skcheng003/webook/internal/service/sms/template"
+	"github.com/skcheng003/webook/internal/service/sms/tencent"
+	"github.com/skcheng003/webook/internal/service/sms/tracking"
+	"github.com/skcheng003/webook/internal/service/sms/webhook"
+	"github.com/skcheng003/webook/pkg/circuitbreaker"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"github.com/skcheng003/webook/pkg/ratelimit"
+	"github.com/spf13/viper"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tencentsms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
 )
 
-func InitSMSService() sms.Service {
-	return memory.NewService()
+// InitSMSService 最外层是异步重试，被限流或者服务商出问题的短信存起来稍后重发，
+// 里面先限流，再在多个服务商之间切换。每个服务商自己先查模板，再熔断，最里面记录发送结果
+func InitSMSService(limiter ratelimit.Limiter, repo repository.AsyncSMSRepository,
+	cfg async.Config, registry *template.Registry, providers []SMSProvider,
+	deliveryRepo repository.SMSDeliveryRepository) *async.Service {
+	breakerCfg := initSMSBreakerConfig()
+	svcs := make([]sms.Service, 0, len(providers))
+	for _, p := range providers {
+		var svc sms.Service = tracking.NewService(p.Svc, p.Name, deliveryRepo)
+		// 每个服务商单独熔断，熔断之后 failover 会切换到下一个
+		breaker := circuitbreaker.NewBreaker("sms."+p.Name, breakerCfg).
+			Observe(circuitbreaker.NewLogObserver(zap.L()), circuitbreaker.NewExpvarObserver())
+		svc = smscb.NewCircuitBreakerSMSService(svc, breaker)
+		svcs = append(svcs, template.NewService(p.Name, svc, registry))
+	}
+	// 连续超时 3 次就换下一个服务商
+	var svc sms.Service = failover.NewTimeoutFailoverSMSService(svcs, 3)
+	svc = smsratelimit.NewRateLimitSMSService(svc, limiter)
+	return async.NewService(svc, repo, cfg)
+}
+
+// smsProviderConfig sms.providers 里面的一项，type 决定用哪些字段
+type smsProviderConfig struct {
+	// Type memory / tencent / aliyun / webhook
+	Type string
+	// Name 查模板的时候用，不配置就是 Type，同一种服务商配了多个账号的时候要区分开
+	Name    string
+	Timeout time.Duration
+	// 腾讯云
+	SecretId  string
+	SecretKey string
+	Region    string
+	AppId     string
+	SignName  string
+	// 阿里云
+	AccessKeyId     string
+	AccessKeySecret string
+	Endpoint        string
+	// Templates 模板参数名，viper 会把 map 的 key 转成小写，所以模板 id 不能当 key
+	Templates []struct {
+		Id     string
+		Params []string
+	}
+	// webhook
+	URL     string
+	Secret  string
+	Headers map[string]string
+}
+
+// SMSProvider 没有经过任何装饰的服务商，发短信和解析回执都要用
+type SMSProvider struct {
+	Name string
+	Svc  sms.Service
+}
+
+// InitSMSProviders 按照配置的顺序创建服务商，没有配置就用打印到控制台的 memory
+func InitSMSProviders() []SMSProvider {
+	var cfgs []smsProviderConfig
+	err := viper.UnmarshalKey("sms.providers", &cfgs)
+	if err != nil {
+		panic(err)
+	}
+	if len(cfgs) == 0 {
+		cfgs = []smsProviderConfig{{Type: "memory"}}
+	}
+	res := make([]SMSProvider, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Timeout == 0 {
+			cfg.Timeout = time.Second * 5
+		}
+		if cfg.Name == "" {
+			cfg.Name = cfg.Type
+		}
+		client := &http.Client{Timeout: cfg.Timeout}
+		var svc sms.Service
+		switch cfg.Type {
+		case "memory":
+			svc = memory.NewService()
+		case "tencent":
+			c, err := tencentsms.NewClient(common.NewCredential(cfg.SecretId, cfg.SecretKey),
+				cfg.Region, profile.NewClientProfile())
+			if err != nil {
+				panic(err)
+			}
+			svc = tencent.NewService(c, cfg.AppId, cfg.SignName)
+		case "aliyun":
+			paramNames := make(map[string][]string, len(cfg.Templates))
+			for _, tpl := range cfg.Templates {
+				paramNames[tpl.Id] = tpl.Params
+			}
+			svc = aliyun.NewService(client, aliyun.Config{
+				Endpoint:        cfg.Endpoint,
+				AccessKeyId:     cfg.AccessKeyId,
+				AccessKeySecret: cfg.AccessKeySecret,
+				SignName:        cfg.SignName,
+				ParamNames:      paramNames,
+			})
+		case "webhook":
+			svc = webhook.NewService(client, webhook.Config{
+				URL:     cfg.URL,
+				Secret:  cfg.Secret,
+				Headers: cfg.Headers,
+			})
+		default:
+			panic(fmt.Sprintf("不支持的短信服务商 %s", cfg.Type))
+		}
+		res = append(res, SMSProvider{Name: cfg.Name, Svc: svc})
+	}
+	return res
+}
+
+// InitSMSDeliveryRepository 手机号用 HMAC 之后的值查询，密钥不能泄露
+func InitSMSDeliveryRepository(d dao.SMSDeliveryDAO) repository.SMSDeliveryRepository {
+	secret := viper.GetString("sms.tracking.secret")
+	if secret == "" {
+		panic("sms.tracking.secret 没有配置")
+	}
+	return repository.NewSMSDeliveryRepository(d, secret)
+}
+
+func InitSMSDeliveryConfig(providers []SMSProvider) service.SMSDeliveryConfig {
+	cfg := service.SMSDeliveryConfig{
+		CallbackToken: viper.GetString("sms.tracking.callbackToken"),
+		Parsers:       make(map[string]sms.ReceiptParser, len(providers)),
+	}
+	for _, p := range providers {
+		if parser, ok := p.Svc.(sms.ReceiptParser); ok {
+			cfg.Parsers[p.Name] = parser
+		}
+	}
+	return cfg
+}
+
+// requiredSMSBiz 我们自己发验证码的业务场景，缺了模板用户就登录不了、解锁不了，启动的时候就要发现
+var requiredSMSBiz = []string{"user/login", "user/unlock", "user/bind_phone", "user/merge_phone", "user/rebind"}
+
+// InitSMSTemplateRegistry 每个业务场景在每个服务商那边都要有模板，
+// 找不到对应语言的模板会用中文的，所以 requiredSMSBiz 在每个服务商那边都必须有中文模板
+func InitSMSTemplateRegistry(providers []SMSProvider) *template.Registry {
+	var cfgs []struct {
+		Biz      string
+		Provider string
+		Locale   string
+		Id       string
+		Params   int
+	}
+	err := viper.UnmarshalKey("sms.templates", &cfgs)
+	if err != nil {
+		panic(err)
+	}
+	tpls := make([]template.Template, 0, len(cfgs))
+	for _, c := range cfgs {
+		locale, ok := i18n.Parse(c.Locale)
+		if !ok {
+			panic(fmt.Sprintf("短信模板 %s 的语言 %s 不支持", c.Id, c.Locale))
+		}
+		tpls = append(tpls, template.Template{
+			Biz:        c.Biz,
+			Provider:   c.Provider,
+			Locale:     locale,
+			Id:         c.Id,
+			ParamCount: c.Params,
+		})
+	}
+	registry := template.NewRegistry(tpls)
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.Name)
+	}
+	err = registry.Check(requiredSMSBiz, names)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+// InitSMSGatewayService 给其它业务方用的短信网关，token 用单独的密钥签发
+func InitSMSGatewayService(svc sms.Service, cmd redis.Cmdable,
+	tokens repository.SMSTokenRepository) *auth.Service {
+	type Quota struct {
+		Biz      string
+		Rate     int
+		Interval time.Duration
+	}
+	cfg := struct {
+		Rate     int
+		Interval time.Duration
+		Quotas   []Quota
+	}{
+		Rate:     100,
+		Interval: time.Minute,
+	}
+	err := viper.UnmarshalKey("sms.gateway", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	res := auth.NewService(svc, initKeySet("jwt.smsGateway"),
+		ratelimit.NewRedisSlidingWindowLimiter(cmd, cfg.Rate, cfg.Interval), tokens)
+	for _, q := range cfg.Quotas {
+		res.Quota(q.Biz, ratelimit.NewRedisSlidingWindowLimiter(cmd, q.Rate, q.Interval))
+	}
+	return res
+}
+
+func initSMSBreakerConfig() circuitbreaker.Config {
+	cfg := circuitbreaker.Config{
+		Window:       time.Second * 30,
+		Buckets:      10,
+		MinRequests:  10,
+		FailureRatio: 0.5,
+		Cooldown:     time.Second * 30,
+		HalfOpenMax:  3,
+	}
+	err := viper.UnmarshalKey("sms.breaker", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+func InitAsyncSMSConfig() async.Config {
+	cfg := async.Config{
+		WindowSize:  100,
+		MaxErrRate:  0.1,
+		MaxLatency:  time.Second,
+		RetryMax:    3,
+		BaseBackoff: time.Second * 10,
+		MaxBackoff:  time.Minute * 5,
+		// 验证码模板都是 user/ 开头的
+		SyncOnly: []string{"user/"},
+	}
+	err := viper.UnmarshalKey("sms.async", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
 }
@@ -0,0 +1,161 @@
+package ioc
+
+import (
+	"fmt"
+	"github.com/skcheng003/webook/internal/domain"
+	"github.com/skcheng003/webook/internal/repository"
+	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/pkg/geoip"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// InitIPLocator login.geoip 是 dbip-country-lite 格式的 CSV，
+// 没有配置的时候识别不了地区，可疑登录只按设备判断
+func InitIPLocator() service.IPLocator {
+	path := viper.GetString("login.geoip")
+	if path == "" {
+		zap.L().Warn("没有配置 login.geoip，可疑登录只按设备判断")
+		return service.NewNopIPLocator()
+	}
+	l, err := geoip.LoadCSV(path)
+	if err != nil {
+		panic(fmt.Errorf("加载 IP 库 %s 失败: %w", path, err))
+	}
+	return l
+}
+
+func InitLockoutConfig() service.LockoutConfig {
+	cfg := service.LockoutConfig{
+		Threshold:   5,
+		IPThreshold: 50,
+		Window:      time.Minute * 15,
+		BaseLock:    time.Minute * 5,
+		MaxLock:     time.Hour * 24,
+		IPLock:      time.Hour,
+	}
+	err := viper.UnmarshalKey("login.lockout", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+func InitCodeLimitConfig() service.CodeLimitConfig {
+	cfg := service.CodeLimitConfig{
+		Daily: repository.CodeQuotaLimit{
+			PhoneBiz: 10,
+			Phone:    20,
+			IP:       100,
+		},
+	}
+	err := viper.UnmarshalKey("code.limit", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// InitCodePolicies 业务场景没有配置的字段用 default 的
+func InitCodePolicies() service.CodePolicies {
+	type bizPolicy struct {
+		Biz               string
+		domain.CodePolicy `mapstructure:",squash"`
+	}
+	cfg := struct {
+		Default domain.CodePolicy
+		Biz     []bizPolicy
+	}{
+		Default: domain.CodePolicy{
+			Length:         6,
+			Expiration:     time.Minute * 10,
+			ResendInterval: time.Minute,
+			MaxAttempts:    3,
+		},
+	}
+	err := viper.UnmarshalKey("code.policy", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	res := service.CodePolicies{
+		Default: checkCodePolicy("default", cfg.Default),
+		Biz:     make(map[string]domain.CodePolicy, len(cfg.Biz)),
+	}
+	for _, b := range cfg.Biz {
+		p := b.CodePolicy
+		if p.Length == 0 {
+			p.Length = cfg.Default.Length
+		}
+		if p.Expiration == 0 {
+			p.Expiration = cfg.Default.Expiration
+		}
+		if p.ResendInterval == 0 {
+			p.ResendInterval = cfg.Default.ResendInterval
+		}
+		if p.MaxAttempts == 0 {
+			p.MaxAttempts = cfg.Default.MaxAttempts
+		}
+		res.Biz[b.Biz] = checkCodePolicy(b.Biz, p)
+	}
+	return res
+}
+
+func checkCodePolicy(biz string, p domain.CodePolicy) domain.CodePolicy {
+	switch {
+	case p.Length < 4 || p.Length > 10:
+		panic(fmt.Sprintf("验证码策略 %s 的长度必须在 4 到 10 之间", biz))
+	case p.Expiration < time.Second || p.ResendInterval > p.Expiration:
+		panic(fmt.Sprintf("验证码策略 %s 的有效期必须大于重发间隔", biz))
+	case p.MaxAttempts <= 0:
+		panic(fmt.Sprintf("验证码策略 %s 的验证次数必须大于 0", biz))
+	}
+	return p
+}
+
+func InitCaptchaConfig() service.CaptchaConfig {
+	cfg := service.CaptchaConfig{
+		TTL:       time.Minute * 5,
+		Threshold: 5,
+		Window:    time.Hour,
+	}
+	err := viper.UnmarshalKey("captcha", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+func InitDeletionConfig() service.DeletionConfig {
+	cfg := service.DeletionConfig{
+		GracePeriod:   time.Hour * 24 * 15,
+		ArticlePolicy: service.ArticlePolicyHide,
+		BatchSize:     100,
+	}
+	err := viper.UnmarshalKey("account.deletion", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if !cfg.ArticlePolicy.Valid() {
+		panic(fmt.Sprintf("account.deletion.articlePolicy 只能是 keep / hide / delete，配置的是 %q", cfg.ArticlePolicy))
+	}
+	return cfg
+}
+
+func InitExportConfig() service.ExportConfig {
+	cfg := service.ExportConfig{
+		Dir:     filepath.Join(os.TempDir(), "webook-export"),
+		LinkTTL: time.Hour * 24,
+		BaseURL: "http://localhost:8081",
+	}
+	err := viper.UnmarshalKey("export", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Secret == "" {
+		panic("export.secret 没有配置")
+	}
+	return cfg
+}
@@ -4,28 +4,79 @@ import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/memstore"
 	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/service"
 	"github.com/skcheng003/webook/internal/web"
 	jwt2 "github.com/skcheng003/webook/internal/web/jwt"
 	"github.com/skcheng003/webook/internal/web/middleware"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"github.com/spf13/viper"
 )
 
-func InitGinServer(middlewares []gin.HandlerFunc, handler *web.UserHandler) *gin.Engine {
-	server := gin.Default()
-	handler.RegisterRoutes(server)
+func InitGinServer(middlewares []gin.HandlerFunc, handler *web.UserHandler,
+	articleHdl *web.ArticleHandler, adminHdl *web.AdminHandler, accountHdl *web.AccountHandler,
+	exportHdl *web.ExportHandler, contactHdl *web.ContactHandler,
+	smsGatewayHdl *web.SMSGatewayHandler, smsDeliveryHdl *web.SMSDeliveryHandler,
+	captchaHdl *web.CaptchaHandler, jwksHdl *web.JWKSHandler) *gin.Engine {
+	type Config struct {
+		// TrustedProxies 前面反向代理的地址或者网段，只有从这些地址过来的请求才看 X-Forwarded-For，
+		// 不配置就一个都不信，ClientIP 直接用连接的对端地址，避免被伪造 IP 绕过按 IP 的限制
+		TrustedProxies []string `yaml:"trustedProxies"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("web", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	server := gin.New()
+	// 回执地址里面带着服务商的 token，导出的下载地址带着签名，都不能写进访问日志
+	server.Use(middleware.NewAccessLogMiddlewareBuilder().
+		RedactPrefix("/sms/receipts/", "/users/export/download").Build(),
+		gin.Recovery())
+	err = server.SetTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+	// 中间件必须在注册路由之前 Use，否则对已经注册的路由不生效
 	server.Use(middlewares...)
+	handler.RegisterRoutes(server)
+	articleHdl.RegisterRoutes(server)
+	adminHdl.RegisterRoutes(server)
+	accountHdl.RegisterRoutes(server)
+	exportHdl.RegisterRoutes(server)
+	contactHdl.RegisterRoutes(server)
+	smsGatewayHdl.RegisterRoutes(server)
+	smsDeliveryHdl.RegisterRoutes(server)
+	captchaHdl.RegisterRoutes(server)
+	jwksHdl.RegisterRoutes(server)
+	server.GET("/openapi.json", openapi.Default.Handler())
 	return server
 }
 
-func InitMiddleWares(jwtHdl jwt2.Handler) []gin.HandlerFunc {
+func InitMiddleWares(jwtHdl jwt2.Handler, captchaSvc service.CaptchaService) []gin.HandlerFunc {
 	store := memstore.NewStore([]byte("W6RUUWNs6W3OYUpxJMG3E4Nj9PStZZUS"), []byte("dUfHJuOWQSSoJNuoPir4fWhwTggzyVDR"))
 
 	return []gin.HandlerFunc{
 		middleware.NewCorsMiddlewareBuilder().Build(),
+		middleware.NewLocaleMiddlewareBuilder().Build(),
+		middleware.NewClientIPMiddlewareBuilder().Build(),
 		middleware.NewLoginJWTMiddlewareBuilder(jwtHdl).
 			IgnorePath("/users/login_sms/code/send").
 			IgnorePath("/users/login_sms").
+			IgnorePath("/users/login_mfa").
+			IgnorePath("/users/login/unlock/code/send", "/users/login/unlock").
 			IgnorePath("/users/signup", "/users/login").
-			IgnorePath("/users/refresh_token").Build(),
+			IgnorePath("/users/refresh_token").
+			IgnorePath("/users/export/download").
+			// 短信网关自己校验业务方的 token
+			IgnorePath("/sms/send").
+			// 服务商的回执用地址里面的 token 校验
+			IgnorePrefix("/sms/receipts/").
+			IgnorePath("/captcha").
+			IgnorePath("/.well-known/jwks.json", "/openapi.json").Build(),
+		// 同一个 IP 发验证码太频繁就要先过图形验证码
+		middleware.NewCaptchaMiddlewareBuilder(captchaSvc).
+			Path("/users/login_sms/code/send", "/users/login/unlock/code/send").
+			Path("/users/email/code/send", "/users/merge/code/send").Build(),
 		sessions.Sessions("ssid", store),
 		// ratelimit.NewBuilder().Build(),
 	}
@@ -1,18 +1,38 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"net"
 )
 
 func main() {
 	initViper()
 	initLogger()
 
-	server := initWebServer()
+	app := initApp()
+	go app.deletionJob.Start(context.Background())
+	go app.exportJob.Start(context.Background())
+	go app.smsJob.Start(context.Background())
+	go runGRPCServer(app)
 	zap.L().Info("开始监听8081端口")
-	server.Run(":8081")
+	app.server.Run(":8081")
+}
+
+// runGRPCServer 给内部其它服务用，和 HTTP 服务共用同一套 service
+func runGRPCServer(app *App) {
+	viper.SetDefault("grpc.addr", ":8090")
+	addr := viper.GetString("grpc.addr")
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		panic(err)
+	}
+	zap.L().Info("gRPC 服务开始监听", zap.String("addr", addr))
+	if err = app.grpcServer.Serve(l); err != nil {
+		zap.L().Error("gRPC 服务退出", zap.Error(err))
+	}
 }
 
 func initViper() {
@@ -0,0 +1,174 @@
+// Package captcha 生成算术题图形验证码，图片用标准库画，不依赖字体文件
+package captcha
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/big"
+)
+
+// Challenge Question 画在图片上，Answer 是用户要输入的结果
+type Challenge struct {
+	Question string
+	Answer   string
+}
+
+// NewArithmetic 生成一道 20 以内的加减法或者 10 以内的乘法，结果不会是负数
+func NewArithmetic() (Challenge, error) {
+	op, err := randInt(3)
+	if err != nil {
+		return Challenge{}, err
+	}
+	max := 20
+	if op == 2 {
+		max = 10
+	}
+	a, err := randInt(max)
+	if err != nil {
+		return Challenge{}, err
+	}
+	b, err := randInt(max)
+	if err != nil {
+		return Challenge{}, err
+	}
+	a, b = a+1, b+1
+	switch op {
+	case 0:
+		return Challenge{Question: fmt.Sprintf("%d+%d=?", a, b), Answer: fmt.Sprint(a + b)}, nil
+	case 1:
+		if a < b {
+			a, b = b, a
+		}
+		return Challenge{Question: fmt.Sprintf("%d-%d=?", a, b), Answer: fmt.Sprint(a - b)}, nil
+	default:
+		return Challenge{Question: fmt.Sprintf("%dx%d=?", a, b), Answer: fmt.Sprint(a * b)}, nil
+	}
+}
+
+const (
+	scale   = 4
+	padding = 8
+)
+
+// Render 把 text 画成 PNG，只支持 glyphs 里面有的字符。
+// 每个字符的位置和颜色随机抖动，再加上干扰线和噪点
+func Render(text string) ([]byte, error) {
+	glyphW, glyphH := 5*scale, 7*scale
+	width := padding*2 + len(text)*(glyphW+scale*2)
+	height := padding*2 + glyphH
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill(img, color.RGBA{R: 245, G: 245, B: 240, A: 255})
+
+	x := padding
+	for _, ch := range text {
+		g, ok := glyphs[ch]
+		if !ok {
+			return nil, fmt.Errorf("captcha: 不支持的字符 %q", ch)
+		}
+		dy, err := randInt(padding)
+		if err != nil {
+			return nil, err
+		}
+		c, err := randColor(0, 120)
+		if err != nil {
+			return nil, err
+		}
+		drawGlyph(img, g, x, dy+padding/2, c)
+		x += glyphW + scale*2
+	}
+	if err := noise(img); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawGlyph(img *image.RGBA, g [7]string, x, y int, c color.RGBA) {
+	for row, line := range g {
+		for col, px := range line {
+			if px != '#' {
+				continue
+			}
+			for i := 0; i < scale; i++ {
+				for j := 0; j < scale; j++ {
+					img.SetRGBA(x+col*scale+i, y+row*scale+j, c)
+				}
+			}
+		}
+	}
+}
+
+// noise 几条随机的干扰线加上噪点，颜色比字浅一些，不影响人看
+func noise(img *image.RGBA) error {
+	b := img.Bounds()
+	for i := 0; i < 4; i++ {
+		y0, err := randInt(b.Dy())
+		if err != nil {
+			return err
+		}
+		y1, err := randInt(b.Dy())
+		if err != nil {
+			return err
+		}
+		c, err := randColor(80, 200)
+		if err != nil {
+			return err
+		}
+		for x := 0; x < b.Dx(); x++ {
+			img.SetRGBA(x, y0+(y1-y0)*x/b.Dx(), c)
+		}
+	}
+	for i := 0; i < b.Dx()*b.Dy()/20; i++ {
+		x, err := randInt(b.Dx())
+		if err != nil {
+			return err
+		}
+		y, err := randInt(b.Dy())
+		if err != nil {
+			return err
+		}
+		c, err := randColor(100, 230)
+		if err != nil {
+			return err
+		}
+		img.SetRGBA(x, y, c)
+	}
+	return nil
+}
+
+func fill(img *image.RGBA, c color.RGBA) {
+	b := img.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func randColor(min, max int) (color.RGBA, error) {
+	var rgb [3]uint8
+	for i := range rgb {
+		v, err := randInt(max - min)
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		rgb[i] = uint8(min + v)
+	}
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}, nil
+}
+
+// randInt 返回 [0, n)，用 crypto/rand，算术题的数字不能被预测
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
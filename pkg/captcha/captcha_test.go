@@ -0,0 +1,51 @@
+package captcha
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewArithmetic(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		c, err := NewArithmetic()
+		require.NoError(t, err)
+		expr, ok := strings.CutSuffix(c.Question, "=?")
+		require.True(t, ok, c.Question)
+		var want int
+		switch {
+		case strings.Contains(expr, "+"):
+			a, b, _ := strings.Cut(expr, "+")
+			want = atoi(t, a) + atoi(t, b)
+		case strings.Contains(expr, "-"):
+			a, b, _ := strings.Cut(expr, "-")
+			want = atoi(t, a) - atoi(t, b)
+		default:
+			a, b, _ := strings.Cut(expr, "x")
+			want = atoi(t, a) * atoi(t, b)
+		}
+		assert.GreaterOrEqual(t, want, 0)
+		assert.Equal(t, strconv.Itoa(want), c.Answer, c.Question)
+	}
+}
+
+func TestRender(t *testing.T) {
+	data, err := Render("12+3=?")
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, padding*2+6*(5*scale+scale*2), img.Bounds().Dx())
+
+	_, err = Render("a")
+	assert.Error(t, err)
+}
+
+func atoi(t *testing.T, s string) int {
+	v, err := strconv.Atoi(s)
+	require.NoError(t, err)
+	return v
+}
@@ -0,0 +1,20 @@
+package captcha
+
+// glyphs 5x7 的点阵字体，# 是要画的点
+var glyphs = map[rune][7]string{
+	'0': {" ### ", "#   #", "#  ##", "# # #", "##  #", "#   #", " ### "},
+	'1': {"  #  ", " ##  ", "  #  ", "  #  ", "  #  ", "  #  ", " ### "},
+	'2': {" ### ", "#   #", "    #", "   # ", "  #  ", " #   ", "#####"},
+	'3': {"#####", "   # ", "  #  ", "   # ", "    #", "#   #", " ### "},
+	'4': {"   # ", "  ## ", " # # ", "#  # ", "#####", "   # ", "   # "},
+	'5': {"#####", "#    ", "#### ", "    #", "    #", "#   #", " ### "},
+	'6': {"  ## ", " #   ", "#    ", "#### ", "#   #", "#   #", " ### "},
+	'7': {"#####", "    #", "   # ", "  #  ", " #   ", " #   ", " #   "},
+	'8': {" ### ", "#   #", "#   #", " ### ", "#   #", "#   #", " ### "},
+	'9': {" ### ", "#   #", "#   #", " ####", "    #", "   # ", " ##  "},
+	'+': {"     ", "  #  ", "  #  ", "#####", "  #  ", "  #  ", "     "},
+	'-': {"     ", "     ", "     ", "#####", "     ", "     ", "     "},
+	'x': {"     ", "#   #", " # # ", "  #  ", " # # ", "#   #", "     "},
+	'=': {"     ", "     ", "#####", "     ", "#####", "     ", "     "},
+	'?': {" ### ", "#   #", "    #", "   # ", "  #  ", "     ", "  #  "},
+}
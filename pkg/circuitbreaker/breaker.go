@@ -0,0 +1,244 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("熔断器已打开")
+
+type State int32
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type Config struct {
+	// Window 统计失败率的滑动窗口，分成 Buckets 个桶，每个桶过期之后整体丢掉
+	Window  time.Duration
+	Buckets int
+	// MinRequests 窗口内的请求数少于这个值不熔断，避免样本太少误判
+	MinRequests int64
+	// FailureRatio 失败率达到这个值就熔断
+	FailureRatio float64
+	// Cooldown 熔断之后多久进入半开状态
+	Cooldown time.Duration
+	// HalfOpenMax 半开状态最多放行几个探测请求，全部成功才恢复
+	HalfOpenMax int64
+}
+
+// Observer 状态变化和每次调用的结果，用来打日志和统计指标。
+// OnStateChange 是在持有锁的时候调用的，里面不能再调用 Breaker 的方法
+type Observer interface {
+	OnStateChange(name string, from State, to State)
+	OnResult(name string, success bool)
+	OnReject(name string)
+}
+
+// Breaker 熔断器：关闭状态正常放行，失败率超过阈值就打开，
+// 打开状态直接返回 ErrOpen，冷却之后半开，放行少量请求探测
+type Breaker struct {
+	name      string
+	cfg       Config
+	observers []Observer
+
+	mu    sync.Mutex
+	state State
+	// openedAt 最近一次打开的时间
+	openedAt time.Time
+	// halfOpen 半开状态已经放行和成功的请求数
+	halfOpenAllowed int64
+	halfOpenSuccess int64
+	window          *window
+	now             func() time.Time
+}
+
+func NewBreaker(name string, cfg Config) *Breaker {
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = 10
+	}
+	if cfg.HalfOpenMax <= 0 {
+		cfg.HalfOpenMax = 1
+	}
+	return &Breaker{
+		name:   name,
+		cfg:    cfg,
+		window: newWindow(cfg.Buckets, cfg.Window/time.Duration(cfg.Buckets)),
+		now:    time.Now,
+	}
+}
+
+func (b *Breaker) Observe(obs ...Observer) *Breaker {
+	b.observers = append(b.observers, obs...)
+	return b
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tryHalfOpen(b.now())
+	return b.state
+}
+
+// Allow 允许调用就返回 done，调用方执行完之后必须调用 done 上报结果
+func (b *Breaker) Allow() (func(success bool), error) {
+	b.mu.Lock()
+	now := b.now()
+	b.tryHalfOpen(now)
+	switch b.state {
+	case StateOpen:
+		b.mu.Unlock()
+		b.reject()
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.halfOpenAllowed >= b.cfg.HalfOpenMax {
+			b.mu.Unlock()
+			b.reject()
+			return nil, ErrOpen
+		}
+		b.halfOpenAllowed++
+	}
+	b.mu.Unlock()
+	return b.done, nil
+}
+
+// Do 包装一次调用，fn 返回 error 就算失败
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err == nil)
+	return err
+}
+
+func (b *Breaker) done(success bool) {
+	for _, obs := range b.observers {
+		obs.OnResult(b.name, success)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	switch b.state {
+	case StateClosed:
+		total, failed := b.window.add(now, success)
+		if total >= b.cfg.MinRequests && float64(failed)/float64(total) >= b.cfg.FailureRatio {
+			b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if !success {
+			b.setState(StateOpen, now)
+			return
+		}
+		b.halfOpenSuccess++
+		if b.halfOpenSuccess >= b.cfg.HalfOpenMax {
+			b.setState(StateClosed, now)
+		}
+	}
+	// 打开状态下上报的是打开之前放行的请求，不影响状态
+}
+
+func (b *Breaker) tryHalfOpen(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.cfg.Cooldown {
+		b.setState(StateHalfOpen, now)
+	}
+}
+
+// setState 调用方要持有锁
+func (b *Breaker) setState(to State, now time.Time) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	switch to {
+	case StateOpen:
+		b.openedAt = now
+	case StateHalfOpen:
+		b.halfOpenAllowed = 0
+		b.halfOpenSuccess = 0
+	case StateClosed:
+		b.window.reset()
+	}
+	for _, obs := range b.observers {
+		obs.OnStateChange(b.name, from, to)
+	}
+}
+
+func (b *Breaker) reject() {
+	for _, obs := range b.observers {
+		obs.OnReject(b.name)
+	}
+}
+
+// window 按时间分桶的滑动窗口
+type window struct {
+	buckets []bucket
+	width   time.Duration
+}
+
+type bucket struct {
+	// start 桶的起始时间，和当前时间相差超过一个窗口就是过期的桶
+	start  time.Time
+	total  int64
+	failed int64
+}
+
+func newWindow(size int, width time.Duration) *window {
+	if width <= 0 {
+		width = time.Second
+	}
+	return &window{
+		buckets: make([]bucket, size),
+		width:   width,
+	}
+}
+
+// add 记录一次结果，返回整个窗口内的请求数和失败数
+func (w *window) add(now time.Time, success bool) (int64, int64) {
+	start := now.Truncate(w.width)
+	idx := int(start.UnixNano()/int64(w.width)) % len(w.buckets)
+	b := &w.buckets[idx]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	b.total++
+	if !success {
+		b.failed++
+	}
+	var total, failed int64
+	span := w.width * time.Duration(len(w.buckets))
+	for _, b := range w.buckets {
+		if now.Sub(b.start) < span {
+			total += b.total
+			failed += b.failed
+		}
+	}
+	return total, failed
+}
+
+func (w *window) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
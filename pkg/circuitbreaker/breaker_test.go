@@ -0,0 +1,100 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type recordObserver struct {
+	transitions []State
+	rejected    int
+}
+
+func (o *recordObserver) OnStateChange(name string, from State, to State) {
+	o.transitions = append(o.transitions, to)
+}
+
+func (o *recordObserver) OnResult(name string, success bool) {
+}
+
+func (o *recordObserver) OnReject(name string) {
+	o.rejected++
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	obs := &recordObserver{}
+	b := NewBreaker("test", Config{
+		Window:       time.Second * 10,
+		Buckets:      10,
+		MinRequests:  4,
+		FailureRatio: 0.5,
+		Cooldown:     time.Second * 30,
+		HalfOpenMax:  2,
+	}).Observe(obs)
+	b.now = func() time.Time { return now }
+	fail := func() error { return errors.New("失败") }
+	ok := func() error { return nil }
+
+	// 请求数不够，全部失败也不熔断
+	for i := 0; i < 3; i++ {
+		_ = b.Do(fail)
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	// 过期的桶不算，窗口里面只剩 1 成功 1 失败，还是没达到最小请求数
+	now = now.Add(time.Second * 11)
+	require.NoError(t, b.Do(ok))
+	_ = b.Do(fail)
+	assert.Equal(t, StateClosed, b.State())
+
+	// 2 成功 2 失败，达到 50%
+	require.NoError(t, b.Do(ok))
+	_ = b.Do(fail)
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, ErrOpen, b.Do(ok))
+	assert.Equal(t, 1, obs.rejected)
+
+	// 冷却之后半开，只放行 HalfOpenMax 个请求
+	now = now.Add(time.Second * 30)
+	done1, err := b.Allow()
+	require.NoError(t, err)
+	done2, err := b.Allow()
+	require.NoError(t, err)
+	_, err = b.Allow()
+	assert.Equal(t, ErrOpen, err)
+	done1(true)
+	assert.Equal(t, StateHalfOpen, b.State())
+	done2(true)
+	assert.Equal(t, StateClosed, b.State())
+
+	// 恢复之后窗口清空，重新统计
+	_ = b.Do(fail)
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateClosed}, obs.transitions)
+}
+
+func TestBreaker_HalfOpenFailure(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker("test", Config{
+		Window:       time.Second * 10,
+		MinRequests:  1,
+		FailureRatio: 0.5,
+		Cooldown:     time.Second * 30,
+	})
+	b.now = func() time.Time { return now }
+	_ = b.Do(func() error { return errors.New("失败") })
+	assert.Equal(t, StateOpen, b.State())
+
+	now = now.Add(time.Second * 30)
+	assert.Equal(t, StateHalfOpen, b.State())
+	_ = b.Do(func() error { return errors.New("失败") })
+	// 探测失败重新打开，重新冷却
+	assert.Equal(t, StateOpen, b.State())
+	now = now.Add(time.Second * 29)
+	assert.Equal(t, StateOpen, b.State())
+}
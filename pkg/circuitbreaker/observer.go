@@ -0,0 +1,78 @@
+package circuitbreaker
+
+import (
+	"expvar"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// LogObserver 状态变化打日志，打开的时候是 Warn，方便配置告警
+type LogObserver struct {
+	l *zap.Logger
+}
+
+func NewLogObserver(l *zap.Logger) *LogObserver {
+	return &LogObserver{
+		l: l,
+	}
+}
+
+func (o *LogObserver) OnStateChange(name string, from State, to State) {
+	fields := []zap.Field{
+		zap.String("breaker", name),
+		zap.String("from", from.String()),
+		zap.String("to", to.String()),
+	}
+	if to == StateOpen {
+		o.l.Warn("熔断器打开", fields...)
+		return
+	}
+	o.l.Info("熔断器状态变化", fields...)
+}
+
+func (o *LogObserver) OnResult(name string, success bool) {
+}
+
+func (o *LogObserver) OnReject(name string) {
+}
+
+// metrics 通过 expvar 暴露，在 /debug/vars 的 circuit_breaker 下面
+var metrics = expvar.NewMap("circuit_breaker")
+
+// ExpvarHandler 格式和 expvar.Handler 一样，但是只输出 circuit_breaker，
+// cmdline、memstats 这些进程信息不对外
+func ExpvarHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, "{\n%q: %s\n}\n", "circuit_breaker", metrics.String())
+	})
+}
+
+// ExpvarObserver 按照熔断器的名字统计当前状态、成功、失败、拒绝和状态变化的次数，
+// 没有 state 说明从来没有熔断过
+type ExpvarObserver struct {
+}
+
+func NewExpvarObserver() *ExpvarObserver {
+	return &ExpvarObserver{}
+}
+
+func (o *ExpvarObserver) OnStateChange(name string, from State, to State) {
+	state := new(expvar.String)
+	state.Set(to.String())
+	metrics.Set(name+".state", state)
+	metrics.Add(name+".transitions."+to.String(), 1)
+}
+
+func (o *ExpvarObserver) OnResult(name string, success bool) {
+	if success {
+		metrics.Add(name+".success", 1)
+		return
+	}
+	metrics.Add(name+".failure", 1)
+}
+
+func (o *ExpvarObserver) OnReject(name string) {
+	metrics.Add(name+".rejected", 1)
+}
@@ -0,0 +1,22 @@
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExpvarHandler(t *testing.T) {
+	NewExpvarObserver().OnResult("expvar_test", true)
+	resp := httptest.NewRecorder()
+	ExpvarHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+	var vars map[string]map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &vars))
+	// 只有熔断器的指标，没有 cmdline 和 memstats
+	assert.Len(t, vars, 1)
+	assert.Equal(t, float64(1), vars["circuit_breaker"]["expvar_test.success"])
+}
@@ -0,0 +1,97 @@
+package geoip
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CSVLocator 从 IP 段的 CSV 文件里面查国家代码，每一行是 起始 IP,结束 IP,国家代码，
+// 也就是 DB-IP 的 dbip-country-lite 格式，IPv4 和 IPv6 可以放在一起
+type CSVLocator struct {
+	// ranges 按照起始 IP 排好序，段之间不重叠
+	ranges []ipRange
+}
+
+type ipRange struct {
+	start   netip.Addr
+	end     netip.Addr
+	country string
+}
+
+// LoadCSV 整个文件读进内存，country-lite 大概几十万行，几十 MB
+func LoadCSV(path string) (*CSVLocator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewCSVLocator(f)
+}
+
+func NewCSVLocator(r io.Reader) (*CSVLocator, error) {
+	cr := csv.NewReader(r)
+	// 有的文件后面还有地区名之类的列，只用前三列
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	var ranges []ipRange
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("第 %d 行至少要有三列", line)
+		}
+		start, err := netip.ParseAddr(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行起始 IP 不对: %w", line, err)
+		}
+		end, err := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行结束 IP 不对: %w", line, err)
+		}
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("第 %d 行 IP 段不对", line)
+		}
+		ranges = append(ranges, ipRange{
+			start:   start,
+			end:     end,
+			country: strings.ToUpper(strings.TrimSpace(record[2])),
+		})
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start.Less(ranges[j].start)
+	})
+	return &CSVLocator{ranges: ranges}, nil
+}
+
+// Country 找不到或者 IP 不合法返回空字符串
+func (l *CSVLocator) Country(ctx context.Context, ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	// ::ffff:1.2.3.4 这种按 IPv4 查
+	addr = addr.Unmap()
+	// 第一个起始 IP 比 addr 大的段，它前面那个段才可能包含 addr
+	i := sort.Search(len(l.ranges), func(i int) bool {
+		return addr.Less(l.ranges[i].start)
+	})
+	if i == 0 {
+		return ""
+	}
+	r := l.ranges[i-1]
+	if r.end.Less(addr) || r.start.Is4() != addr.Is4() {
+		return ""
+	}
+	return r.country
+}
@@ -0,0 +1,50 @@
+package geoip
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const testCSV = `1.0.1.0,1.0.3.255,CN
+1.0.0.0,1.0.0.255,AU
+2001:200::,2001:200:ffff:ffff:ffff:ffff:ffff:ffff,jp
+8.8.8.0,8.8.8.255,US,United States
+`
+
+func TestCSVLocator_Country(t *testing.T) {
+	l, err := NewCSVLocator(strings.NewReader(testCSV))
+	require.NoError(t, err)
+	testCases := []struct {
+		name string
+		ip   string
+
+		wantCountry string
+	}{
+		{name: "段的开头", ip: "1.0.0.0", wantCountry: "AU"},
+		{name: "段的中间", ip: "1.0.2.3", wantCountry: "CN"},
+		{name: "段的结尾", ip: "1.0.3.255", wantCountry: "CN"},
+		{name: "多出来的列", ip: "8.8.8.8", wantCountry: "US"},
+		{name: "IPv6", ip: "2001:200::1", wantCountry: "JP"},
+		{name: "IPv4 映射的 IPv6", ip: "::ffff:1.0.0.1", wantCountry: "AU"},
+		{name: "两个段中间", ip: "1.0.4.0"},
+		{name: "比第一个段还小", ip: "0.0.0.1"},
+		{name: "不是 IP", ip: "localhost"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantCountry, l.Country(context.Background(), tc.ip))
+		})
+	}
+}
+
+func TestNewCSVLocator(t *testing.T) {
+	_, err := NewCSVLocator(strings.NewReader("1.0.0.0,1.0.0.255\n"))
+	assert.Error(t, err)
+	_, err = NewCSVLocator(strings.NewReader("1.0.0.255,1.0.0.0,AU\n"))
+	assert.Error(t, err)
+	_, err = NewCSVLocator(strings.NewReader("1.0.0.0,2001:200::,AU\n"))
+	assert.Error(t, err)
+}
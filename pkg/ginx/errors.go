@@ -0,0 +1,77 @@
+package ginx
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Error 业务错误，同时决定 HTTP 状态码和返回给前端的错误码。
+// 错误码一共六位，前三位就是 HTTP 状态码，后三位是业务自己的编号
+type Error struct {
+	Code   int
+	Status int
+	Msg    string
+}
+
+func NewError(code int, status int, msg string) *Error {
+	e := &Error{
+		Code:   code,
+		Status: status,
+		Msg:    msg,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	codes[code] = e
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// 通用的错误码，业务自己的错误码从 xxx100 开始编
+var (
+	ErrInvalidInput    = NewError(400000, http.StatusBadRequest, "输入有误")
+	ErrUnauthorized    = NewError(401000, http.StatusUnauthorized, "请先登录")
+	ErrForbidden       = NewError(403000, http.StatusForbidden, "没有权限")
+	ErrNotFound        = NewError(404000, http.StatusNotFound, "资源不存在")
+	ErrTooManyRequests = NewError(429000, http.StatusTooManyRequests, "请求太频繁，请稍后再试")
+	ErrInternal        = NewError(500000, http.StatusInternalServerError, "系统错误")
+)
+
+type mapping struct {
+	target error
+	biz    *Error
+}
+
+var (
+	mu       sync.RWMutex
+	registry []mapping
+	// codes 按错误码找错误，请求结构体的 errcode 标签用
+	codes = map[int]*Error{}
+)
+
+// Register 把 service 层的错误映射成业务错误，一般在 init 里面调用。
+// 匹配的时候用 errors.Is，所以包装过的错误也能识别出来
+func Register(target error, biz *Error) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry = append(registry, mapping{target: target, biz: biz})
+}
+
+// Lookup 找出 err 对应的业务错误，没有注册过的一律当成系统错误
+func Lookup(err error) *Error {
+	var biz *Error
+	if errors.As(err, &biz) {
+		return biz
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, m := range registry {
+		if errors.Is(err, m.target) {
+			return m.biz
+		}
+	}
+	return ErrInternal
+}
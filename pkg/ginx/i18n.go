@@ -0,0 +1,31 @@
+package ginx
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/pkg/i18n"
+)
+
+// MsgOK 管理后台之类不需要具体提示的接口用
+var MsgOK = NewMessage(200000, "OK")
+
+func init() {
+	i18n.Register(i18n.EnUS, map[int]string{
+		MsgOK.Id:                "OK",
+		ErrInvalidInput.Code:    "Invalid input",
+		ErrUnauthorized.Code:    "Please log in first",
+		ErrForbidden.Code:       "Permission denied",
+		ErrNotFound.Code:        "Resource not found",
+		ErrTooManyRequests.Code: "Too many requests, please try again later",
+		ErrInternal.Code:        "Internal server error",
+	})
+}
+
+// Lang 当前请求的语言，由中间件放进 context，没有就看 Accept-Language
+func Lang(ctx *gin.Context) i18n.Lang {
+	if val, ok := ctx.Get(i18n.ContextKey); ok {
+		if lang, ok := val.(i18n.Lang); ok {
+			return lang
+		}
+	}
+	return i18n.FromAcceptLanguage(ctx.GetHeader("Accept-Language"))
+}
@@ -4,9 +4,9 @@ import (
 	_ "embed"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/pkg/ginx"
 	"github.com/skcheng003/webook/pkg/ratelimit"
 	"log"
-	"net/http"
 )
 
 type Builder struct {
@@ -33,12 +33,12 @@ func (b *Builder) Build() gin.HandlerFunc {
 			log.Println(err)
 			// 这一步很有意思，就是如果这边出错了
 			// 要怎么办？
-			ctx.AbortWithStatus(http.StatusInternalServerError)
+			ginx.Abort(ctx, ginx.ErrInternal)
 			return
 		}
 		if limited {
 			log.Println(err)
-			ctx.AbortWithStatus(http.StatusTooManyRequests)
+			ginx.Abort(ctx, ginx.ErrTooManyRequests)
 			return
 		}
 		ctx.Next()
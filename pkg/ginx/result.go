@@ -0,0 +1,31 @@
+package ginx
+
+// Result 所有接口统一的返回结构，Code 为 0 表示成功
+type Result struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data any    `json:"data"`
+	// msgId 成功提示的翻译 id，不返回给前端
+	msgId int
+}
+
+// Message 成功时的提示文案，Id 只用来查翻译，返回给前端的 code 还是 0。
+// Id 和错误码一样是六位，前三位是 200
+type Message struct {
+	Id  int
+	Msg string
+}
+
+func NewMessage(id int, msg string) Message {
+	return Message{Id: id, Msg: msg}
+}
+
+// OK 带提示文案的成功结果，需要返回数据就再调用 WithData
+func OK(msg Message) Result {
+	return Result{Msg: msg.Msg, msgId: msg.Id}
+}
+
+func (r Result) WithData(data any) Result {
+	r.Data = data
+	return r
+}
@@ -0,0 +1,30 @@
+package ginx
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/pkg/openapi"
+	"path"
+)
+
+// Handle 注册路由，同时把接口记到 openapi.Default 里面
+func Handle(g *gin.RouterGroup, method, relativePath string,
+	fn func(ctx *gin.Context) (Result, error), op openapi.Operation) {
+	g.Handle(method, relativePath, Wrap(fn))
+	openapi.Default.Add(method, joinPath(g.BasePath(), relativePath), op)
+}
+
+// HandleBody 和 Handle 一样，请求结构直接从 fn 的参数类型推出来
+func HandleBody[T any](g *gin.RouterGroup, method, relativePath string,
+	fn func(ctx *gin.Context, req T) (Result, error), op openapi.Operation) {
+	g.Handle(method, relativePath, WrapBody(fn))
+	var req T
+	op.Request = req
+	openapi.Default.Add(method, joinPath(g.BasePath(), relativePath), op)
+}
+
+func joinPath(base, relativePath string) string {
+	if relativePath == "" {
+		return base
+	}
+	return path.Join(base, relativePath)
+}
@@ -0,0 +1,83 @@
+package ginx
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/skcheng003/webook/pkg/i18n"
+	"go.uber.org/zap"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// Wrap 让 handler 直接返回结果和错误，由这里统一写响应
+func Wrap(fn func(ctx *gin.Context) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		res, err := fn(ctx)
+		if err != nil {
+			Abort(ctx, err)
+			return
+		}
+		if res.msgId != 0 {
+			res.Msg = i18n.Message(Lang(ctx), res.msgId, res.Msg)
+		}
+		ctx.JSON(http.StatusOK, res)
+	}
+}
+
+// WrapBody 在 Wrap 的基础上帮忙解析和校验请求，
+// 校验规则写在 binding 标签里面，不通过的时候返回 errcode 标签对应的错误，没有写就是 ErrInvalidInput
+func WrapBody[T any](fn func(ctx *gin.Context, req T) (Result, error)) gin.HandlerFunc {
+	return Wrap(func(ctx *gin.Context) (Result, error) {
+		var req T
+		if err := ctx.ShouldBind(&req); err != nil {
+			return Result{}, bindError(reflect.TypeOf(req), err)
+		}
+		return fn(ctx, req)
+	})
+}
+
+// bindError 只看第一个不通过的字段。
+// 缺少必填字段统一当成 ErrInvalidInput，说明前端传错了，用户自己填错的才需要具体的提示
+func bindError(t reflect.Type, err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) == 0 || verrs[0].Tag() == "required" {
+		return ErrInvalidInput
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ErrInvalidInput
+	}
+	f, ok := t.FieldByName(verrs[0].StructField())
+	if !ok {
+		return ErrInvalidInput
+	}
+	code, err := strconv.Atoi(f.Tag.Get("errcode"))
+	if err != nil {
+		return ErrInvalidInput
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if biz, ok := codes[code]; ok {
+		return biz
+	}
+	return ErrInvalidInput
+}
+
+// Abort 按照错误写响应并且中断后续的 handler，中间件里面也可以用
+func Abort(ctx *gin.Context, err error) {
+	biz := Lookup(err)
+	if biz.Status >= http.StatusInternalServerError {
+		zap.L().Error("处理请求失败",
+			zap.String("method", ctx.Request.Method),
+			zap.String("path", ctx.Request.URL.Path),
+			zap.Error(err))
+	}
+	ctx.AbortWithStatusJSON(biz.Status, Result{
+		Code: biz.Code,
+		Msg:  i18n.Message(Lang(ctx), biz.Code, biz.Msg),
+	})
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Builder 每个请求打一条日志，服务端出错的时候用 Error 级别
+type Builder struct {
+	l *zap.Logger
+}
+
+func NewBuilder(l *zap.Logger) *Builder {
+	return &Builder{l: l}
+}
+
+func (b *Builder) Build() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		st, _ := status.FromError(err)
+		fields := []zap.Field{
+			zap.String("method", info.FullMethod),
+			zap.String("code", st.Code().String()),
+			zap.Duration("duration", time.Since(start)),
+		}
+		if err == nil {
+			b.l.Info("grpc_request", fields...)
+			return resp, err
+		}
+		fields = append(fields, zap.Error(err))
+		switch st.Code() {
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			b.l.Error("grpc_request", fields...)
+		default:
+			b.l.Warn("grpc_request", fields...)
+		}
+		return resp, err
+	}
+}
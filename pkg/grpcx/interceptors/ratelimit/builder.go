@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/skcheng003/webook/pkg/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+)
+
+// Builder 和 gin 的限流中间件一样，按照调用方的 IP 限流
+type Builder struct {
+	prefix  string
+	limiter ratelimit.Limiter
+}
+
+func NewBuilder(limiter ratelimit.Limiter) *Builder {
+	return &Builder{
+		prefix:  "grpc-ip-limiter",
+		limiter: limiter,
+	}
+}
+
+func (b *Builder) Prefix(prefix string) *Builder {
+	b.prefix = prefix
+	return b
+}
+
+func (b *Builder) Build() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		limited, err := b.limiter.Limit(ctx, b.key(ctx))
+		if err != nil {
+			// 限流器出问题的时候保守一点，直接拒绝
+			zap.L().Error("grpc 限流失败", zap.String("method", info.FullMethod), zap.Error(err))
+			return nil, status.Error(codes.ResourceExhausted, "限流器异常")
+		}
+		if limited {
+			return nil, status.Error(codes.ResourceExhausted, "请求太频繁，请稍后再试")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (b *Builder) key(ctx context.Context) string {
+	addr := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+	}
+	return fmt.Sprintf("%s:%s", b.prefix, addr)
+}
@@ -0,0 +1,107 @@
+package i18n
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Lang string
+
+const (
+	ZhCN Lang = "zh-CN"
+	EnUS Lang = "en-US"
+	// Default 源语言，代码里面直接写的文案都是中文
+	Default = ZhCN
+)
+
+// ContextKey 用字符串做 key，这样 gin.Context 里面 Set 的值也能通过 Value 取到
+const ContextKey = "i18n_lang"
+
+var (
+	mu       sync.RWMutex
+	catalogs = map[Lang]map[int]string{}
+)
+
+// Register 注册某个语言的文案，key 是错误码或者提示码，重复注册会覆盖
+func Register(lang Lang, msgs map[int]string) {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := catalogs[lang]
+	if !ok {
+		c = make(map[int]string, len(msgs))
+		catalogs[lang] = c
+	}
+	for code, msg := range msgs {
+		c[code] = msg
+	}
+}
+
+// Message 找不到翻译就返回 fallback，fallback 一般就是写在代码里的中文
+func Message(lang Lang, code int, fallback string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if msg, ok := catalogs[lang][code]; ok {
+		return msg
+	}
+	return fallback
+}
+
+// Parse 把 en、en_us、EN-US 之类的写法统一成支持的语言
+func Parse(s string) (Lang, bool) {
+	s = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "_", "-"))
+	switch {
+	case s == "zh" || strings.HasPrefix(s, "zh-"):
+		return ZhCN, true
+	case s == "en" || strings.HasPrefix(s, "en-"):
+		return EnUS, true
+	}
+	return "", false
+}
+
+// FromAcceptLanguage 按照 q 值从高到低挑第一个支持的语言，都不支持就用 Default
+func FromAcceptLanguage(header string) Lang {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var cands []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		cands = append(cands, candidate{tag: tag, q: q})
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].q > cands[j].q
+	})
+	for _, c := range cands {
+		if c.q <= 0 {
+			break
+		}
+		if lang, ok := Parse(c.tag); ok {
+			return lang
+		}
+	}
+	return Default
+}
+
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, ContextKey, lang)
+}
+
+// FromContext 没有设置过语言就返回 Default
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(ContextKey).(Lang); ok {
+		return lang
+	}
+	return Default
+}
@@ -0,0 +1,35 @@
+package i18n
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFromAcceptLanguage(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		want   Lang
+	}{
+		{name: "空", header: "", want: Default},
+		{name: "英文", header: "en-US,en;q=0.9", want: EnUS},
+		{name: "只写语言", header: "en", want: EnUS},
+		{name: "按 q 值排序", header: "en;q=0.5,zh-TW;q=0.8", want: ZhCN},
+		{name: "跳过不支持的语言", header: "fr-FR,de;q=0.9,en;q=0.1", want: EnUS},
+		{name: "都不支持", header: "fr-FR,de", want: Default},
+		{name: "q 为 0 表示不接受", header: "en;q=0", want: Default},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, FromAcceptLanguage(tc.header))
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	Register(EnUS, map[int]string{400999: "Invalid"})
+	assert.Equal(t, "Invalid", Message(EnUS, 400999, "输入有误"))
+	// 没有翻译就用代码里的文案
+	assert.Equal(t, "输入有误", Message(ZhCN, 400999, "输入有误"))
+	assert.Equal(t, "未知", Message(EnUS, 400998, "未知"))
+}
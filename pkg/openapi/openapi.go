@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Operation 描述一个接口，Request 和 Response 传对应结构体的零值就可以
+type Operation struct {
+	Summary string
+	// Tags 不传就用路径的第一段，比如 /users/login 就是 users
+	Tags []string
+	// Public 不需要登录就能访问
+	Public bool
+	// Request GET 请求里面是 query 参数，其它是 JSON 请求体
+	Request any
+	// Response 成功时 Result.Data 的结构，nil 表示没有数据
+	Response any
+}
+
+// Document 在注册路由的时候收集接口描述，最后输出成 OpenAPI 3 文档
+type Document struct {
+	mu      sync.RWMutex
+	title   string
+	version string
+	paths   map[string]map[string]*operation
+	schemas map[string]*Schema
+}
+
+// Default 全局的文档，ginx.Handle 注册的路由都记在这里
+var Default = New("webook", "1.0.0")
+
+func New(title, version string) *Document {
+	return &Document{
+		title:   title,
+		version: version,
+		paths:   map[string]map[string]*operation{},
+		schemas: map[string]*Schema{},
+	}
+}
+
+type operation struct {
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+	// Security 空数组表示不需要登录，覆盖全局的配置，所以要用指针区分 nil
+	Security *[]map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Add 同一个 method 和 path 重复添加会覆盖
+func (d *Document) Add(method, path string, op Operation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	method = strings.ToLower(method)
+	path, params := convertPath(path)
+	o := &operation{
+		Summary:   op.Summary,
+		Tags:      op.Tags,
+		Responses: d.responses(op.Response),
+	}
+	if len(o.Tags) == 0 {
+		if seg, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/"); seg != "" {
+			o.Tags = []string{seg}
+		}
+	}
+	if op.Public {
+		o.Security = &[]map[string][]string{}
+	}
+	for _, p := range params {
+		o.Parameters = append(o.Parameters, parameter{
+			Name: p, In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	if op.Request != nil {
+		if method == "get" {
+			o.Parameters = append(o.Parameters, d.queryParams(reflect.TypeOf(op.Request))...)
+		} else {
+			o.RequestBody = &requestBody{
+				Required: true,
+				Content: map[string]mediaType{
+					"application/json": {Schema: d.schemaOf(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+	}
+	if d.paths[path] == nil {
+		d.paths[path] = map[string]*operation{}
+	}
+	d.paths[path][method] = o
+}
+
+// responses 成功和失败都用统一的 Result 包起来，失败的具体原因看 code
+func (d *Document) responses(data any) map[string]response {
+	envelope := func(data *Schema) *Schema {
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"code": {Type: "integer", Description: "0 表示成功，其它是错误码"},
+				"msg":  {Type: "string"},
+				"data": data,
+			},
+			Required: []string{"code", "msg"},
+		}
+	}
+	ok := &Schema{}
+	if data != nil {
+		ok = d.schemaOf(reflect.TypeOf(data))
+	}
+	return map[string]response{
+		"200": {
+			Description: "成功",
+			Content:     map[string]mediaType{"application/json": {Schema: envelope(ok)}},
+		},
+		"default": {
+			Description: "失败，HTTP 状态码是错误码的前三位",
+			Content:     map[string]mediaType{"application/json": {Schema: envelope(&Schema{})}},
+		},
+	}
+}
+
+func (d *Document) queryParams(t reflect.Type) []parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var res []parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, ok := fieldName(f, "form")
+		if !ok {
+			continue
+		}
+		s, required := d.fieldSchema(f)
+		res = append(res, parameter{Name: name, In: "query", Required: required, Schema: s})
+	}
+	return res
+}
+
+// convertPath 把 gin 的 /pub/:id 转成 /pub/{id}
+func convertPath(path string) (string, []string) {
+	segs := strings.Split(path, "/")
+	var params []string
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			params = append(params, seg[1:])
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+func (d *Document) MarshalJSON() ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return json.Marshal(map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   d.title,
+			"version": d.version,
+		},
+		"paths": d.paths,
+		"components": map[string]any{
+			"schemas": d.schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]string{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+		},
+		// 默认都要登录，Public 的接口单独覆盖
+		"security": []map[string][]string{{"bearerAuth": {}}},
+	})
+}
+
+// Handler 输出文档，前端用来生成客户端
+func (d *Document) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		data, err := json.Marshal(d)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		ctx.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}
+}
@@ -0,0 +1,76 @@
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+type testReq struct {
+	Email    string   `json:"email" binding:"required,email"`
+	Nickname string   `json:"nickname" binding:"max=16"`
+	Emails   []string `json:"emails" binding:"required,dive,email"`
+	Ignored  string   `json:"-"`
+}
+
+type testPageReq struct {
+	Offset int `form:"offset" binding:"min=0"`
+}
+
+type testVO struct {
+	testEmbedded
+	Tags []string `json:"tags"`
+}
+
+type testEmbedded struct {
+	Id int64 `json:"id"`
+}
+
+func TestDocument(t *testing.T) {
+	d := New("test", "1.0.0")
+	d.Add(http.MethodPost, "/users/signup", Operation{Public: true, Request: testReq{}})
+	d.Add(http.MethodGet, "/users/:id/logs", Operation{Request: testPageReq{}, Response: []testVO{}})
+	data, err := json.Marshal(d)
+	require.NoError(t, err)
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Tags       []string         `json:"tags"`
+			Security   *[]any           `json:"security"`
+			Parameters []map[string]any `json:"parameters"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	signup := doc.Paths["/users/signup"]["post"]
+	assert.Equal(t, []string{"users"}, signup.Tags)
+	// 不需要登录的接口要显式覆盖成空数组
+	require.NotNil(t, signup.Security)
+	assert.Empty(t, *signup.Security)
+
+	logs := doc.Paths["/users/{id}/logs"]["get"]
+	assert.Nil(t, logs.Security)
+	require.Len(t, logs.Parameters, 2)
+	assert.Equal(t, "id", logs.Parameters[0]["name"])
+	assert.Equal(t, "path", logs.Parameters[0]["in"])
+	assert.Equal(t, "offset", logs.Parameters[1]["name"])
+	assert.Equal(t, "query", logs.Parameters[1]["in"])
+
+	req := doc.Components.Schemas["testReq"]
+	assert.Equal(t, []string{"email", "emails"}, req.Required)
+	assert.Equal(t, "email", req.Properties["email"].Format)
+	// dive 之后的规则作用在元素上
+	assert.Empty(t, req.Properties["emails"].Format)
+	assert.Equal(t, "email", req.Properties["emails"].Items.Format)
+	assert.Equal(t, 16, *req.Properties["nickname"].MaxLength)
+	assert.NotContains(t, req.Properties, "Ignored")
+
+	vo := doc.Components.Schemas["testVO"]
+	assert.Contains(t, vo.Properties, "id")
+	assert.Equal(t, "array", vo.Properties["tags"].Type)
+}
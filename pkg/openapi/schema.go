@@ -0,0 +1,208 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema OpenAPI 3 里面的 Schema Object，只实现了我们用得到的字段
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// AdditionalProperties map 类型用
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+}
+
+// TagFunc 把自定义的校验规则翻译成 Schema 上的约束，param 是等号后面的部分
+type TagFunc func(s *Schema, param string)
+
+var (
+	tagMu sync.RWMutex
+	tags  = map[string]TagFunc{
+		"email": func(s *Schema, param string) {
+			s.Format = "email"
+		},
+		"e164": func(s *Schema, param string) {
+			s.Pattern = `^\+[1-9]?[0-9]{7,14}$`
+		},
+		"oneof": func(s *Schema, param string) {
+			s.Enum = strings.Fields(param)
+		},
+		"datetime": func(s *Schema, param string) {
+			if param == "2006-01-02" {
+				s.Format = "date"
+				return
+			}
+			s.Format = "date-time"
+		},
+		"eqfield": func(s *Schema, param string) {
+			s.Description = "必须和 " + param + " 一致"
+		},
+		"min": func(s *Schema, param string) {
+			limit(s, param, false)
+		},
+		"max": func(s *Schema, param string) {
+			limit(s, param, true)
+		},
+		"len": func(s *Schema, param string) {
+			limit(s, param, false)
+			limit(s, param, true)
+		},
+	}
+)
+
+// RegisterTag 业务自己注册的校验规则，需要在这里告诉文档怎么描述
+func RegisterTag(tag string, fn TagFunc) {
+	tagMu.Lock()
+	defer tagMu.Unlock()
+	tags[tag] = fn
+}
+
+func limit(s *Schema, param string, isMax bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch s.Type {
+	case "string", "array":
+		l := int(n)
+		if isMax {
+			s.MaxLength = &l
+		} else {
+			s.MinLength = &l
+		}
+	case "integer", "number":
+		if isMax {
+			s.Maximum = &n
+		} else {
+			s.Minimum = &n
+		}
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf 具名的结构体放进 components，返回引用
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := d.schemas[name]; !ok {
+			// 先占位，防止递归引用自己的时候死循环
+			d.schemas[name] = &Schema{}
+			*d.schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// interface 之类的没法描述，前端当成任意值
+	return &Schema{}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	d.collectFields(s, t)
+	return s
+}
+
+func (d *Document) collectFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			// 嵌入的结构体字段在 JSON 里面是平铺的
+			d.collectFields(s, f.Type)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, ok := fieldName(f, "json")
+		if !ok {
+			continue
+		}
+		prop, required := d.fieldSchema(f)
+		s.Properties[name] = prop
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// fieldName 按照 encoding/json 或者 form 的规则拿字段名
+func fieldName(f reflect.StructField, key string) (string, bool) {
+	tag := f.Tag.Get(key)
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+// fieldSchema 根据 binding 标签补充约束，返回字段是否必填
+func (d *Document) fieldSchema(f reflect.StructField) (*Schema, bool) {
+	s := d.schemaOf(f.Type)
+	binding := f.Tag.Get("binding")
+	if binding == "" || s.Ref != "" {
+		return s, false
+	}
+	required := false
+	tagMu.RLock()
+	defer tagMu.RUnlock()
+	// target dive 之后的规则作用在数组的元素上
+	target := s
+	for _, rule := range strings.Split(binding, ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+		if tag == "required" && target == s {
+			required = true
+			continue
+		}
+		if tag == "dive" && target.Items != nil {
+			target = target.Items
+			continue
+		}
+		if fn, ok := tags[tag]; ok {
+			fn(target, param)
+		}
+	}
+	return s, required
+}
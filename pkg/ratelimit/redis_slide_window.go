@@ -27,6 +27,14 @@ func NewRedisSlidingWindowLimiter(cmd redis.Cmdable, rate int, interval time.Dur
 }
 
 func (r RedisSlidingWindowLimiter) Limit(ctx context.Context, key string) (bool, error) {
+	return r.LimitN(ctx, key, 1)
+}
+
+func (r RedisSlidingWindowLimiter) LimitN(ctx context.Context, key string, n int) (bool, error) {
 	return r.cmd.Eval(ctx, slideWindow, []string{key},
-		r.interval.Milliseconds(), r.rate, time.Now().UnixMilli()).Bool()
+		r.interval.Milliseconds(), r.rate, time.Now().UnixMilli(), n).Bool()
+}
+
+func (r RedisSlidingWindowLimiter) Reset(ctx context.Context, key string) error {
+	return r.cmd.Del(ctx, key).Err()
 }
@@ -9,18 +9,22 @@ local window = tonumber(ARGV[1])
 -- 阈值
 local threshold = tonumber( ARGV[2])
 local now = tonumber(ARGV[3])
+-- 这次要占用几个名额
+local n = tonumber(ARGV[4])
 -- 窗口的起始时间
 local min = now - window
 
 redis.call('ZREMRANGEBYSCORE', key, '-inf', min)
 local cnt = redis.call('ZCOUNT', key, '-inf', '+inf')
 -- local cnt = redis.call('ZCOUNT', key, min, '+inf')
-if cnt >= threshold then
+if cnt + n > threshold then
     -- 执行限流
     return "true"
 else
-    -- 把 score 和 member 都设置成 now
-    redis.call('ZADD', key, now, now)
+    -- score 是 now，member 要唯一，不然同一毫秒的请求只算一次
+    for i = 1, n do
+        redis.call('ZADD', key, now, now .. ':' .. (cnt + i))
+    end
     redis.call('PEXPIRE', key, window)
     return "false"
-end
\ No newline at end of file
+end
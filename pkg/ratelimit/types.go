@@ -4,4 +4,8 @@ import "context"
 
 type Limiter interface {
 	Limit(ctx context.Context, key string) (bool, error)
+	// LimitN 一次占用 n 个名额，剩下的不够 n 个就限流，一个也不占
+	LimitN(ctx context.Context, key string, n int) (bool, error)
+	// Reset 清空某个 key 的限流记录，误伤的时候管理员手动放行
+	Reset(ctx context.Context, key string) error
 }
@@ -0,0 +1,92 @@
+// Package totp 实现 RFC 6238 基于时间的一次性密码，兼容 Google Authenticator 等身份验证器
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// 身份验证器默认使用 6 位数字，30 秒一个时间窗口
+	digits     = 6
+	period     = 30
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret 生成 base32 编码的随机密钥
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+// Code 计算 t 所在时间窗口的验证码
+func Code(secret string, t time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(t.Unix()/period)), nil
+}
+
+// Validate 校验验证码，skew 表示前后各容忍几个时间窗口，用来抵消客户端的时钟偏差
+func Validate(secret string, code string, t time.Time, skew int) bool {
+	_, ok := ValidateStep(secret, code, t, skew)
+	return ok
+}
+
+// ValidateStep 和 Validate 一样，同时返回匹配上的时间窗口序号。
+// 调用方记住用过的最大序号，就可以拒绝同一个验证码在有效期内被重放
+func ValidateStep(secret string, code string, t time.Time, skew int) (int64, bool) {
+	if len(code) != digits {
+		return 0, false
+	}
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	counter := t.Unix() / period
+	for i := -skew; i <= skew; i++ {
+		expected := hotp(key, uint64(counter+int64(i)))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter + int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// URI 生成 otpauth 协议的 provisioning URI，前端把它渲染成二维码给身份验证器扫描
+func URI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", digits))
+	params.Set("period", fmt.Sprintf("%d", period))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// hotp RFC 4226 的动态截断算法
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, bin%1000000)
+}
@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量，取 8 位结果的后 6 位
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).
+		EncodeToString([]byte("12345678901234567890"))
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tc := range testCases {
+		code, err := Code(secret, time.Unix(tc.unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+	code, err := Code(secret, now)
+	require.NoError(t, err)
+
+	assert.True(t, Validate(secret, code, now, 1))
+	// 上一个时间窗口的验证码在容忍范围内
+	assert.True(t, Validate(secret, code, now.Add(period*time.Second), 1))
+	assert.False(t, Validate(secret, code, now.Add(3*period*time.Second), 1))
+	assert.False(t, Validate(secret, "12345", now, 1))
+}
+
+func TestValidateStep(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, now)
+	require.NoError(t, err)
+
+	step, ok := ValidateStep(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/period, step)
+	// 下一个时间窗口校验，匹配的还是生成时的窗口
+	step, ok = ValidateStep(secret, code, now.Add(period*time.Second), 1)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/period, step)
+}
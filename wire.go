@@ -3,41 +3,110 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	grpc2 "github.com/skcheng003/webook/internal/grpc"
+	"github.com/skcheng003/webook/internal/job"
 	"github.com/skcheng003/webook/internal/repository"
 	"github.com/skcheng003/webook/internal/repository/cache"
 	"github.com/skcheng003/webook/internal/repository/dao"
 	"github.com/skcheng003/webook/internal/service"
+	"github.com/skcheng003/webook/internal/service/sms"
+	"github.com/skcheng003/webook/internal/service/sms/async"
 	"github.com/skcheng003/webook/internal/web"
 	jwt2 "github.com/skcheng003/webook/internal/web/jwt"
 	"github.com/skcheng003/webook/ioc"
 )
 
-func initWebServer() *gin.Engine {
+func initApp() *App {
 	wire.Build(
 		// 第三方组件
 		ioc.InitRedis, ioc.InitDB,
 
 		dao.NewGORMUserDAO,
+		dao.NewGORMMFADAO,
+		dao.NewGORMLoginLogDAO,
+		dao.NewGORMArticleDAO,
+		dao.NewGORMAsyncSMSDAO,
+		dao.NewGORMSMSDeliveryDAO,
 
 		cache.NewRedisUserCache,
 		cache.NewRedisCodeCache,
+		cache.NewRedisCodeQuotaCache,
+		cache.NewRedisSMSTokenCache,
+		cache.NewRedisCaptchaCache,
+		cache.NewRedisLoginAttemptCache,
+		cache.NewRedisExportTaskCache,
 
 		repository.NewUserRepository,
 		repository.NewCachedCodeRepository,
+		repository.NewCachedCodeQuotaRepository,
+		repository.NewCachedSMSTokenRepository,
+		repository.NewCachedCaptchaRepository,
+		repository.NewMFARepository,
+		repository.NewCachedLoginAttemptRepository,
+		repository.NewLoginLogRepository,
+		repository.NewCachedArticleRepository,
+		repository.NewCachedExportTaskRepository,
+		repository.NewAsyncSMSRepository,
+		ioc.InitSMSDeliveryRepository,
 
-		// 基于内存实现的短信服务
+		// 短信服务，限流加上服务商故障切换
+		ioc.InitAsyncSMSConfig,
+		ioc.InitSMSTemplateRegistry,
+		ioc.InitSMSGatewayService,
+		ioc.InitSMSProviders,
 		ioc.InitSMSService,
+		wire.Bind(new(sms.Service), new(*async.Service)),
+		// 基于内存实现的邮件服务
+		ioc.InitEmailService,
+		ioc.InitLimiter,
 
+		ioc.InitLockoutConfig,
+		service.NewLogSecurityEventEmitter,
 		service.NewUserService,
+		ioc.InitCodeLimitConfig,
+		ioc.InitCodePolicies,
 		service.NewSMSCodeService,
+		ioc.InitCaptchaConfig,
+		service.NewCaptchaService,
+		service.NewMailCodeService,
+		service.NewTOTPService,
+		ioc.InitIPLocator,
+		service.NewLoginLogService,
+		service.NewArticleService,
+		ioc.InitDeletionConfig,
+		service.NewAccountService,
+		ioc.InitExportConfig,
+		service.NewEmailExportNotifier,
+		service.NewExportService,
+		ioc.InitSMSDeliveryConfig,
+		service.NewSMSDeliveryService,
+		wire.Bind(new(service.SessionRevoker), new(jwt2.Handler)),
 
 		web.NewUserHandler,
+		web.NewJWKSHandler,
+		web.NewArticleHandler,
+		web.NewAdminHandler,
+		web.NewAccountHandler,
+		web.NewExportHandler,
+		web.NewContactHandler,
+		web.NewSMSGatewayHandler,
+		web.NewSMSDeliveryHandler,
+		web.NewCaptchaHandler,
+		ioc.InitJWTKeys,
 		jwt2.NewRedisJWTHandler,
 
 		ioc.InitMiddleWares,
 		ioc.InitGinServer,
+
+		grpc2.NewUserServiceServer,
+		grpc2.NewArticleServiceServer,
+		ioc.InitGRPCServer,
+
+		job.NewAccountDeletionJob,
+		job.NewExportCleanupJob,
+		job.NewAsyncSMSJob,
+		wire.Struct(new(App), "*"),
 	)
-	return new(gin.Engine)
+	return new(App)
 }
@@ -7,7 +7,8 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
+	"github.com/skcheng003/webook/internal/grpc"
+	"github.com/skcheng003/webook/internal/job"
 	"github.com/skcheng003/webook/internal/repository"
 	"github.com/skcheng003/webook/internal/repository/cache"
 	"github.com/skcheng003/webook/internal/repository/dao"
@@ -19,20 +20,87 @@ import (
 
 // Injectors from wire.go:
 
-func initWebServer() *gin.Engine {
+func initApp() *App {
 	cmdable := ioc.InitRedis()
-	handler := jwt.NewRedisJWTHandler(cmdable)
-	v := ioc.InitMiddleWares(handler)
+	keys := ioc.InitJWTKeys()
+	handler := jwt.NewRedisJWTHandler(cmdable, keys)
+	captchaCache := cache.NewRedisCaptchaCache(cmdable)
+	captchaRepository := repository.NewCachedCaptchaRepository(captchaCache)
+	captchaConfig := ioc.InitCaptchaConfig()
+	captchaService := service.NewCaptchaService(captchaRepository, captchaConfig)
+	v := ioc.InitMiddleWares(handler, captchaService)
 	db := ioc.InitDB()
 	userDao := dao.NewGORMUserDAO(db)
 	userCache := cache.NewRedisUserCache(cmdable)
 	userRepository := repository.NewUserRepository(userDao, userCache)
-	userService := service.NewUserService(userRepository)
-	smsService := ioc.InitSMSService()
+	loginAttemptCache := cache.NewRedisLoginAttemptCache(cmdable)
+	loginAttemptRepository := repository.NewCachedLoginAttemptRepository(loginAttemptCache)
+	limiter := ioc.InitLimiter(cmdable)
+	asyncSMSDAO := dao.NewGORMAsyncSMSDAO(db)
+	asyncSMSRepository := repository.NewAsyncSMSRepository(asyncSMSDAO)
+	config := ioc.InitAsyncSMSConfig()
+	v2 := ioc.InitSMSProviders()
+	registry := ioc.InitSMSTemplateRegistry(v2)
+	smsDeliveryDAO := dao.NewGORMSMSDeliveryDAO(db)
+	smsDeliveryRepository := ioc.InitSMSDeliveryRepository(smsDeliveryDAO)
+	asyncService := ioc.InitSMSService(limiter, asyncSMSRepository, config, registry, v2, smsDeliveryRepository)
 	codeCache := cache.NewRedisCodeCache(cmdable)
 	codeRepository := repository.NewCachedCodeRepository(codeCache)
-	codeService := service.NewSMSCodeService(smsService, codeRepository)
-	userHandler := web.NewUserHandler(userService, codeService, handler)
-	engine := ioc.InitGinServer(v, userHandler)
-	return engine
+	codeQuotaCache := cache.NewRedisCodeQuotaCache(cmdable)
+	codeQuotaRepository := repository.NewCachedCodeQuotaRepository(codeQuotaCache)
+	codeLimitConfig := ioc.InitCodeLimitConfig()
+	codePolicies := ioc.InitCodePolicies()
+	codeService := service.NewSMSCodeService(asyncService, codeRepository, codeQuotaRepository, codeLimitConfig, codePolicies)
+	mfaDao := dao.NewGORMMFADAO(db)
+	mfaRepository := repository.NewMFARepository(mfaDao)
+	mfaService := service.NewTOTPService(mfaRepository, userRepository)
+	securityEventEmitter := service.NewLogSecurityEventEmitter()
+	lockoutConfig := ioc.InitLockoutConfig()
+	userService := service.NewUserService(userRepository, loginAttemptRepository, codeService, mfaService, securityEventEmitter, lockoutConfig)
+	loginLogDao := dao.NewGORMLoginLogDAO(db)
+	loginLogRepository := repository.NewLoginLogRepository(loginLogDao)
+	ipLocator := ioc.InitIPLocator()
+	loginLogService := service.NewLoginLogService(loginLogRepository, ipLocator, securityEventEmitter)
+	userHandler := web.NewUserHandler(userService, codeService, mfaService, loginLogService, handler)
+	articleDao := dao.NewGORMArticleDAO(db)
+	articleRepository := repository.NewCachedArticleRepository(articleDao)
+	articleService := service.NewArticleService(articleRepository)
+	articleHandler := web.NewArticleHandler(articleService)
+	adminHandler := web.NewAdminHandler(userService, articleService, limiter, handler)
+	deletionConfig := ioc.InitDeletionConfig()
+	accountService := service.NewAccountService(userRepository, articleRepository, userService, mfaService, handler, deletionConfig)
+	accountHandler := web.NewAccountHandler(accountService)
+	exportTaskCache := cache.NewRedisExportTaskCache(cmdable)
+	exportTaskRepository := repository.NewCachedExportTaskRepository(exportTaskCache)
+	emailService := ioc.InitEmailService()
+	exportNotifier := service.NewEmailExportNotifier(emailService, userRepository)
+	exportConfig := ioc.InitExportConfig()
+	exportService := service.NewExportService(exportTaskRepository, userRepository, articleRepository, loginLogRepository, exportNotifier, exportConfig)
+	exportHandler := web.NewExportHandler(exportService)
+	emailCodeService := service.NewMailCodeService(emailService, codeRepository, codeQuotaRepository, codeLimitConfig, codePolicies)
+	contactHandler := web.NewContactHandler(userService, accountService, codeService, emailCodeService)
+	smsTokenCache := cache.NewRedisSMSTokenCache(cmdable)
+	smsTokenRepository := repository.NewCachedSMSTokenRepository(smsTokenCache)
+	authService := ioc.InitSMSGatewayService(asyncService, cmdable, smsTokenRepository)
+	smsGatewayHandler := web.NewSMSGatewayHandler(authService)
+	smsDeliveryConfig := ioc.InitSMSDeliveryConfig(v2)
+	smsDeliveryService := service.NewSMSDeliveryService(smsDeliveryRepository, smsDeliveryConfig)
+	smsDeliveryHandler := web.NewSMSDeliveryHandler(smsDeliveryService)
+	captchaHandler := web.NewCaptchaHandler(captchaService)
+	jwksHandler := web.NewJWKSHandler(keys)
+	engine := ioc.InitGinServer(v, userHandler, articleHandler, adminHandler, accountHandler, exportHandler, contactHandler, smsGatewayHandler, smsDeliveryHandler, captchaHandler, jwksHandler)
+	userServiceServer := grpc.NewUserServiceServer(userService, handler, codeService, emailCodeService)
+	articleServiceServer := grpc.NewArticleServiceServer(articleService)
+	server := ioc.InitGRPCServer(userServiceServer, articleServiceServer, handler, limiter)
+	accountDeletionJob := job.NewAccountDeletionJob(accountService)
+	exportCleanupJob := job.NewExportCleanupJob(exportService)
+	asyncSMSJob := job.NewAsyncSMSJob(asyncService)
+	app := &App{
+		server:      engine,
+		grpcServer:  server,
+		deletionJob: accountDeletionJob,
+		exportJob:   exportCleanupJob,
+		smsJob:      asyncSMSJob,
+	}
+	return app
 }